@@ -3,6 +3,6 @@ module scoing-trader
 go 1.13
 
 require (
-	github.com/go-pg/pg/v9 v9.1.2 // indirect
+	github.com/go-pg/pg/v9 v9.1.2
 	github.com/shopspring/decimal v0.0.0-20200105231215-408a2507e114
 )
@@ -1,46 +1,365 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/shopspring/decimal"
 	"log"
 	"os"
 	"scoing-trader/trader"
+	"scoing-trader/trader/db"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/market/model"
+	traderModel "scoing-trader/trader/model/trader"
+	"scoing-trader/trader/model/trader/strategies"
+	"strings"
 	"time"
 )
 
-var logFilePath = "trader.log"
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s <%s> [flags]\n", os.Args[0], strings.Join(commands, "|"))
+		os.Exit(2)
+	}
 
-//var server = "menz.dynip.sapo.pt"
-var server = "localhost"
-var port = "8989"
-var logToFile = false
-var evolution = false
-var liveMode = true
+	command := os.Args[1]
 
-func main() {
+	options, err := parseOptions(command, os.Args[2:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := run(command, options); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	var startTime = time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC)
-	var endTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+func run(command string, options Options) error {
+	if command == "live" {
+		if options.LogFile != "" {
+			logFile, err := os.OpenFile(options.LogFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+			if err != nil {
+				return err
+			}
+			defer logFile.Close()
+			log.SetOutput(logFile)
+		}
+		return runLive(options)
+	}
+
+	startTime, endTime, _ := options.TimeRange()
+	dataOptions := trader.DataOptions{
+		Start:    startTime,
+		End:      endTime,
+		UseModel: options.UseModel,
+		Host:     options.Server,
+		Port:     options.Port,
+		DataDir:  options.DataDir,
+		Offline:  options.Offline,
+	}
+
+	if command == "sync" {
+		if options.DataDir == "" || options.Offline {
+			return errors.New("sync needs a data directory and can't run offline")
+		}
+		store, err := trader.SyncPredictions(dataOptions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s holds %v\n", store.Dir, store.Ranges())
+		return nil
+	}
 
-	if logToFile {
-		var _, err = os.Stat(logFilePath)
-		if !os.IsNotExist(err) {
-			os.Remove(logFilePath)
+	var symbols *model.SymbolRegistry
+	if options.SymbolsFile != "" {
+		loaded, err := trader.LoadSymbols(options.SymbolsFile)
+		if err != nil {
+			return err
 		}
-		logFile, err := os.OpenFile(logFilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		symbols = loaded
+	}
+
+	fillPriceModel, err := newFillPriceModel(options)
+	if err != nil {
+		return err
+	}
+
+	if command == "worker" {
+		dataset, err := trader.SetupEnvironment(dataOptions)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		log.SetOutput(logFile)
+		return trader.ServeWorker(options.Listen, trader.RunOptions{Dataset: dataset, Symbols: symbols,
+			FillPriceModel: fillPriceModel, Workers: options.Workers})
+	}
+
+	repository, err := openRepository(options)
+	if err != nil {
+		return err
+	}
+	if repository != nil {
+		defer repository.Close()
+	}
+
+	config, err := options.NamedConfig()
+	if err != nil {
+		return err
 	}
-	if liveMode {
-		live := trader.NewLive(server, port, 60)
-		live.Run()
-	} else {
-		trader.SetupEnvironment(startTime, endTime, true, server, port)
-		if evolution {
-			trader.RunEvolution()
-		} else {
-			trader.RunSingleSim()
+
+	dataset, err := trader.SetupEnvironment(dataOptions)
+	if err != nil {
+		return err
+	}
+
+	fitnessFunction, err := fitness.Parse(options.Fitness)
+	if err != nil {
+		return err
+	}
+
+	selection, crossover, mutation, err := options.GeneticOperators()
+	if err != nil {
+		return err
+	}
+
+	exportConfig, err := options.ExportConfig()
+	if err != nil {
+		return err
+	}
+
+	objectives, err := trader.ParseObjectives(options.Objectives)
+	if err != nil {
+		return err
+	}
+
+	marketOptions, err := options.MarketOptions()
+	if err != nil {
+		return err
+	}
+
+	runOptions := trader.RunOptions{
+		Dataset:            dataset,
+		Symbols:            symbols,
+		FillPriceModel:     fillPriceModel,
+		Repository:         repository,
+		Config:             config,
+		ConfigOutput:       options.ConfigOutput,
+		Start:              startTime,
+		End:                endTime,
+		InitialBalance:     decimal.NewFromFloat(options.InitialBalance),
+		Fee:                decimal.NewFromFloat(options.Fee),
+		Market:             marketOptions,
+		LogFile:            options.LogFile,
+		Export:             exportConfig,
+		ReportFile:         options.ReportFile,
+		HTMLFile:           options.HTMLFile,
+		GenerationSize:     options.GenerationSize,
+		NumGenerations:     options.NumGenerations,
+		MutationRate:       options.MutationRate,
+		Selection:          selection,
+		Crossover:          crossover,
+		Mutation:           mutation,
+		Parents:            options.Parents,
+		Elitism:            options.Elitism,
+		MinDiversity:       options.MinDiversity,
+		Fitness:            fitnessFunction,
+		FitnessSpec:        options.Fitness,
+		ValidationFraction: options.ValidationSplit,
+		TestFraction:       options.TestSplit,
+		TrainWindow:        days(options.TrainDays),
+		ValidationWindow:   days(options.ValidationDays),
+		TestWindow:         days(options.TestDays),
+		SearchMethod:       options.SearchMethod,
+		GridSteps:          options.GridSteps,
+		Samples:            options.Samples,
+		SearchParams:       options.SearchParams,
+		Workers:            options.Workers,
+		Remote:             options.Remote,
+		Seed:               options.Seed,
+		SearchResults:      options.SearchResults,
+		CheckpointFile:     options.Checkpoint,
+		HistoryFile:        options.HistoryFile,
+		Resume:             options.Resume,
+		Objectives:         objectives,
+		FrontFile:          options.FrontFile,
+	}
+
+	switch command {
+	case "backtest":
+		simulation, err := trader.RunSingleSim(runOptions)
+		if err != nil {
+			return err
+		}
+		fmt.Println(simulation.Trader.Accountant.NetWorth().String() + "$")
+		fmt.Print(simulation.Report())
+	case "replay":
+		simulation, err := trader.RunReplay(runOptions)
+		if err != nil {
+			return err
+		}
+		for _, record := range simulation.Trader.Records {
+			if record.Event != traderModel.HOLD {
+				fmt.Println(record.ToString())
+			}
+		}
+		fmt.Println(simulation.Trader.Accountant.ToString())
+		fmt.Print(simulation.Report())
+	case "evolve":
+		folds, err := trader.RunEvolution(runOptions)
+		if err != nil {
+			return err
+		}
+		for i, fold := range folds {
+			fmt.Printf("fold %d train %s in sample %.4f validation %.4f test %.4f\n", i, fold.Split.Train,
+				fold.Best.Fitness, fold.Validation, fold.Test)
+		}
+		result := folds[len(folds)-1].Best
+		fmt.Printf("%.4f %v\n", result.Fitness, result.Config.ToSlice())
+	case "search":
+		search, results, err := trader.RunSearch(runOptions)
+		if err != nil {
+			return err
+		}
+		if best, found := trader.BestSearchResult(results); found {
+			fmt.Printf("best of %d: config %d %.4f %v\n", len(results), best.Index, best.Fitness, best.Config)
 		}
+		factory, err := strategies.Lookup(search.Evolution.StrategyName)
+		if err != nil {
+			return err
+		}
+		schema := factory.NewConfig().Schema()
+		for _, param := range searchedParams(search) {
+			fmt.Printf("param %d %s:\n", param, schema[param].Name)
+			for _, bin := range search.Sensitivity(results, param) {
+				fmt.Printf("  [%.4f, %.4f) %4d configs mean %.4f best %.4f\n", bin.Low, bin.High, bin.Count,
+					bin.MeanFitness, bin.BestFitness)
+			}
+		}
+	case "pareto":
+		front, err := trader.RunPareto(runOptions)
+		if err != nil {
+			return err
+		}
+		for _, specimen := range front {
+			scores := make([]string, len(objectives))
+			for i, objective := range objectives {
+				scores[i] = fmt.Sprintf("%s %.4f", objective.Name, specimen.Objectives[i])
+			}
+			fmt.Printf("%s %v\n", strings.Join(scores, " "), specimen.Config.ToSlice())
+		}
+	}
+
+	return nil
+}
+
+func runLive(options Options) error {
+	config, err := options.NamedConfig()
+	if err != nil {
+		return err
+	}
+
+	strategy, _, err := strategies.FromConfig(config)
+	if err != nil {
+		return err
+	}
+	log.Printf("Trading with %s config %s (fitness %.4f)", config.Strategy, config.Name, config.Fitness)
+
+	marketOptions, err := options.MarketOptions()
+	if err != nil {
+		return err
+	}
+
+	marketEnt, fee, err := newLiveMarket(options, marketOptions)
+	if err != nil {
+		return err
+	}
+
+	live := trader.NewLive(options.Server, options.Port, 60, marketEnt, fee, marketOptions.Quote(), strategy)
+
+	repository, err := openRepository(options)
+	if err != nil {
+		return err
+	}
+	if repository != nil {
+		defer repository.Close()
+		live.Trader.Recorder = db.NewEventRecorder(repository)
+	}
+
+	live.Run()
+
+	return nil
+}
+
+// newLiveMarket connects to the exchange, or creates the paper trading market, and returns it with the fee the
+// accountant reserves buys with.
+func newLiveMarket(options Options, marketOptions trader.MarketOptions) (model.Market, decimal.Decimal, error) {
+	fee := decimal.NewFromFloat(options.Fee)
+
+	if !options.Paper {
+		binanceMarket, err := market.NewBinanceMarket(options.ExchangeEndpoint, os.Getenv("BINANCE_API_KEY"),
+			os.Getenv("BINANCE_SECRET_KEY"), 30)
+		if err != nil {
+			return nil, fee, err
+		}
+		return binanceMarket, fee, nil
+	}
+
+	simulatedMarket, fee := marketOptions.NewMarket(decimal.NewFromFloat(options.InitialBalance), fee)
+	if options.SymbolsFile != "" {
+		symbols, err := model.LoadSymbolRegistry(options.SymbolsFile)
+		if err != nil {
+			return nil, fee, err
+		}
+		simulatedMarket.SetSymbolRegistry(symbols)
+	}
+	fillPriceModel, err := newFillPriceModel(options)
+	if err != nil {
+		return nil, fee, err
+	}
+	if fillPriceModel != nil {
+		simulatedMarket.SetFillPriceModel(fillPriceModel)
+	}
+
+	return simulatedMarket, fee, nil
+}
+
+// searchedParams are the params a search varied.
+func searchedParams(search *trader.Search) []int {
+	if len(search.Params) > 0 {
+		return search.Params
+	}
+
+	params := make([]int, len(search.Evolution.StartingPoint))
+	for i := range params {
+		params[i] = i
+	}
+	return params
+}
+
+func days(count int) time.Duration {
+	return time.Duration(count) * 24 * time.Hour
+}
+
+func newFillPriceModel(options Options) (market.FillPriceModel, error) {
+	if options.TopOfBookFile != "" {
+		return market.LoadSpreadBook(options.TopOfBookFile)
+	}
+	if options.SlippageBps > 0 {
+		return market.NewFixedSlippage(options.SlippageBps), nil
+	}
+	if options.ImpactBps > 0 {
+		return market.NewVolumeImpact(options.ImpactBps, options.ImpactNotional), nil
+	}
+	return nil, nil
+}
+
+func openRepository(options Options) (db.Repository, error) {
+	if options.DatabaseUrl == "" {
+		return nil, nil
 	}
+	return db.NewPgRepository(options.DatabaseUrl)
 }
@@ -0,0 +1,692 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"scoing-trader/trader"
+	"scoing-trader/trader/export"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/model/market/model"
+	traderModel "scoing-trader/trader/model/trader"
+	"scoing-trader/trader/model/trader/strategies"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var commands = []string{"live", "backtest", "evolve", "search", "pareto", "replay", "sync", "worker"}
+
+// Options holds everything a run can be configured with. They can be loaded from a JSON config file, flags given on
+// the command line take precedence over the file.
+type Options struct {
+	ConfigFile         string    `json:"-"`
+	Server             string    `json:"server"`
+	Port               string    `json:"port"`
+	Start              string    `json:"start"`
+	End                string    `json:"end"`
+	UseModel           bool      `json:"use_model"`
+	DataDir            string    `json:"data_dir"`
+	Offline            bool      `json:"offline"`
+	StrategyConfig     string    `json:"strategy_config"`
+	Strategy           string    `json:"strategy"`
+	Params             []float64 `json:"params"`
+	ConfigOutput       string    `json:"config_output"`
+	InitialBalance     float64   `json:"initial_balance"`
+	Fee                float64   `json:"fee"`
+	QuoteAsset         string    `json:"quote_asset"`
+	MakerFee           float64   `json:"maker_fee"`
+	TakerFee           float64   `json:"taker_fee"`
+	CommissionAsset    string    `json:"commission_asset"`
+	CommissionDiscount float64   `json:"commission_discount"`
+	CommissionBalance  float64   `json:"commission_balance"`
+	UnfilledRate       float64   `json:"unfilled_rate"`
+	MinLatency         string    `json:"min_latency"`
+	MaxLatency         string    `json:"max_latency"`
+	Expiry             string    `json:"expiry"`
+	LogFile            string    `json:"log_file"`
+	ResultFile         string    `json:"result_file"`
+	ResultFormat       string    `json:"result_format"`
+	ResultInterval     string    `json:"result_interval"`
+	ResultTables       string    `json:"result_tables"`
+	ReportFile         string    `json:"report_file"`
+	HTMLFile           string    `json:"html_file"`
+	SymbolsFile        string    `json:"symbols_file"`
+	SlippageBps        float64   `json:"slippage_bps"`
+	ImpactBps          float64   `json:"impact_bps"`
+	ImpactNotional     float64   `json:"impact_reference_notional"`
+	TopOfBookFile      string    `json:"top_of_book_file"`
+	DatabaseUrl        string    `json:"database_url"`
+	Paper              bool      `json:"paper"`
+	ExchangeEndpoint   string    `json:"exchange_endpoint"`
+	GenerationSize     int       `json:"generation_size"`
+	NumGenerations     int       `json:"num_generations"`
+	MutationRate       float64   `json:"mutation_rate"`
+	Selection          string    `json:"selection"`
+	TournamentSize     int       `json:"tournament_size"`
+	Crossover          string    `json:"crossover"`
+	BlendAlpha         float64   `json:"blend_alpha"`
+	SBXEta             float64   `json:"sbx_eta"`
+	Mutation           string    `json:"mutation"`
+	MutationSigma      float64   `json:"mutation_sigma"`
+	MutationDecay      float64   `json:"mutation_decay"`
+	Parents            int       `json:"parents"`
+	Elitism            int       `json:"elitism"`
+	MinDiversity       float64   `json:"min_diversity"`
+	Fitness            string    `json:"fitness"`
+	ValidationSplit    float64   `json:"validation_split"`
+	TestSplit          float64   `json:"test_split"`
+	TrainDays          int       `json:"train_days"`
+	ValidationDays     int       `json:"validation_days"`
+	TestDays           int       `json:"test_days"`
+	SearchMethod       string    `json:"search_method"`
+	GridSteps          int       `json:"grid_steps"`
+	Samples            int       `json:"samples"`
+	SearchParams       []int     `json:"search_params"`
+	Workers            int       `json:"workers"`
+	Remote             []string  `json:"remote_workers"`
+	Listen             string    `json:"listen"`
+	Objectives         string    `json:"objectives"`
+	FrontFile          string    `json:"front_file"`
+	Seed               int64     `json:"seed"`
+	SearchResults      string    `json:"search_results"`
+	Checkpoint         string    `json:"checkpoint"`
+	HistoryFile        string    `json:"history_file"`
+	Resume             bool      `json:"resume"`
+}
+
+type paramList struct {
+	params *[]float64
+}
+
+func (p paramList) String() string {
+	if p.params == nil {
+		return ""
+	}
+
+	values := make([]string, len(*p.params))
+	for i, param := range *p.params {
+		values[i] = strconv.FormatFloat(param, 'g', -1, 64)
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (p paramList) Set(value string) error {
+	params := make([]float64, 0)
+
+	for _, field := range strings.Split(value, ",") {
+		param, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return err
+		}
+		params = append(params, param)
+	}
+
+	*p.params = params
+
+	return nil
+}
+
+type stringList struct {
+	values *[]string
+}
+
+func (l stringList) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l stringList) Set(value string) error {
+	values := make([]string, 0)
+
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			values = append(values, field)
+		}
+	}
+
+	*l.values = values
+
+	return nil
+}
+
+type indexList struct {
+	indices *[]int
+}
+
+func (l indexList) String() string {
+	if l.indices == nil {
+		return ""
+	}
+
+	values := make([]string, len(*l.indices))
+	for i, index := range *l.indices {
+		values[i] = strconv.Itoa(index)
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (l indexList) Set(value string) error {
+	indices := make([]int, 0)
+
+	for _, field := range strings.Split(value, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return err
+		}
+		indices = append(indices, index)
+	}
+
+	*l.indices = indices
+
+	return nil
+}
+
+func defaultOptions(command string) Options {
+	options := Options{
+		Server:             "localhost",
+		Port:               "8989",
+		Start:              "2019-07-01",
+		End:                "2020-01-01",
+		UseModel:           true,
+		DataDir:            "data",
+		StrategyConfig:     "configs/backtest.json",
+		InitialBalance:     1000,
+		Fee:                0.001,
+		LogFile:            "trader.log",
+		ResultFile:         "result.csv",
+		ResultInterval:     "1h",
+		ResultTables:       "equity,trades",
+		DatabaseUrl:        os.Getenv("TRADER_DATABASE_URL"),
+		Paper:              true,
+		QuoteAsset:         model.DefaultQuoteAsset,
+		MakerFee:           -1,
+		TakerFee:           -1,
+		CommissionDiscount: 0.25,
+		MinLatency:         "0s",
+		MaxLatency:         "0s",
+		Expiry:             "0s",
+		ImpactNotional:     10000,
+		ExchangeEndpoint:   "https://api.binance.com",
+		GenerationSize:     200,
+		NumGenerations:     10,
+		MutationRate:       0.4,
+		Selection:          "truncation",
+		TournamentSize:     3,
+		Crossover:          "blend",
+		SBXEta:             15,
+		Mutation:           "uniform",
+		MutationSigma:      0.1,
+		MutationDecay:      0.95,
+		Parents:            2,
+		Elitism:            2,
+		Fitness:            "net_worth",
+		ValidationSplit:    0.2,
+		TestSplit:          0.2,
+		SearchMethod:       "grid",
+		GridSteps:          5,
+		Samples:            100,
+		Seed:               1,
+		SearchResults:      "search.csv",
+		Checkpoint:         "evolution.json",
+		HistoryFile:        "generations.csv",
+		Listen:             ":8990",
+		Objectives:         "return,max_drawdown,trades",
+		FrontFile:          "pareto.csv",
+	}
+
+	switch command {
+	case "live":
+		options.LogFile = ""
+		options.StrategyConfig = "configs/live.json"
+	case "evolve":
+		options.StrategyConfig = "configs/evolution-start.json"
+		options.ConfigOutput = "configs/evolved.json"
+		options.Seed = 0
+	case "search":
+		options.StrategyConfig = "configs/evolution-start.json"
+	case "pareto":
+		options.StrategyConfig = "configs/evolution-start.json"
+		options.Seed = 0
+	}
+
+	return options
+}
+
+func newFlagSet(command string, options *Options, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(output)
+
+	flags.StringVar(&options.ConfigFile, "config", options.ConfigFile, "JSON config file, flags override its values")
+	flags.StringVar(&options.Server, "server", options.Server, "prediction server host")
+	flags.StringVar(&options.Port, "port", options.Port, "prediction server port")
+	flags.StringVar(&options.StrategyConfig, "strategy-config", options.StrategyConfig, "named strategy config file")
+	flags.StringVar(&options.Strategy, "strategy", options.Strategy, "strategy name ("+strings.Join(strategies.Names(), ", ")+"), "+
+		"must match the strategy config unless -params is given")
+	flags.Var(paramList{&options.Params}, "params", "comma separated strategy params, replace the strategy config")
+	flags.Float64Var(&options.Fee, "fee", options.Fee, "exchange fee rate")
+	flags.StringVar(&options.QuoteAsset, "quote", options.QuoteAsset, "asset the balance is kept in, only the symbols "+
+		"quoted in it are traded")
+	flags.Float64Var(&options.MakerFee, "maker-fee", options.MakerFee, "fee rate of fills resting on the book, -fee "+
+		"when negative")
+	flags.Float64Var(&options.TakerFee, "taker-fee", options.TakerFee, "fee rate of fills taking liquidity, -fee "+
+		"when negative")
+	flags.StringVar(&options.CommissionAsset, "commission-asset", options.CommissionAsset, "asset commissions are "+
+		"paid in while its balance lasts (e.g. BNB), empty pays them in the quote asset")
+	flags.Float64Var(&options.CommissionDiscount, "commission-discount", options.CommissionDiscount, "discount on "+
+		"commissions paid in the commission asset")
+	flags.Float64Var(&options.CommissionBalance, "commission-balance", options.CommissionBalance, "simulated balance "+
+		"of the commission asset")
+	flags.Float64Var(&options.UnfilledRate, "unfilled-rate", options.UnfilledRate, "share of simulated market orders "+
+		"resting on the book instead of filling at once")
+	flags.StringVar(&options.MinLatency, "min-latency", options.MinLatency, "shortest wait of a simulated resting "+
+		"order before it can fill")
+	flags.StringVar(&options.MaxLatency, "max-latency", options.MaxLatency, "longest wait of a simulated resting "+
+		"order before it can fill")
+	flags.StringVar(&options.Expiry, "expiry", options.Expiry, "time after which simulated resting orders expire, 0 "+
+		"keeps them until filled")
+	flags.StringVar(&options.LogFile, "log", options.LogFile, "log file, empty logs to stderr")
+	flags.StringVar(&options.SymbolsFile, "symbols", options.SymbolsFile, "exchangeInfo JSON with symbol filters")
+	flags.StringVar(&options.DatabaseUrl, "database", options.DatabaseUrl, "postgres url to record events and configs")
+
+	if command == "live" {
+		flags.BoolVar(&options.Paper, "paper", options.Paper, "trade on a simulated market")
+		flags.StringVar(&options.ExchangeEndpoint, "exchange", options.ExchangeEndpoint, "exchange REST endpoint")
+		flags.Float64Var(&options.InitialBalance, "balance", options.InitialBalance, "initial paper trading balance")
+		flags.Float64Var(&options.SlippageBps, "slippage", options.SlippageBps, "paper trading slippage in basis points")
+		flags.Float64Var(&options.ImpactBps, "impact-bps", options.ImpactBps, "paper trading price impact in basis "+
+			"points per reference notional traded")
+		flags.Float64Var(&options.ImpactNotional, "impact-reference-notional", options.ImpactNotional, "order "+
+			"notional moving the paper trading price by -impact-bps")
+		return flags
+	}
+
+	flags.StringVar(&options.Start, "start", options.Start, "start of the prediction range (YYYY-MM-DD or RFC3339)")
+	flags.StringVar(&options.End, "end", options.End, "end of the prediction range (YYYY-MM-DD or RFC3339)")
+	flags.BoolVar(&options.UseModel, "use-model", options.UseModel, "request model predictions from the server")
+	flags.StringVar(&options.DataDir, "data", options.DataDir, "directory caching the predictions, empty always requests them")
+	flags.BoolVar(&options.Offline, "offline", options.Offline, "only use the predictions cached in the data directory")
+	flags.Float64Var(&options.InitialBalance, "balance", options.InitialBalance, "initial balance")
+	flags.Int64Var(&options.Seed, "seed", options.Seed, "seed of the simulated fills and the random configs, an "+
+		"evolution draws one from the clock when 0")
+	flags.StringVar(&options.ResultFile, "result", options.ResultFile, "file the equity history is exported to, the other "+
+		"tables get their name appended, empty skips the export")
+	flags.StringVar(&options.ResultFormat, "result-format", options.ResultFormat, "export format (csv, jsonl), "+
+		"defaults to the extension of -result")
+	flags.StringVar(&options.ResultInterval, "result-interval", options.ResultInterval, "time between equity snapshots, "+
+		"0 for every prediction")
+	flags.StringVar(&options.ResultTables, "result-tables", options.ResultTables, "comma separated tables to export "+
+		"(equity, trades, decisions)")
+	flags.StringVar(&options.ReportFile, "report", options.ReportFile, "JSON file the performance report is saved to")
+	flags.StringVar(&options.HTMLFile, "html", options.HTMLFile, "HTML file with the charts and metrics of the run")
+	flags.Float64Var(&options.SlippageBps, "slippage", options.SlippageBps, "fixed slippage in basis points")
+	flags.Float64Var(&options.ImpactBps, "impact-bps", options.ImpactBps, "price impact in basis points per "+
+		"reference notional traded")
+	flags.Float64Var(&options.ImpactNotional, "impact-reference-notional", options.ImpactNotional, "order notional "+
+		"moving the price by -impact-bps")
+	flags.StringVar(&options.TopOfBookFile, "top-of-book", options.TopOfBookFile, "recorded top of book CSV for fill prices")
+
+	if command == "evolve" || command == "search" {
+		flags.StringVar(&options.Fitness, "fitness", options.Fitness, "weighted sum of "+
+			strings.Join(fitness.Names(), ", ")+" scoring the configs, e.g. 0.5*sharpe+0.5*calmar+min_trades(20)")
+	}
+
+	if command == "evolve" || command == "search" || command == "pareto" || command == "worker" {
+		flags.IntVar(&options.Workers, "workers", options.Workers, "simulations run at once, 0 for one per CPU")
+	}
+
+	if command == "worker" {
+		flags.StringVar(&options.Listen, "listen", options.Listen, "address evaluations are served on")
+	}
+
+	if command == "search" {
+		flags.StringVar(&options.SearchMethod, "method", options.SearchMethod, "search method, grid or random")
+		flags.IntVar(&options.GridSteps, "steps", options.GridSteps, "grid values per param, spanning its range")
+		flags.IntVar(&options.Samples, "samples", options.Samples, "random search configs")
+		flags.Var(indexList{&options.SearchParams}, "search-params", "comma separated indices of the params varied, "+
+			"all when empty, the others keep their value from the strategy config")
+		flags.StringVar(&options.SearchResults, "results", options.SearchResults, "CSV file the results are appended "+
+			"to, an interrupted search resumes from it")
+	}
+
+	if command == "pareto" {
+		flags.StringVar(&options.Objectives, "objectives", options.Objectives, "comma separated objectives of the "+
+			"front ("+strings.Join(trader.ObjectiveNames(), ", ")+"), each maximised or minimised as its name implies")
+		flags.StringVar(&options.FrontFile, "front", options.FrontFile, "file the pareto front is saved to, as CSV "+
+			"or JSON Lines by extension")
+	}
+
+	if command == "evolve" || command == "pareto" {
+		flags.IntVar(&options.GenerationSize, "generation-size", options.GenerationSize, "specimens per generation")
+		flags.IntVar(&options.NumGenerations, "generations", options.NumGenerations, "number of generations")
+		flags.Float64Var(&options.MutationRate, "mutation-rate", options.MutationRate, "probability of mutating a child")
+		flags.StringVar(&options.Crossover, "crossover", options.Crossover, "crossover: uniform, blend or sbx")
+		flags.Float64Var(&options.BlendAlpha, "blend-alpha", options.BlendAlpha, "blend crossover reach beyond the parents")
+		flags.Float64Var(&options.SBXEta, "sbx-eta", options.SBXEta, "sbx distribution index, higher stays closer to "+
+			"the parents")
+		flags.StringVar(&options.Mutation, "mutation", options.Mutation, "mutation: uniform or gaussian")
+		flags.Float64Var(&options.MutationSigma, "mutation-sigma", options.MutationSigma, "gaussian step as a fraction "+
+			"of the param range")
+		flags.Float64Var(&options.MutationDecay, "mutation-decay", options.MutationDecay, "gaussian step decay per "+
+			"generation")
+		flags.IntVar(&options.Parents, "parents", options.Parents, "parents per child")
+	}
+
+	if command == "evolve" {
+		flags.StringVar(&options.Selection, "selection", options.Selection, "parent selection: truncation, tournament, "+
+			"roulette or rank")
+		flags.IntVar(&options.TournamentSize, "tournament-size", options.TournamentSize, "specimens per tournament")
+		flags.IntVar(&options.Elitism, "elitism", options.Elitism, "fittest specimens kept unchanged each generation")
+		flags.Float64Var(&options.MinDiversity, "min-diversity", options.MinDiversity, "diversity under which random "+
+			"specimens are added, 0 never adds any")
+		flags.StringVar(&options.ConfigOutput, "output", options.ConfigOutput, "file the best evolved config is saved to")
+		flags.Float64Var(&options.ValidationSplit, "validation-split", options.ValidationSplit,
+			"fraction of the range before the test window used to pick the best generation")
+		flags.Float64Var(&options.TestSplit, "test-split", options.TestSplit, "fraction at the end of the range kept for test")
+		flags.IntVar(&options.TrainDays, "train-days", options.TrainDays, "walk forward train window, replaces the splits")
+		flags.IntVar(&options.ValidationDays, "validation-days", options.ValidationDays, "walk forward validation window")
+		flags.IntVar(&options.TestDays, "test-days", options.TestDays, "walk forward test window, each fold moves by it")
+		flags.StringVar(&options.Checkpoint, "checkpoint", options.Checkpoint, "JSON file the population is saved to "+
+			"after every generation, none when empty")
+		flags.StringVar(&options.HistoryFile, "history", options.HistoryFile, "file the best of every generation is "+
+			"saved to, as CSV or JSON Lines by extension")
+		flags.BoolVar(&options.Resume, "resume", options.Resume, "go on from the checkpoint of an interrupted evolution")
+		flags.Var(stringList{&options.Remote}, "remote", "comma separated URLs of worker processes the simulations "+
+			"are sent to, each serving the predictions of the whole range")
+	}
+
+	return flags
+}
+
+func parseOptions(command string, args []string, output io.Writer) (Options, error) {
+	if !isCommand(command) {
+		return Options{}, errors.New(fmt.Sprintf("unknown command %q, expected one of %s", command,
+			strings.Join(commands, ", ")))
+	}
+
+	options := defaultOptions(command)
+	flags := newFlagSet(command, &options, output)
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+
+	if flags.NArg() > 0 {
+		return options, errors.New("unexpected arguments: " + strings.Join(flags.Args(), " "))
+	}
+
+	if options.ConfigFile != "" {
+		if err := loadOptions(options.ConfigFile, &options); err != nil {
+			return options, err
+		}
+
+		// Parse again so the flags given override the values of the config file.
+		if err := flags.Parse(args); err != nil {
+			return options, err
+		}
+	}
+
+	return options, options.validate()
+}
+
+func loadOptions(path string, options *Options) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(options); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (o Options) validate() error {
+	start, end, err := o.TimeRange()
+	if err != nil {
+		return err
+	}
+
+	if !end.After(start) {
+		return errors.New("end must be after start")
+	}
+
+	if o.Offline && o.DataDir == "" {
+		return errors.New("offline runs need a data directory")
+	}
+
+	if o.InitialBalance <= 0 {
+		return errors.New("initial balance must be positive")
+	}
+
+	if o.Fee < 0 {
+		return errors.New("fee can't be negative")
+	}
+
+	if o.QuoteAsset == "" {
+		return errors.New("the balance needs a quote asset")
+	}
+
+	if o.CommissionDiscount < 0 || o.CommissionDiscount >= 1 || o.CommissionBalance < 0 {
+		return errors.New("commission discount must be in [0, 1) and the commission balance can't be negative")
+	}
+
+	if _, err := o.MarketOptions(); err != nil {
+		return err
+	}
+
+	if o.SlippageBps < 0 || o.ImpactBps < 0 || o.ImpactNotional <= 0 {
+		return errors.New("slippage and impact can't be negative and the impact reference notional must be positive")
+	}
+
+	if o.SlippageBps > 0 && o.ImpactBps > 0 {
+		return errors.New("fixed slippage and volume impact are alternative fill price models, pick one")
+	}
+
+	if o.GenerationSize < 2 || o.NumGenerations < 1 {
+		return errors.New("evolution needs at least 2 specimens and 1 generation")
+	}
+
+	if _, err := fitness.Parse(o.Fitness); err != nil {
+		return err
+	}
+
+	if o.ValidationSplit < 0 || o.TestSplit < 0 || o.ValidationSplit+o.TestSplit >= 1 {
+		return errors.New("validation and test splits must be non-negative and leave time to train")
+	}
+
+	if _, _, _, err := o.GeneticOperators(); err != nil {
+		return err
+	}
+
+	if o.Parents < 1 || o.Elitism < 0 || o.Elitism >= o.GenerationSize || o.MinDiversity < 0 {
+		return errors.New("evolution needs a parent per child and elitism below the generation size")
+	}
+
+	if o.SearchMethod != "grid" && o.SearchMethod != "random" {
+		return errors.New(fmt.Sprintf("unknown search method %q, expected grid or random", o.SearchMethod))
+	}
+
+	if o.GridSteps < 2 || o.Samples < 1 || o.Workers < 0 {
+		return errors.New("search needs at least 2 grid steps, 1 sample and no negative workers")
+	}
+
+	if _, err := o.ExportConfig(); err != nil {
+		return err
+	}
+
+	if _, err := trader.ParseObjectives(o.Objectives); err != nil {
+		return err
+	}
+
+	if o.Resume && o.Checkpoint == "" {
+		return errors.New("resuming needs a checkpoint file")
+	}
+
+	if o.TrainDays < 0 || o.ValidationDays < 0 || o.TestDays < 0 || (o.TrainDays > 0) != (o.TestDays > 0) {
+		return errors.New("walk forward needs both train and test days")
+	}
+
+	return nil
+}
+
+// NamedConfig returns the strategy config to run, built from -params when given or loaded from the config file.
+func (o Options) NamedConfig() (*traderModel.NamedConfig, error) {
+	if o.Params != nil {
+		strategy := o.Strategy
+		if strategy == "" {
+			strategy = "basic_with_memory"
+		}
+
+		_, config, err := strategies.New(strategy, o.Params)
+		if err != nil {
+			return nil, err
+		}
+
+		return traderModel.NewNamedConfig("params", strategies.CanonicalName(strategy), config, 0, traderModel.Provenance{Source: "command line"})
+	}
+
+	named, err := traderModel.LoadNamedConfig(o.StrategyConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Strategy != "" && strategies.CanonicalName(o.Strategy) != strategies.CanonicalName(named.Strategy) {
+		return nil, errors.New(fmt.Sprintf("strategy config %s is for %s, not %s", o.StrategyConfig, named.Strategy,
+			o.Strategy))
+	}
+
+	return named, nil
+}
+
+// GeneticOperators builds the selection, crossover and mutation of an evolution.
+func (o Options) GeneticOperators() (trader.Selection, trader.Crossover, trader.Mutation, error) {
+	selection, err := trader.NewSelection(o.Selection, o.TournamentSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	crossover, err := trader.NewCrossover(o.Crossover, o.BlendAlpha, o.SBXEta)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	mutation, err := trader.NewMutation(o.Mutation, o.MutationSigma, o.MutationDecay)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return selection, crossover, mutation, nil
+}
+
+// MarketOptions sets up the simulated market with the quote asset, the maker and taker fees, the commission asset and
+// the lifecycle of resting orders.
+func (o Options) MarketOptions() (trader.MarketOptions, error) {
+	options := trader.MarketOptions{
+		QuoteAsset:         o.QuoteAsset,
+		CommissionAsset:    o.CommissionAsset,
+		CommissionDiscount: decimal.NewFromFloat(o.CommissionDiscount),
+		CommissionBalance:  decimal.NewFromFloat(o.CommissionBalance),
+	}
+
+	if o.MakerFee >= 0 {
+		maker := decimal.NewFromFloat(o.MakerFee)
+		options.MakerFee = &maker
+	}
+	if o.TakerFee >= 0 {
+		taker := decimal.NewFromFloat(o.TakerFee)
+		options.TakerFee = &taker
+	}
+
+	if o.UnfilledRate < 0 || o.UnfilledRate > 1 {
+		return options, errors.New("the unfilled rate must be in [0, 1]")
+	}
+	options.UnfilledRate = o.UnfilledRate
+
+	for _, duration := range []struct {
+		value  string
+		target *time.Duration
+	}{{o.MinLatency, &options.MinLatency}, {o.MaxLatency, &options.MaxLatency}, {o.Expiry, &options.Expiry}} {
+		parsed, err := time.ParseDuration(duration.value)
+		if err != nil || parsed < 0 {
+			return options, errors.New(fmt.Sprintf("invalid duration %q", duration.value))
+		}
+		*duration.target = parsed
+	}
+
+	if options.MaxLatency < options.MinLatency {
+		return options, errors.New("the max latency can't be below the min latency")
+	}
+
+	return options, nil
+}
+
+func (o Options) ExportConfig() (export.Config, error) {
+	config := export.Config{Path: o.ResultFile}
+
+	if o.ResultFormat != "" {
+		format, err := export.ParseFormat(o.ResultFormat)
+		if err != nil {
+			return config, err
+		}
+		config.Format = format
+	}
+
+	interval, err := time.ParseDuration(o.ResultInterval)
+	if err != nil || interval < 0 {
+		return config, errors.New(fmt.Sprintf("invalid result interval %q", o.ResultInterval))
+	}
+	config.Interval = interval
+
+	config.Tables, err = export.ParseTables(o.ResultTables)
+
+	return config, err
+}
+
+func (o Options) TimeRange() (time.Time, time.Time, error) {
+	start, err := parseTime(o.Start)
+	if err != nil {
+		return start, start, err
+	}
+
+	end, err := parseTime(o.End)
+
+	return start, end, err
+}
+
+func parseTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(dateLayout, value); err == nil {
+		return parsed, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return parsed, errors.New(fmt.Sprintf("invalid time %q, expected YYYY-MM-DD or RFC3339", value))
+	}
+
+	return parsed, nil
+}
+
+func isCommand(command string) bool {
+	for _, known := range commands {
+		if command == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/trader/strategies"
+	"testing"
+	"time"
+)
+
+func TestParseOptionsDefaults(t *testing.T) {
+	options, err := parseOptions("backtest", nil, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, end, _ := options.TimeRange()
+	if !start.Equal(time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Incorrect default time range ", start, end)
+	}
+
+	if options.StrategyConfig != "configs/backtest.json" || options.InitialBalance != 1000 || options.Fee != 0.001 {
+		t.Error("Incorrect defaults ", options)
+	}
+
+	live, _ := parseOptions("live", nil, ioutil.Discard)
+	if live.LogFile != "" || !live.Paper || live.StrategyConfig != "configs/live.json" {
+		t.Error("Expected live to log to stderr and paper trade with the live config by default")
+	}
+}
+
+func TestOptionsNamedConfig(t *testing.T) {
+	for _, command := range commands {
+		options, _ := parseOptions(command, nil, ioutil.Discard)
+
+		named, err := options.NamedConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := strategies.FromConfig(named); err != nil {
+			t.Error(command, err)
+		}
+	}
+
+	options, _ := parseOptions("backtest", []string{"-strategy", "Basic"}, ioutil.Discard)
+	if _, err := options.NamedConfig(); err == nil {
+		t.Error("Expected strategy mismatch with the config file to fail")
+	}
+
+	options, _ = parseOptions("backtest", []string{"-strategy", "Basic", "-params", "1,2,3,0.4,0.5,0.6,-0.1,0.1,0.5,0.5"},
+		ioutil.Discard)
+	named, err := options.NamedConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if named.Strategy != "basic" || named.Provenance.Source != "command line" {
+		t.Error("Incorrect config built from params ", named)
+	}
+
+	options, _ = parseOptions("backtest", []string{"-strategy", "Basic", "-params", "1,2,3,4,5,6,-0.1,0.1,0.5,0.5"},
+		ioutil.Discard)
+	if _, err := options.NamedConfig(); err == nil {
+		t.Error("Expected params out of their range to fail")
+	}
+}
+
+func TestParseOptionsFlags(t *testing.T) {
+	options, err := parseOptions("evolve", []string{"-start", "2020-01-01", "-end", "2020-02-01T12:00:00Z",
+		"-generations", "3", "-params", "1,2.5,-0.3", "-balance", "500"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, end, _ := options.TimeRange()
+	if !end.Equal(time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Error("Incorrect end ", end)
+	}
+
+	if options.NumGenerations != 3 || options.InitialBalance != 500 {
+		t.Error("Flags not applied ", options)
+	}
+
+	if len(options.Params) != 3 || options.Params[1] != 2.5 || options.Params[2] != -0.3 {
+		t.Error("Incorrect params ", options.Params)
+	}
+
+	if _, err := parseOptions("backtest", []string{"-generations", "3"}, ioutil.Discard); err == nil {
+		t.Error("Expected evolution flag to be rejected by backtest")
+	}
+
+	if _, err := parseOptions("backtest", []string{"-start", "2020-02-01", "-end", "2020-01-01"}, ioutil.Discard); err == nil {
+		t.Error("Expected inverted time range to fail")
+	}
+
+	if _, err := parseOptions("backtest", []string{"-offline", "-data", ""}, ioutil.Discard); err == nil {
+		t.Error("Expected offline run without a data directory to fail")
+	}
+
+	for _, args := range [][]string{{"-result-format", "xlsx"}, {"-result-interval", "hourly"}, {"-result-tables", "orders"}} {
+		if _, err := parseOptions("backtest", args, ioutil.Discard); err == nil {
+			t.Error("Expected invalid export options to fail ", args)
+		}
+	}
+
+	if _, err := parseOptions("evolve", []string{"-resume", "-checkpoint", ""}, ioutil.Discard); err == nil {
+		t.Error("Expected resuming without a checkpoint to fail")
+	}
+
+	worker, err := parseOptions("worker", []string{"-listen", ":9000", "-workers", "4"}, ioutil.Discard)
+	if err != nil || worker.Listen != ":9000" || worker.Workers != 4 {
+		t.Error("Worker flags not applied ", worker, err)
+	}
+
+	remote, err := parseOptions("evolve", []string{"-remote", "http://a:8990, http://b:8990"}, ioutil.Discard)
+	if err != nil || len(remote.Remote) != 2 || remote.Remote[1] != "http://b:8990" {
+		t.Error("Incorrect remote workers ", remote.Remote, err)
+	}
+
+	pareto, err := parseOptions("pareto", []string{"-objectives", "sharpe,volatility", "-front", "front.jsonl",
+		"-generations", "5"}, ioutil.Discard)
+	if err != nil || pareto.Objectives != "sharpe,volatility" || pareto.FrontFile != "front.jsonl" ||
+		pareto.NumGenerations != 5 {
+		t.Error("Pareto flags not applied ", pareto, err)
+	}
+
+	if _, err := parseOptions("pareto", []string{"-objectives", "return"}, ioutil.Discard); err == nil {
+		t.Error("Expected a pareto front of a single objective to fail")
+	}
+
+	if _, err := parseOptions("pareto", []string{"-elitism", "2"}, ioutil.Discard); err == nil {
+		t.Error("Expected elitism to be rejected by pareto")
+	}
+
+	commission, err := parseOptions("backtest", []string{"-maker-fee", "0.0002", "-commission-asset", "BNB",
+		"-commission-balance", "5"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fees, _ := commission.MarketOptions()
+	if fees.MakerFee == nil || fees.MakerFee.String() != "0.0002" || fees.TakerFee != nil ||
+		fees.CommissionAsset != "BNB" || fees.CommissionDiscount.String() != "0.25" ||
+		fees.CommissionBalance.String() != "5" {
+		t.Error("Incorrect market options ", fees)
+	}
+
+	if quote, err := parseOptions("live", []string{"-quote", "BUSD"}, ioutil.Discard); err != nil ||
+		quote.QuoteAsset != "BUSD" {
+		t.Error("Expected a BUSD balance ", quote.QuoteAsset, err)
+	}
+
+	lifecycle, err := parseOptions("evolve", []string{"-unfilled-rate", "0.3", "-min-latency", "1m",
+		"-max-latency", "5m", "-expiry", "1h"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders, _ := lifecycle.MarketOptions(); orders.UnfilledRate != 0.3 || orders.MinLatency != time.Minute ||
+		orders.MaxLatency != 5*time.Minute || orders.Expiry != time.Hour {
+		t.Error("Incorrect order lifecycle ", orders)
+	}
+
+	for _, args := range [][]string{{"-unfilled-rate", "2"}, {"-min-latency", "5m", "-max-latency", "1m"},
+		{"-expiry", "soon"}} {
+		if _, err := parseOptions("backtest", args, ioutil.Discard); err == nil {
+			t.Error("Expected an invalid order lifecycle to fail ", args)
+		}
+	}
+
+	impact, err := parseOptions("live", []string{"-impact-bps", "5", "-impact-reference-notional", "2000"},
+		ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fillPriceModel, err := newFillPriceModel(impact); err != nil ||
+		!reflect.DeepEqual(fillPriceModel, market.NewVolumeImpact(5, 2000)) {
+		t.Error("Expected a volume impact fill price model ", fillPriceModel, err)
+	}
+
+	if _, err := parseOptions("backtest", []string{"-impact-bps", "5", "-slippage", "2"}, ioutil.Discard); err == nil {
+		t.Error("Expected slippage and volume impact together to fail")
+	}
+
+	paper, err := parseOptions("live", []string{"-maker-fee", "0.002", "-taker-fee", "0.0005"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paperOptions, _ := paper.MarketOptions()
+	if _, fee, err := newLiveMarket(paper, paperOptions); err != nil || fee.String() != "0.002" {
+		t.Error("Expected the paper accountant to reserve the maker fee ", fee, err)
+	}
+
+	if _, err := parseOptions("live", []string{"-commission-discount", "1"}, ioutil.Discard); err == nil {
+		t.Error("Expected a full commission discount to fail")
+	}
+
+	if _, err := parseOptions("trade", nil, ioutil.Discard); err == nil {
+		t.Error("Expected unknown command to fail")
+	}
+
+	if _, err := parseOptions("backtest", []string{"-h"}, ioutil.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Error("Expected help got ", err)
+	}
+}
+
+func TestParseOptionsConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "options")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	config := `{"server": "predictions.local", "strategy": "Basic", "fee": 0.00075, "params": [1, 2], "start": "2020-03-01",
+		"end": "2020-04-01"}`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := parseOptions("backtest", []string{"-config", path, "-fee", "0.002"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Server != "predictions.local" || options.Strategy != "Basic" || len(options.Params) != 2 {
+		t.Error("Config file not applied ", options)
+	}
+
+	if options.Fee != 0.002 {
+		t.Error("Flag did not override config file, fee ", options.Fee)
+	}
+
+	if options.Port != "8989" {
+		t.Error("Default lost when loading config file, port ", options.Port)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"unknown": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseOptions("backtest", []string{"-config", path}, ioutil.Discard); err == nil {
+		t.Error("Expected unknown config field to fail")
+	}
+}
@@ -0,0 +1,158 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"scoing-trader/trader/export"
+	"scoing-trader/trader/fitness"
+	"strings"
+)
+
+// Checkpoint is the state of an evolution after Generation generations: the population to test next, with the fitness
+// of the specimens carried over, the best specimens so far, the history and the seed the generations are drawn from.
+type Checkpoint struct {
+	Strategy       string               `json:"strategy"`
+	Seed           int64                `json:"seed"`
+	Generation     int                  `json:"generation"`
+	Population     []CheckpointSpecimen `json:"population"`
+	Best           CheckpointSpecimen   `json:"best"`
+	Selected       *CheckpointSpecimen  `json:"selected,omitempty"`
+	BestValidation float64              `json:"best_validation"`
+	History        []GenerationResult   `json:"history"`
+}
+
+type CheckpointSpecimen struct {
+	Fitness   float64   `json:"fitness"`
+	Config    []float64 `json:"config"`
+	Evaluated bool      `json:"evaluated,omitempty"`
+}
+
+func checkpointSpecimen(specimen Specimen) CheckpointSpecimen {
+	return CheckpointSpecimen{Fitness: specimen.Fitness, Config: specimen.Config.ToSlice(), Evaluated: specimen.evaluated}
+}
+
+func (evo *Evolution) specimen(saved CheckpointSpecimen) Specimen {
+	config := evo.factory.NewConfig()
+	config.FromSlice(saved.Config)
+	return Specimen{Fitness: saved.Fitness, Config: config, evaluated: saved.Evaluated}
+}
+
+// LoadCheckpoint reads the checkpoint at path, it is nil when there is none yet.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint to a temporary file renamed over path, so an interruption leaves the previous one whole.
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporary := path + ".tmp"
+	if err := ioutil.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, path)
+}
+
+// checkResume checks the checkpoint was written by an evolution of the same strategy and generation size.
+func (evo *Evolution) checkResume(checkpoint *Checkpoint) error {
+	numParams := len(evo.factory.NewConfig().Schema())
+
+	if checkpoint.Strategy != evo.StrategyName || len(checkpoint.Population) != evo.GenerationSize {
+		return errors.New(fmt.Sprintf("%s holds an evolution of %d %s specimens, not %d %s specimens",
+			evo.CheckpointFile, len(checkpoint.Population), checkpoint.Strategy, evo.GenerationSize, evo.StrategyName))
+	}
+
+	for _, specimen := range append(checkpoint.Population, checkpoint.Best) {
+		if len(specimen.Config) != numParams {
+			return errors.New(fmt.Sprintf("%s holds configs of %d params, %s has %d", evo.CheckpointFile,
+				len(specimen.Config), evo.StrategyName, numParams))
+		}
+	}
+
+	return nil
+}
+
+// saveHistory writes a row per generation with its fitness, diversity and best config, its params named by the schema,
+// in the format implied by the extension of the history file. Generations without validation have no out of sample
+// fitness.
+func (evo *Evolution) saveHistory() error {
+	names := append([]string{"generation", "in_sample", "out_of_sample", "mean_fitness", "diversity"},
+		evo.factory.NewConfig().Schema().Names()...)
+
+	columns := make([]export.Column, len(names))
+	for i, name := range names {
+		columns[i] = export.Column{Name: name, Type: export.Float}
+	}
+
+	table, err := export.CreateTable(evo.HistoryFile, export.FormatOf(evo.HistoryFile), columns)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range evo.History {
+		var outOfSample interface{}
+		if evo.Validation != nil {
+			outOfSample = result.OutOfSample
+		}
+
+		row := []interface{}{float64(result.Generation), result.InSample, outOfSample, result.MeanFitness,
+			result.Diversity}
+		for _, param := range result.Config {
+			row = append(row, param)
+		}
+
+		if err := table.WriteRow(row); err != nil {
+			table.Close()
+			return err
+		}
+	}
+
+	return table.Close()
+}
+
+// foldPath is the file of fold i of a walk forward, e.g. evolution_fold2.json for evolution.json.
+func foldPath(path string, fold int) string {
+	if path == "" {
+		return ""
+	}
+
+	extension := filepath.Ext(path)
+	return fmt.Sprintf("%s_fold%d%s", strings.TrimSuffix(path, extension), fold, extension)
+}
+
+// meanFitness is the mean fitness of the specimens that weren't rejected, Rejected when all were.
+func meanFitness(specimens []Specimen) float64 {
+	total, count := 0.0, 0
+	for _, specimen := range specimens {
+		if specimen.Fitness != fitness.Rejected {
+			total += specimen.Fitness
+			count++
+		}
+	}
+
+	if count == 0 {
+		return fitness.Rejected
+	}
+
+	return total / float64(count)
+}
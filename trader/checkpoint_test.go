@@ -0,0 +1,82 @@
+package trader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvolutionResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "evolution")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	evo := Evolution{
+		Dataset:        testDataset(),
+		InitialBalance: decimal.NewFromInt(1000),
+		Fee:            decimal.NewFromFloat(0.001),
+		GenerationSize: 4,
+		NumGenerations: 2,
+		MutationRate:   0.5,
+		Elitism:        1,
+		StrategyName:   "basic",
+		Seed:           7,
+		CheckpointFile: filepath.Join(dir, "evolution.json"),
+		HistoryFile:    filepath.Join(dir, "generations.csv"),
+	}
+
+	interrupted := evo
+	if _, err := interrupted.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := LoadCheckpoint(evo.CheckpointFile)
+	if err != nil || checkpoint == nil {
+		t.Fatal("Expected a checkpoint ", err)
+	}
+	if checkpoint.Generation != 2 || checkpoint.Seed != 7 || len(checkpoint.Population) != 4 ||
+		!checkpoint.Population[0].Evaluated || len(checkpoint.History) != 2 {
+		t.Fatal("Incorrect checkpoint ", checkpoint)
+	}
+
+	resumed := evo
+	resumed.NumGenerations = 3
+	resumed.Resume = true
+	best, err := resumed.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resumed.History) != 3 || resumed.History[2].Generation != 2 ||
+		fmt.Sprint(resumed.History[:2]) != fmt.Sprint(interrupted.History) {
+		t.Fatal("Expected the history to go on from the checkpoint ", resumed.History)
+	}
+	if best.Fitness < interrupted.History[0].InSample || best.Fitness < interrupted.History[1].InSample {
+		t.Error("Expected the best of all generations ", best.Fitness)
+	}
+
+	file, err := os.Open(evo.HistoryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][1] != "in_sample" || rows[3][0] != "2" || rows[3][2] != "" {
+		t.Error("Incorrect history ", rows)
+	}
+
+	resumed.StrategyName = "basic_with_memory"
+	if _, err := resumed.Run(); err == nil {
+		t.Error("Expected a checkpoint of another strategy to be refused")
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"fmt"
+	"github.com/go-pg/pg/v9"
+)
+
+// migrations are applied in order and never edited once released, new schema changes are appended.
+var migrations = []string{
+	`CREATE SCHEMA IF NOT EXISTS cointron`,
+	`CREATE TABLE IF NOT EXISTS cointron.trader_events (
+		event_id    TEXT PRIMARY KEY,
+		event_time  TIMESTAMPTZ NOT NULL,
+		event_type  TEXT NOT NULL,
+		coin        TEXT,
+		balance     NUMERIC NOT NULL DEFAULT 0,
+		net_worth   NUMERIC NOT NULL DEFAULT 0,
+		positions   TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS cointron.trader_config (
+		config_id     TEXT PRIMARY KEY,
+		creation_time TIMESTAMPTZ NOT NULL,
+		strategy_name TEXT NOT NULL,
+		fitness       DOUBLE PRECISION NOT NULL DEFAULT 0
+	)`,
+	`ALTER TABLE cointron.trader_events
+		ADD COLUMN IF NOT EXISTS qty         NUMERIC NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS value       NUMERIC NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS transaction NUMERIC NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS profit      NUMERIC NOT NULL DEFAULT 0`,
+	`ALTER TABLE cointron.trader_config ADD COLUMN IF NOT EXISTS config_params DOUBLE PRECISION[]`,
+	`CREATE INDEX IF NOT EXISTS trader_events_time_idx ON cointron.trader_events (event_time)`,
+	`CREATE INDEX IF NOT EXISTS trader_config_fitness_idx ON cointron.trader_config (strategy_name, fitness DESC)`,
+}
+
+// Migrate brings the schema up to date, recording the applied version in cointron.schema_migrations.
+func Migrate(db *pg.DB) (int, error) {
+	version := 0
+
+	err := db.RunInTransaction(func(tx *pg.Tx) error {
+		if _, err := tx.Exec(migrations[0]); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS cointron.schema_migrations (version INT NOT NULL)`); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`LOCK TABLE cointron.schema_migrations IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		if _, err := tx.QueryOne(pg.Scan(&version), `SELECT COALESCE(MAX(version), 0) FROM cointron.schema_migrations`); err != nil {
+			return err
+		}
+
+		for ; version < len(migrations); version++ {
+			if _, err := tx.Exec(migrations[version]); err != nil {
+				return fmt.Errorf("migration %d failed: %w", version+1, err)
+			}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM cointron.schema_migrations`); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`INSERT INTO cointron.schema_migrations VALUES (?)`, version)
+		return err
+	})
+
+	return version, err
+}
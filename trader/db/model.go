@@ -1,22 +1,32 @@
 package db
 
-import "time"
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+const SnapshotEvent = "SNAPSHOT"
 
 type TraderEvent struct {
-	tableName struct{}  `sql:"cointron.trader_events"`
-	Id        string    `sql:"event_id,pk"`
-	EventTime time.Time `sql:"event_time"`
-	Type      string    `sql:"event_type"`
-	Coin      string    `sql:"coin"`
-	Balance   int64     `sql:"balance,use_zero"`
-	Networth  int64     `sql:"net_worth,use_zero"`
-	Positions string    `sql:"positions"`
+	tableName   struct{}        `pg:"cointron.trader_events"`
+	Id          string          `pg:"event_id,pk"`
+	EventTime   time.Time       `pg:"event_time"`
+	Type        string          `pg:"event_type"`
+	Coin        string          `pg:"coin"`
+	Qty         decimal.Decimal `pg:"qty,use_zero"`
+	Value       decimal.Decimal `pg:"value,use_zero"`
+	Transaction decimal.Decimal `pg:"transaction,use_zero"`
+	Profit      decimal.Decimal `pg:"profit,use_zero"`
+	Balance     decimal.Decimal `pg:"balance,use_zero"`
+	Networth    decimal.Decimal `pg:"net_worth,use_zero"`
+	Positions   string          `pg:"positions"`
 }
 
 type TraderConfig struct {
-	tableName struct{}  `sql:"cointron.trader_config"`
-	Id        string    `sql:"config_id,pk"`
-	EventTime time.Time `sql:"creation_time"`
-	Strategy  string    `sql:"strategy_name"`
-	Fitness   int64     `sql:"fitness,use_zero"`
+	tableName struct{}  `pg:"cointron.trader_config"`
+	Id        string    `pg:"config_id,pk"`
+	EventTime time.Time `pg:"creation_time"`
+	Strategy  string    `pg:"strategy_name"`
+	Fitness   float64   `pg:"fitness,use_zero"`
+	Params    []float64 `pg:"config_params,array"`
 }
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"scoing-trader/trader/model/trader"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventRecorder stores the trades and snapshots of a trader in a Repository. Ids are prefixed with the run so events
+// of different runs never collide.
+type EventRecorder struct {
+	Repository Repository
+	RunId      string
+	mutex      sync.Mutex
+	count      int64
+}
+
+func NewEventRecorder(repository Repository) *EventRecorder {
+	return &EventRecorder{
+		Repository: repository,
+		RunId:      strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+func (r *EventRecorder) RecordTrade(record trader.TradeRecord, balance decimal.Decimal, netWorth decimal.Decimal,
+	positions map[string]decimal.Decimal) error {
+	positionsJson, err := json.Marshal(positions)
+	if err != nil {
+		return err
+	}
+
+	return r.Repository.SaveEvent(&TraderEvent{
+		Id:          r.nextId(),
+		EventTime:   record.Timestamp,
+		Type:        string(record.Event),
+		Coin:        record.Coin,
+		Qty:         record.Qty,
+		Value:       record.Value,
+		Transaction: record.Transaction,
+		Profit:      record.Profit,
+		Balance:     balance,
+		Networth:    netWorth,
+		Positions:   string(positionsJson),
+	})
+}
+
+func (r *EventRecorder) RecordSnapshot(timestamp time.Time, balance decimal.Decimal, netWorth decimal.Decimal,
+	positions map[string]map[string]decimal.Decimal) error {
+	positionsJson, err := json.Marshal(positions)
+	if err != nil {
+		return err
+	}
+
+	return r.Repository.SaveEvent(&TraderEvent{
+		Id:        r.nextId(),
+		EventTime: timestamp,
+		Type:      SnapshotEvent,
+		Balance:   balance,
+		Networth:  netWorth,
+		Positions: string(positionsJson),
+	})
+}
+
+func (r *EventRecorder) nextId() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.count++
+	return fmt.Sprintf("%s-%d", r.RunId, r.count)
+}
+
+// NewTraderConfig builds the row stored for an evolved strategy config. The id is derived from the parameters so
+// storing the same config twice only updates its fitness.
+func NewTraderConfig(strategy string, config trader.StrategyConfig, fitness float64, timestamp time.Time) *TraderConfig {
+	hash := sha1.Sum([]byte(fmt.Sprint(config.ToSlice())))
+
+	return &TraderConfig{
+		Id:        fmt.Sprintf("%s-%s", strategy, hex.EncodeToString(hash[:8])),
+		EventTime: timestamp,
+		Strategy:  strategy,
+		Fitness:   fitness,
+		Params:    config.ToSlice(),
+	}
+}
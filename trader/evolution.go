@@ -1,133 +1,451 @@
 package trader
 
 import (
+	"encoding/binary"
+	"errors"
 	"github.com/shopspring/decimal"
+	"hash/fnv"
 	"log"
+	"math"
 	"math/rand"
+	"scoing-trader/trader/db"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
 	"scoing-trader/trader/model/trader"
 	"scoing-trader/trader/model/trader/strategies"
-	"sort"
-	"sync"
 	"time"
 )
 
+// Evolution evolves strategy configs with a genetic algorithm. Every generation the Elitism fittest specimens survive
+// unchanged and the others are bred from Parents parents picked by Selection, combined by Crossover and, with
+// probability MutationRate, changed by Mutation. When the Diversity of a generation falls below MinDiversity a quarter
+// of the next one is drawn at random to keep the population from converging too early. Left empty, the operators
+// breed every child between the two fittest specimens as evolutions always did.
+//
+// Specimens are drawn and bred from Seed, the clock when 0, and every config is simulated with a seed of its own derived
+// from it, so an evolution with a given seed repeats exactly.
+//
+// Every generation is simulated by a pool of Workers, one per CPU when 0. Given Remote worker URLs, the pool sends the
+// configs to those workers instead, along with FitnessSpec and the Train range of Dataset since each worker holds the
+// predictions of the whole run.
+//
+// After every generation the population is saved to CheckpointFile with the Seed the generations are drawn from, and
+// the history to HistoryFile. With Resume set, an evolution finding a checkpoint goes on from it.
 type Evolution struct {
-	Predictions    []predictor.Prediction
+	Dataset        predictor.Dataset
+	Validation     predictor.Dataset
+	Fitness        fitness.Fitness
+	FitnessSpec    string
 	InitialBalance decimal.Decimal
 	Fee            decimal.Decimal
+	Market         MarketOptions
 	Uncertainty    float64
 	GenerationSize int
 	NumGenerations int
 	MutationRate   float64
+	Selection      Selection
+	Crossover      Crossover
+	Mutation       Mutation
+	Parents        int
+	Elitism        int
+	MinDiversity   float64
 	StartingPoint  []float64
 	StrategyName   string
+	Symbols        *model.SymbolRegistry
+	FillPriceModel market.FillPriceModel
+	Repository     db.Repository
+	Seed           int64
+	Workers        int
+	Remote         []string
+	Train          predictor.TimeRange
+	CheckpointFile string
+	HistoryFile    string
+	Resume         bool
+	History        []GenerationResult
+	factory        strategies.Factory
 }
 
 type Specimen struct {
 	Fitness float64
 	Config  trader.StrategyConfig
+	// Objectives are the scores of a multi-objective evolution, in the order of its objectives.
+	Objectives []float64
+	// evaluated specimens survived from the previous generation and keep their fitness.
+	evaluated bool
 }
 
-func (evo *Evolution) Run() Specimen {
-	rand.Seed(time.Now().UnixNano())
+// GenerationResult holds the fitness of the best specimen of a generation on the training predictions and, when the
+// evolution has a validation set, on predictions it was never selected on, along with the mean fitness and the
+// diversity of the generation.
+type GenerationResult struct {
+	Generation  int       `json:"generation"`
+	InSample    float64   `json:"in_sample"`
+	OutOfSample float64   `json:"out_of_sample"`
+	MeanFitness float64   `json:"mean_fitness"`
+	Diversity   float64   `json:"diversity"`
+	Config      []float64 `json:"config"`
+}
+
+type specimenResult struct {
+	index    int
+	specimen Specimen
+	err      error
+}
+
+func (evo *Evolution) Run() (Specimen, error) {
+	if err := evo.setup(); err != nil {
+		return Specimen{}, err
+	}
+
+	var specimenPool []Specimen
+	var best Specimen
+	var selected Specimen
+	bestValidation := math.Inf(-1)
+	start := 0
+	evo.History = nil
+
+	checkpoint, err := evo.resume()
+	if err != nil {
+		return Specimen{}, err
+	}
+
+	if checkpoint != nil {
+		evo.Seed = checkpoint.Seed
+		start = checkpoint.Generation
+		evo.History = checkpoint.History
+		best = evo.specimen(checkpoint.Best)
+		if checkpoint.Selected != nil {
+			selected = evo.specimen(*checkpoint.Selected)
+			bestValidation = checkpoint.BestValidation
+		}
+		for _, saved := range checkpoint.Population {
+			specimenPool = append(specimenPool, evo.specimen(saved))
+		}
+
+		log.Printf("Resuming evolution from %s after generation %d", evo.CheckpointFile, start-1)
+	} else {
+		if evo.Seed == 0 {
+			evo.Seed = time.Now().UnixNano()
+		}
+		log.Printf("Evolution seed %d", evo.Seed)
+		specimenPool = evo.firstGeneration(rand.New(rand.NewSource(evo.Seed)))
+	}
+
+	for i := start; i < evo.NumGenerations; i++ {
+		testedSpecimens, err := evo.simulateGeneration(specimenPool, i)
+		if err != nil {
+			return Specimen{}, err
+		}
+		ranked := byFitness(testedSpecimens)
+
+		result := GenerationResult{
+			Generation:  i,
+			InSample:    ranked[0].Fitness,
+			MeanFitness: meanFitness(ranked),
+			Diversity:   Diversity(ranked),
+			Config:      ranked[0].Config.ToSlice(),
+		}
+
+		if evo.Validation != nil {
+			result.OutOfSample, err = evo.evaluate(ranked[0], evo.Validation)
+			if err != nil {
+				return Specimen{}, err
+			}
+
+			// Keep the generation best that generalises best rather than the one fitting the training set best.
+			if result.OutOfSample > bestValidation {
+				bestValidation = result.OutOfSample
+				selected = ranked[0]
+			}
+
+			log.Printf("Generation %d Fitness: %.4f Validation: %.4f Diversity: %.4f", i, result.InSample,
+				result.OutOfSample, result.Diversity)
+		} else {
+			log.Printf("Generation %d Fitness: %.4f Diversity: %.4f", i, result.InSample, result.Diversity)
+		}
+		log.Println(result.Config)
+		evo.History = append(evo.History, result)
+		evo.saveSpecimen(ranked[0])
+
+		if i == 0 || ranked[0].Fitness > best.Fitness {
+			best = ranked[0]
+		} else {
+			log.Printf("New Generation worse than overall best (%.4f)", best.Fitness)
+		}
+
+		// Every generation breeds from its own seed so a resumed evolution goes on as the interrupted one would have.
+		random := rand.New(rand.NewSource(evo.Seed + int64(i) + 1))
+		specimenPool = evo.breed(ranked, i, result.Diversity, random)
+
+		if err := evo.checkpoint(i+1, specimenPool, best, selected, bestValidation); err != nil {
+			return Specimen{}, err
+		}
+	}
+
+	if evo.Validation != nil {
+		return selected, nil
+	}
+
+	return best, nil
+}
+
+// resume loads the checkpoint to go on from when the evolution resumes, nil when it starts afresh.
+func (evo *Evolution) resume() (*Checkpoint, error) {
+	if !evo.Resume || evo.CheckpointFile == "" {
+		return nil, nil
+	}
+
+	checkpoint, err := LoadCheckpoint(evo.CheckpointFile)
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+
+	return checkpoint, evo.checkResume(checkpoint)
+}
+
+// checkpoint saves the state after the given number of generations, and the history of those generations.
+func (evo *Evolution) checkpoint(generation int, population []Specimen, best Specimen, selected Specimen,
+	bestValidation float64) error {
+	if evo.HistoryFile != "" {
+		if err := evo.saveHistory(); err != nil {
+			return err
+		}
+	}
+
+	if evo.CheckpointFile == "" {
+		return nil
+	}
+
+	checkpoint := Checkpoint{
+		Strategy:   evo.StrategyName,
+		Seed:       evo.Seed,
+		Generation: generation,
+		Best:       checkpointSpecimen(best),
+		History:    evo.History,
+	}
+	for _, specimen := range population {
+		checkpoint.Population = append(checkpoint.Population, checkpointSpecimen(specimen))
+	}
+	if selected.Config != nil {
+		saved := checkpointSpecimen(selected)
+		checkpoint.Selected = &saved
+		checkpoint.BestValidation = bestValidation
+	}
+
+	return checkpoint.Save(evo.CheckpointFile)
+}
 
+// firstGeneration spreads the specimens around the starting point, each one more param away from it than the last, or
+// draws them over the param ranges without one.
+func (evo *Evolution) firstGeneration(random *rand.Rand) []Specimen {
 	var specimenPool []Specimen
-	var candidates []Specimen
+	schema := evo.factory.NewConfig().Schema()
 
 	for i := 0; i < evo.GenerationSize; i++ {
-		config := strategies.BasicWithMemoryConfig{}
+		config := evo.factory.NewConfig()
 		if evo.StartingPoint != nil {
-			config.FromSlice(evo.StartingPoint)
+			params := append([]float64{}, evo.StartingPoint...)
 			for j := 0; j < i; j++ {
-				config.RandomizeParam()
+				schema.RandomizeParam(params, random)
 			}
+			config.FromSlice(params)
 		} else {
-			config.RandomFromSlices(config.ParamRanges())
+			config.FromSlice(schema.Random(random))
 		}
 
 		specimenPool = append(specimenPool,
 			Specimen{
 				Fitness: 0.0,
-				Config:  &config,
+				Config:  config,
 			})
 	}
 
-	for i := 0; i < evo.NumGenerations; i++ {
-		testedSpecimens := evo.simulateGeneration(specimenPool)
-		newCandidates := evo.selectCandidates(testedSpecimens, 2)
+	return specimenPool
+}
 
-		log.Printf("Generation %d Fitness: %.4f", i, newCandidates[0].Fitness)
-		log.Println(newCandidates[0].Config.ToSlice())
+// setup fills in the defaults and looks up the strategy the specimens are configs of.
+func (evo *Evolution) setup() error {
+	if evo.StrategyName == "" {
+		evo.StrategyName = "basic_with_memory"
+	}
 
-		if len(candidates) == 0 || newCandidates[0].Fitness > candidates[0].Fitness {
-			candidates = newCandidates
-		} else {
-			log.Printf("New Generation worse than overall best (%.4f)", candidates[0].Fitness)
+	if evo.Fitness == nil && evo.FitnessSpec != "" {
+		parsed, err := fitness.Parse(evo.FitnessSpec)
+		if err != nil {
+			return err
 		}
+		evo.Fitness = parsed
+	}
+	if evo.Fitness == nil {
+		evo.Fitness = fitness.NetWorth
+		evo.FitnessSpec = "net_worth"
+	}
+	if len(evo.Remote) > 0 && evo.FitnessSpec == "" {
+		return errors.New("remote workers need the fitness spec of the evolution")
+	}
+
+	if evo.Selection == nil {
+		evo.Selection = Truncation{}
+	}
+	if evo.Crossover == nil {
+		evo.Crossover = BlendCrossover{}
+	}
+	if evo.Mutation == nil {
+		evo.Mutation = UniformMutation{}
+	}
+	if evo.Parents < 1 {
+		evo.Parents = 2
+	}
 
-		specimenPool = evo.breed(candidates)
+	factory, err := strategies.Lookup(evo.StrategyName)
+	if err != nil {
+		return err
 	}
+	evo.factory = factory
 
-	return candidates[0]
+	return nil
 }
 
-func (evo *Evolution) breed(candidates []Specimen) []Specimen {
+func (evo *Evolution) saveSpecimen(specimen Specimen) {
+	if evo.Repository == nil {
+		return
+	}
+
+	if err := evo.Repository.SaveConfig(db.NewTraderConfig(evo.StrategyName, specimen.Config, specimen.Fitness, time.Now())); err != nil {
+		log.Println("Failed saving evolved config: " + err.Error())
+	}
+}
+
+// breed makes the next generation from the ranked specimens of the last one.
+func (evo *Evolution) breed(ranked []Specimen, generation int, diversity float64, random *rand.Rand) []Specimen {
 	var newGeneration []Specimen
 
-	//rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
-	//TODO: Permitir mais de dois candidatos
-	for i := 0; i < evo.GenerationSize; i++ {
-		child := &strategies.BasicWithMemoryConfig{}
-		child.RandomFromSlices(candidates[0].Config.ToSlice(), candidates[1].Config.ToSlice())
+	for i := 0; i < evo.Elitism && i < len(ranked) && i < evo.GenerationSize; i++ {
+		elite := ranked[i]
+		elite.evaluated = true
+		newGeneration = append(newGeneration, elite)
+	}
 
-		if rand.Float64() <= evo.MutationRate {
-			for i := 0; i < (child.NumParams() / 3); i++ {
-				child.RandomizeParam()
-			}
-		}
+	immigrants := 0
+	if evo.MinDiversity > 0 && diversity < evo.MinDiversity {
+		immigrants = (evo.GenerationSize - len(newGeneration)) / 4
+		log.Printf("Diversity %.4f below %.4f, adding %d random specimens", diversity, evo.MinDiversity, immigrants)
+	}
 
-		newGeneration = append(newGeneration,
-			Specimen{
-				Fitness: 0.0,
-				Config:  child,
-			})
+	for len(newGeneration) < evo.GenerationSize-immigrants {
+		parents := evo.Selection.Select(ranked, evo.Parents, random)
+		newGeneration = append(newGeneration, evo.child(parents, generation, random))
+	}
+
+	for len(newGeneration) < evo.GenerationSize {
+		immigrant := evo.factory.NewConfig()
+		immigrant.FromSlice(immigrant.Schema().Random(random))
+		newGeneration = append(newGeneration, Specimen{Fitness: 0.0, Config: immigrant})
 	}
 
 	return newGeneration
 }
 
-func (evo *Evolution) simulateGeneration(untestedSpecimens []Specimen) []Specimen {
-	var testedSpecimens []Specimen
+// child crosses the parents and mutates the child at the mutation rate. The operators work on the unit cube of the
+// schema, so log scale params are bred by order of magnitude and the child snaps to the steps of its params.
+func (evo *Evolution) child(parents []Specimen, generation int, random *rand.Rand) Specimen {
+	schema := evo.factory.NewConfig().Schema()
 
-	resultChan := make(chan Specimen, evo.GenerationSize)
-	var wg sync.WaitGroup
+	min, max := make([]float64, len(schema)), make([]float64, len(schema))
+	for i := range max {
+		max[i] = 1
+	}
 
-	for _, specimen := range untestedSpecimens {
-		wg.Add(1)
-		go evo.runSingleSimulation(specimen, &evo.Predictions, resultChan, &wg)
+	params := make([][]float64, len(parents))
+	for i, parent := range parents {
+		params[i] = schema.ToUnit(parent.Config.ToSlice())
 	}
 
-	for i := 0; i < evo.GenerationSize; i++ {
-		testedSpecimens = append(testedSpecimens, <-resultChan)
+	childParams := evo.Crossover.Cross(params, min, max, random)
+	if random.Float64() <= evo.MutationRate {
+		evo.Mutation.Mutate(childParams, min, max, generation, random)
 	}
 
-	return testedSpecimens
+	child := evo.factory.NewConfig()
+	child.FromSlice(schema.FromUnit(childParams))
+	return Specimen{Fitness: 0.0, Config: child}
 }
 
-func (evo *Evolution) runSingleSimulation(specimen Specimen, predictions *[]predictor.Prediction, out chan<- Specimen, wg *sync.WaitGroup) {
-	defer wg.Done()
-	strategy := strategies.NewBasicWithMemoryStrategy(specimen.Config.ToSlice(), 10)
-	sim := NewSimulation(predictions, strategy, specimen.Config, evo.InitialBalance, evo.Fee, evo.Uncertainty, false, false)
-	sim.Run()
-	nw, _ := sim.Trader.Accountant.NetWorth().Float64()
-	specimen.Fitness = nw
-	out <- specimen
-}
+// simulateGeneration scores the specimens not evaluated yet, keeping them in the order of the pool whichever finishes
+// first so that ties rank the same in every run.
+func (evo *Evolution) simulateGeneration(untestedSpecimens []Specimen, generation int) ([]Specimen, error) {
+	testedSpecimens := make([]Specimen, len(untestedSpecimens))
+	jobs := make([]evaluationJob, 0, len(untestedSpecimens))
 
-func (evo *Evolution) selectCandidates(specimens []Specimen, numCandidates int) []Specimen {
-	sort.Slice(specimens, func(i, j int) bool {
-		return specimens[i].Fitness > specimens[j].Fitness
+	for i, specimen := range untestedSpecimens {
+		if specimen.evaluated {
+			testedSpecimens[i] = specimen
+		} else {
+			jobs = append(jobs, evaluationJob{index: i, specimen: specimen})
+		}
+	}
+
+	remotes := make([]*RemoteWorker, len(evo.Remote))
+	for i, url := range evo.Remote {
+		remotes[i] = NewRemoteWorker(url)
+	}
+
+	results, err := evo.evaluatePool(jobs, generation, func(specimen Specimen, worker int) (Specimen, error) {
+		var err error
+		if len(remotes) > 0 {
+			specimen.Fitness, err = evo.evaluateRemote(specimen, remotes, worker)
+		} else {
+			specimen.Fitness, err = evo.evaluate(specimen, evo.Dataset)
+		}
+		return specimen, err
 	})
-	return specimens[0:numCandidates]
+	for _, result := range results {
+		testedSpecimens[result.index] = result.specimen
+	}
+
+	return testedSpecimens, err
+}
+
+// evaluate runs the specimen over the predictions of dataset and scores its equity curve.
+func (evo *Evolution) evaluate(specimen Specimen, dataset predictor.Dataset) (float64, error) {
+	sim, err := evo.simulate(specimen, dataset)
+	if err != nil {
+		return 0, err
+	}
+
+	return evo.Fitness.Score(sim.Curve), nil
+}
+
+// simulationSeed derives the seed of the simulations of a config from the evolution seed and the config alone, so a
+// config scores the same whichever specimen, generation or worker runs it.
+func (evo *Evolution) simulationSeed(config []float64) int64 {
+	hash := fnv.New64a()
+	binary.Write(hash, binary.LittleEndian, evo.Seed)
+	binary.Write(hash, binary.LittleEndian, config)
+	return int64(hash.Sum64())
+}
+
+func (evo *Evolution) simulate(specimen Specimen, dataset predictor.Dataset) (*Simulation, error) {
+	source, err := dataset.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := evo.factory.NewStrategy(specimen.Config.ToSlice())
+	sim := NewSimulation(source, strategy, specimen.Config, evo.InitialBalance, evo.Fee, evo.Market,
+		evo.Uncertainty, false, false)
+	sim.SetSeed(evo.simulationSeed(specimen.Config.ToSlice()))
+	if evo.Symbols != nil {
+		sim.SetSymbolRegistry(evo.Symbols)
+	}
+	if evo.FillPriceModel != nil {
+		sim.Market.SetFillPriceModel(evo.FillPriceModel)
+	}
+	if err := sim.Run(); err != nil {
+		return nil, err
+	}
+
+	return sim, nil
 }
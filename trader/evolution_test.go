@@ -0,0 +1,38 @@
+package trader
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestEvolutionSeed(t *testing.T) {
+	evolve := func(seed int64) Evolution {
+		evo := Evolution{
+			Dataset:        testDataset(),
+			InitialBalance: decimal.NewFromInt(1000),
+			Fee:            decimal.NewFromFloat(0.001),
+			Uncertainty:    0.2,
+			GenerationSize: 4,
+			NumGenerations: 2,
+			MutationRate:   0.5,
+			Selection:      Tournament{Size: 2},
+			Mutation:       GaussianMutation{Probability: 0.5, Sigma: 0.1, Decay: 1},
+			StrategyName:   "basic_with_memory",
+			Seed:           seed,
+		}
+		if _, err := evo.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return evo
+	}
+
+	first, second, other := evolve(11), evolve(11), evolve(12)
+
+	if fmt.Sprint(first.History) != fmt.Sprint(second.History) {
+		t.Error("Expected evolutions with the same seed to repeat ", first.History, second.History)
+	}
+	if fmt.Sprint(first.History) == fmt.Sprint(other.History) {
+		t.Error("Expected another seed to evolve other configs ", first.History)
+	}
+}
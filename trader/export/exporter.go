@@ -0,0 +1,231 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"scoing-trader/trader/model/trader"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	EquityTable    = "equity"
+	TradesTable    = "trades"
+	DecisionsTable = "decisions"
+)
+
+var Tables = []string{EquityTable, TradesTable, DecisionsTable}
+
+// Config of an export. The equity table is written to Path, the other tables next to it with their name appended, e.g.
+// result_trades.csv. Equity snapshots are taken at most once per Interval, at every timestamp when it is 0, with a
+// price and position column for each of the Coins.
+type Config struct {
+	Path     string
+	Format   Format
+	Interval time.Duration
+	Tables   []string
+	Coins    []string
+}
+
+// ParseTables reads a comma separated list of tables.
+func ParseTables(list string) ([]string, error) {
+	tables := make([]string, 0)
+
+	for _, table := range strings.Split(list, ",") {
+		table = strings.TrimSpace(table)
+		if table == "" {
+			continue
+		}
+
+		known := false
+		for _, name := range Tables {
+			known = known || table == name
+		}
+		if !known {
+			return nil, errors.New(fmt.Sprintf("unknown export table %q, expected one of %s", table,
+				strings.Join(Tables, ", ")))
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+func (c Config) TablePath(table string) string {
+	if table == EquityTable {
+		return c.Path
+	}
+
+	extension := filepath.Ext(c.Path)
+	return strings.TrimSuffix(c.Path, extension) + "_" + table + extension
+}
+
+func (c Config) Exports(table string) bool {
+	for _, exported := range c.Tables {
+		if exported == table {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot of the account, with the price of every coin and the value held in it.
+type Snapshot struct {
+	Timestamp time.Time
+	Balance   float64
+	NetWorth  float64
+	Prices    map[string]float64
+	Values    map[string]float64
+}
+
+var tradeColumns = []Column{
+	{"timestamp", Time}, {"coin", String}, {"event", String}, {"qty", Float}, {"price", Float},
+	{"transaction", Float}, {"profit", Float},
+}
+
+var decisionColumns = []Column{
+	{"timestamp", Time}, {"coin", String}, {"event", String}, {"qty", Float}, {"price", Float},
+	{"buy_conf", Float}, {"sell_conf", Float}, {"debug", String},
+}
+
+// Exporter writes what a run did to the configured tables as it happens.
+type Exporter struct {
+	Config
+	coins     []string
+	equity    TableWriter
+	trades    TableWriter
+	decisions TableWriter
+	sampled   bool
+	lastBin   time.Time
+}
+
+func New(config Config) (*Exporter, error) {
+	if config.Format == "" {
+		config.Format = FormatOf(config.Path)
+	}
+
+	exporter := &Exporter{Config: config, coins: append([]string{}, config.Coins...)}
+	sort.Strings(exporter.coins)
+
+	var err error
+	if config.Exports(EquityTable) {
+		columns := []Column{{"timestamp", Time}, {"balance", Float}, {"net_worth", Float}}
+		for _, coin := range exporter.coins {
+			columns = append(columns, Column{coin, Float}, Column{coin + "_positions", Float})
+		}
+
+		if exporter.equity, err = CreateTable(config.TablePath(EquityTable), config.Format, columns); err != nil {
+			return nil, err
+		}
+	}
+	if config.Exports(TradesTable) {
+		if exporter.trades, err = CreateTable(config.TablePath(TradesTable), config.Format, tradeColumns); err != nil {
+			exporter.Close()
+			return nil, err
+		}
+	}
+	if config.Exports(DecisionsTable) {
+		exporter.decisions, err = CreateTable(config.TablePath(DecisionsTable), config.Format, decisionColumns)
+		if err != nil {
+			exporter.Close()
+			return nil, err
+		}
+	}
+
+	return exporter, nil
+}
+
+// Due reports whether a snapshot should be taken at timestamp, i.e. none was in its interval yet. Timestamps must not
+// go back in time.
+func (e *Exporter) Due(timestamp time.Time) bool {
+	bin := timestamp
+	if e.Interval > 0 {
+		bin = timestamp.Truncate(e.Interval)
+	}
+
+	if e.sampled && !bin.After(e.lastBin) {
+		return false
+	}
+
+	e.sampled = true
+	e.lastBin = bin
+
+	return true
+}
+
+// WriteSnapshot writes a row of the equity table, failing for a coin without columns.
+func (e *Exporter) WriteSnapshot(snapshot Snapshot) error {
+	if e.equity == nil {
+		return nil
+	}
+
+	for coin := range snapshot.Prices {
+		index := sort.SearchStrings(e.coins, coin)
+		if index == len(e.coins) || e.coins[index] != coin {
+			return errors.New(fmt.Sprintf("coin %s has no column in the equity table", coin))
+		}
+	}
+
+	row := []interface{}{snapshot.Timestamp, snapshot.Balance, snapshot.NetWorth}
+	for _, coin := range e.coins {
+		row = append(row, optional(snapshot.Prices, coin), optional(snapshot.Values, coin))
+	}
+
+	return e.equity.WriteRow(row)
+}
+
+// WriteRecord writes a decision of the trader, to the trades table as well when it bought or sold.
+func (e *Exporter) WriteRecord(record trader.TradeRecord) error {
+	qty, _ := record.Qty.Float64()
+	price, _ := record.Value.Float64()
+
+	if e.trades != nil && record.Event != trader.HOLD {
+		transaction, _ := record.Transaction.Float64()
+		profit, _ := record.Profit.Float64()
+
+		err := e.trades.WriteRow([]interface{}{record.Timestamp, record.Coin, string(record.Event), qty, price,
+			transaction, profit})
+		if err != nil {
+			return err
+		}
+	}
+
+	if e.decisions != nil {
+		return e.decisions.WriteRow([]interface{}{record.Timestamp, record.Coin, string(record.Event), qty, price,
+			record.BuyConf, record.SellConf, record.DebugText})
+	}
+
+	return nil
+}
+
+// Close closes every table, the first error is returned once all were closed.
+func (e *Exporter) Close() error {
+	var closeErr error
+	keep := func(err error) {
+		if err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
+	if e.equity != nil {
+		keep(e.equity.Close())
+	}
+	if e.trades != nil {
+		keep(e.trades.Close())
+	}
+	if e.decisions != nil {
+		keep(e.decisions.Close())
+	}
+
+	return closeErr
+}
+
+func optional(values map[string]float64, key string) interface{} {
+	if value, known := values[key]; known {
+		return value
+	}
+	return nil
+}
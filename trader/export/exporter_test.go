@@ -0,0 +1,101 @@
+package export
+
+import (
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"scoing-trader/trader/model/trader"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	exporter, err := New(Config{
+		Path:     filepath.Join(dir, "result.csv"),
+		Interval: time.Hour,
+		Tables:   []string{EquityTable, TradesTable},
+		Coins:    []string{"ETHUSDT", "BTCUSDT"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for minute := 0; minute < 150; minute += 30 {
+		timestamp := day.Add(time.Duration(minute) * time.Minute)
+		if !exporter.Due(timestamp) {
+			continue
+		}
+
+		snapshot := Snapshot{Timestamp: timestamp, Balance: 100, NetWorth: 100,
+			Prices: map[string]float64{"BTCUSDT": 7000}, Values: map[string]float64{"BTCUSDT": 0}}
+		if minute > 0 {
+			snapshot.Prices["ETHUSDT"] = 130
+		}
+		if err := exporter.WriteSnapshot(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unknown := Snapshot{Timestamp: day.Add(3 * time.Hour), Prices: map[string]float64{"BNBUSDT": 20}}
+	if err := exporter.WriteSnapshot(unknown); err == nil {
+		t.Error("Expected a coin without columns to fail")
+	}
+
+	for _, event := range []trader.DecisionType{trader.BUY, trader.HOLD, trader.SELL} {
+		record := trader.TradeRecord{Timestamp: day, Coin: "BTCUSDT", Event: event, Qty: decimal.NewFromFloat(0.5),
+			Value: decimal.NewFromInt(7000)}
+		if err := exporter.WriteRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	equity, err := ioutil.ReadFile(filepath.Join(dir, "result.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "timestamp,balance,net_worth,BTCUSDT,BTCUSDT_positions,ETHUSDT,ETHUSDT_positions\n" +
+		"2020-01-01T00:00:00Z,100,100,7000,0,,\n" +
+		"2020-01-01T01:00:00Z,100,100,7000,0,130,\n" +
+		"2020-01-01T02:00:00Z,100,100,7000,0,130,\n"
+	if string(equity) != expected {
+		t.Error("Incorrect equity table ", string(equity))
+	}
+
+	trades, err := ioutil.ReadFile(filepath.Join(dir, "result_trades.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(string(trades)), "\n"); len(lines) != 3 || !strings.Contains(lines[2], "SELL") {
+		t.Error("Expected the buy and the sell in the trades table ", lines)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "result_decisions.csv")); !os.IsNotExist(err) {
+		t.Error("Expected no decisions table")
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	tables, err := ParseTables("equity, decisions")
+	if err != nil || len(tables) != 2 || tables[1] != DecisionsTable {
+		t.Error("Incorrect tables ", tables, err)
+	}
+
+	if _, err := ParseTables("equity,orders"); err == nil {
+		t.Error("Expected an unknown table to fail")
+	}
+}
@@ -0,0 +1,219 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+)
+
+var Formats = []Format{CSV, JSONLines}
+
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if Format(strings.ToLower(name)) == format {
+			return format, nil
+		}
+	}
+
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+
+	return "", errors.New(fmt.Sprintf("unknown export format %q, expected one of %s", name, strings.Join(names, ", ")))
+}
+
+// FormatOf is the format implied by the extension of path, CSV when it names none.
+func FormatOf(path string) Format {
+	format, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return CSV
+	}
+	return format
+}
+
+type ColumnType int
+
+const (
+	String ColumnType = iota
+	Float
+	Time
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// TableWriter writes rows holding a value per column: a string, float64 or time.Time matching the column type, or nil
+// when the value is unknown.
+type TableWriter interface {
+	WriteRow(row []interface{}) error
+	Close() error
+}
+
+func NewTableWriter(w io.Writer, format Format, columns []Column) (TableWriter, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case JSONLines:
+		return &jsonLinesWriter{w: bufio.NewWriter(w), columns: columns}, nil
+	default:
+		_, err := ParseFormat(string(format))
+		return nil, err
+	}
+}
+
+// CreateTable writes the table to a new file at path, closing the writer closes the file.
+func CreateTable(path string, format Format, columns []Column) (TableWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewTableWriter(file, format, columns)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileTable{TableWriter: writer, file: file}, nil
+}
+
+type fileTable struct {
+	TableWriter
+	file *os.File
+}
+
+func (f *fileTable) Close() error {
+	if err := f.TableWriter.Close(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+func checkRow(columns []Column, row []interface{}) error {
+	if len(row) != len(columns) {
+		return errors.New(fmt.Sprintf("row has %d values for %d columns", len(row), len(columns)))
+	}
+
+	for i, value := range row {
+		valid := true
+		switch value.(type) {
+		case nil:
+		case string:
+			valid = columns[i].Type == String
+		case float64:
+			valid = columns[i].Type == Float
+		case time.Time:
+			valid = columns[i].Type == Time
+		default:
+			valid = false
+		}
+
+		if !valid {
+			return errors.New(fmt.Sprintf("invalid value %v for column %s", value, columns[i].Name))
+		}
+	}
+
+	return nil
+}
+
+type csvWriter struct {
+	writer  *csv.Writer
+	columns []Column
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+
+	return &csvWriter{writer: writer, columns: columns}, writer.Write(header)
+}
+
+func (c *csvWriter) WriteRow(row []interface{}) error {
+	if err := checkRow(c.columns, row); err != nil {
+		return err
+	}
+
+	record := make([]string, len(row))
+	for i, value := range row {
+		switch value := value.(type) {
+		case string:
+			record[i] = value
+		case float64:
+			record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		case time.Time:
+			record[i] = value.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return c.writer.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// jsonLinesWriter writes an object per row with the values in column order, unknown and non finite values are null.
+type jsonLinesWriter struct {
+	w       *bufio.Writer
+	columns []Column
+}
+
+func (j *jsonLinesWriter) WriteRow(row []interface{}) error {
+	if err := checkRow(j.columns, row); err != nil {
+		return err
+	}
+
+	j.w.WriteByte('{')
+	for i, value := range row {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(j.columns[i].Name)
+		j.w.Write(name)
+		j.w.WriteByte(':')
+
+		if number, ok := value.(float64); value == nil || ok && (math.IsNaN(number) || math.IsInf(number, 0)) {
+			j.w.WriteString("null")
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		j.w.Write(encoded)
+	}
+	j.w.WriteByte('}')
+
+	return j.w.WriteByte('\n')
+}
+
+func (j *jsonLinesWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+var testColumns = []Column{{"timestamp", Time}, {"coin", String}, {"price", Float}}
+
+func writeTable(t *testing.T, format Format) []byte {
+	var output bytes.Buffer
+
+	writer, err := NewTableWriter(&output, format, testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := [][]interface{}{
+		{day, "BTCUSDT", 7000.5},
+		{day.Add(time.Hour), "ETH,USDT", nil},
+		{day.Add(2 * time.Hour), nil, math.NaN()},
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.WriteRow([]interface{}{day, "BTCUSDT", "7000"}); err == nil {
+		t.Error("Expected a string in a float column to fail")
+	}
+	if err := writer.WriteRow([]interface{}{day}); err == nil {
+		t.Error("Expected a short row to fail")
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return output.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	expected := "timestamp,coin,price\n" +
+		"2020-01-01T00:00:00Z,BTCUSDT,7000.5\n" +
+		"2020-01-01T01:00:00Z,\"ETH,USDT\",\n" +
+		"2020-01-01T02:00:00Z,,NaN\n"
+
+	if output := string(writeTable(t, CSV)); output != expected {
+		t.Error("Incorrect CSV ", output)
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	expected := `{"timestamp":"2020-01-01T00:00:00Z","coin":"BTCUSDT","price":7000.5}` + "\n" +
+		`{"timestamp":"2020-01-01T01:00:00Z","coin":"ETH,USDT","price":null}` + "\n" +
+		`{"timestamp":"2020-01-01T02:00:00Z","coin":null,"price":null}` + "\n"
+
+	if output := string(writeTable(t, JSONLines)); output != expected {
+		t.Error("Incorrect JSON lines ", output)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	if FormatOf("out/result.jsonl") != JSONLines || FormatOf("result.JSONL") != JSONLines || FormatOf("result") != CSV {
+		t.Error("Incorrect formats from extensions")
+	}
+
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("Expected an unknown format to fail")
+	}
+}
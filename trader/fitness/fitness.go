@@ -0,0 +1,175 @@
+package fitness
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"scoing-trader/trader/metrics"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rejected is the score of a run failing a constraint such as MinTrades. It stays finite so it can be saved as JSON.
+const Rejected = -math.MaxFloat64
+
+// MaxProfitFactor caps the profit factor of runs that never sold at a loss.
+const MaxProfitFactor = 10
+
+// Fitness scores the outcome of a simulation, higher is better.
+type Fitness interface {
+	Score(curve *metrics.Curve) float64
+}
+
+type Func func(curve *metrics.Curve) float64
+
+func (f Func) Score(curve *metrics.Curve) float64 {
+	return f(curve)
+}
+
+// NetWorth is the final net worth of the run, what evolutions were always scored on.
+var NetWorth = Func(func(curve *metrics.Curve) float64 {
+	return curve.FinalNetWorth()
+})
+
+var Return = Func(func(curve *metrics.Curve) float64 {
+	return curve.TotalReturn()
+})
+
+var Sharpe = Func(func(curve *metrics.Curve) float64 {
+	return curve.Sharpe()
+})
+
+var Sortino = Func(func(curve *metrics.Curve) float64 {
+	return curve.Sortino()
+})
+
+var Calmar = Func(func(curve *metrics.Curve) float64 {
+	return curve.Calmar()
+})
+
+var ProfitFactor = Func(func(curve *metrics.Curve) float64 {
+	return math.Min(curve.ProfitFactor(), MaxProfitFactor)
+})
+
+// DrawdownPenalised is the total return less penalty times the max drawdown.
+func DrawdownPenalised(penalty float64) Fitness {
+	return Func(func(curve *metrics.Curve) float64 {
+		return curve.TotalReturn() - penalty*curve.MaxDrawdown()
+	})
+}
+
+// MinTrades rejects runs with fewer than count trades and scores 0 otherwise, it is meant to be combined with others.
+func MinTrades(count int) Fitness {
+	return Func(func(curve *metrics.Curve) float64 {
+		if curve.NumTrades() < count {
+			return Rejected
+		}
+		return 0
+	})
+}
+
+type Component struct {
+	Fitness Fitness
+	Weight  float64
+}
+
+type weighted []Component
+
+// Combine sums the weighted scores of the components, a run rejected by any of them is rejected.
+func Combine(components ...Component) Fitness {
+	return weighted(components)
+}
+
+func (w weighted) Score(curve *metrics.Curve) float64 {
+	score := 0.0
+
+	for _, component := range w {
+		componentScore := component.Fitness.Score(curve)
+		if componentScore == Rejected {
+			return Rejected
+		}
+		score += component.Weight * componentScore
+	}
+
+	return score
+}
+
+var builtins = map[string]func(arg float64) Fitness{
+	"net_worth":     func(float64) Fitness { return NetWorth },
+	"return":        func(float64) Fitness { return Return },
+	"sharpe":        func(float64) Fitness { return Sharpe },
+	"sortino":       func(float64) Fitness { return Sortino },
+	"calmar":        func(float64) Fitness { return Calmar },
+	"profit_factor": func(float64) Fitness { return ProfitFactor },
+	"drawdown_return": func(penalty float64) Fitness {
+		return DrawdownPenalised(penalty)
+	},
+	"min_trades": func(count float64) Fitness {
+		return MinTrades(int(count))
+	},
+}
+
+// defaultArgs of the built-ins taking an argument, the others take none.
+var defaultArgs = map[string]float64{
+	"drawdown_return": 1,
+	"min_trades":      1,
+}
+
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse builds a fitness from a sum of optionally weighted built-ins, e.g. "0.7*sortino+0.3*calmar+min_trades(20)".
+// drawdown_return takes the drawdown penalty and min_trades the trade count between parentheses.
+func Parse(spec string) (Fitness, error) {
+	components := make([]Component, 0)
+
+	for _, term := range strings.Split(spec, "+") {
+		term = strings.TrimSpace(term)
+		weight := 1.0
+
+		if separator := strings.Index(term, "*"); separator >= 0 {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(term[:separator]), 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid weight in fitness term %q", term))
+			}
+			weight = parsed
+			term = strings.TrimSpace(term[separator+1:])
+		}
+
+		name := term
+		arg := defaultArgs[name]
+
+		if open := strings.Index(term, "("); open >= 0 && strings.HasSuffix(term, ")") {
+			name = strings.TrimSpace(term[:open])
+			if _, takesArg := defaultArgs[name]; !takesArg {
+				return nil, errors.New(fmt.Sprintf("fitness %s takes no argument", name))
+			}
+
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(term[open+1:len(term)-1]), 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid argument in fitness term %q", term))
+			}
+			arg = parsed
+		}
+
+		builtin, exists := builtins[name]
+		if !exists {
+			return nil, errors.New(fmt.Sprintf("unknown fitness %q, expected one of %s", name, strings.Join(Names(), ", ")))
+		}
+
+		components = append(components, Component{Fitness: builtin(arg), Weight: weight})
+	}
+
+	if len(components) == 1 && components[0].Weight == 1 {
+		return components[0].Fitness, nil
+	}
+
+	return Combine(components...), nil
+}
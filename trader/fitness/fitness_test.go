@@ -0,0 +1,59 @@
+package fitness
+
+import (
+	"math"
+	"scoing-trader/trader/metrics"
+	"scoing-trader/trader/model/trader"
+	"testing"
+	"time"
+)
+
+func testCurve(trades int, values ...float64) *metrics.Curve {
+	curve := metrics.NewCurve()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, value := range values {
+		curve.Add(start.Add(time.Duration(i)*time.Hour), value)
+	}
+	for i := 0; i < trades; i++ {
+		curve.Trades = append(curve.Trades, metrics.Trade{Timestamp: start, Event: trader.BUY})
+	}
+	return curve
+}
+
+func TestParse(t *testing.T) {
+	curve := testCurve(3, 100, 120, 90, 110)
+
+	single, err := Parse("net_worth")
+	if err != nil || single.Score(curve) != 110 {
+		t.Error("Incorrect net worth fitness ", err)
+	}
+
+	combined, err := Parse("0.5 * return + drawdown_return(2) + min_trades(3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := 0.5*0.1 + 0.1 - 2*0.25
+	if score := combined.Score(curve); math.Abs(score-expected) > 1e-9 {
+		t.Error("Incorrect combined fitness ", score, expected)
+	}
+
+	if strict, _ := Parse("return+min_trades(4)"); strict.Score(curve) != Rejected {
+		t.Error("Expected too few trades to be rejected")
+	}
+
+	for _, spec := range []string{"martingale", "sharpe(2)", "x*calmar", "min_trades(x)"} {
+		if _, err := Parse(spec); err == nil {
+			t.Error("Expected invalid fitness to fail ", spec)
+		}
+	}
+}
+
+func TestProfitFactorCap(t *testing.T) {
+	curve := testCurve(0, 100, 110)
+	curve.Trades = append(curve.Trades, metrics.Trade{Event: trader.SELL, Profit: 10})
+
+	if ProfitFactor.Score(curve) != MaxProfitFactor {
+		t.Error("Expected profit factor without losses to be capped, got ", ProfitFactor.Score(curve))
+	}
+}
@@ -0,0 +1,337 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"scoing-trader/trader/fitness"
+	"sort"
+)
+
+// Selection picks the parents of a child from a tested population.
+type Selection interface {
+	Select(population []Specimen, count int, random *rand.Rand) []Specimen
+}
+
+// Crossover combines the params of the parents into those of a child, within the param ranges.
+type Crossover interface {
+	Cross(parents [][]float64, min []float64, max []float64, random *rand.Rand) []float64
+}
+
+// Mutation changes the params of a child in place. Generation counts from 0 so the mutation can narrow as the evolution
+// goes.
+type Mutation interface {
+	Mutate(params []float64, min []float64, max []float64, generation int, random *rand.Rand)
+}
+
+// Truncation selects the fittest specimens, always the same ones for a population.
+type Truncation struct{}
+
+func (Truncation) Select(population []Specimen, count int, random *rand.Rand) []Specimen {
+	sorted := byFitness(population)
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
+}
+
+// Tournament selects each parent as the fittest of Size specimens drawn at random.
+type Tournament struct {
+	Size int
+}
+
+func (t Tournament) Select(population []Specimen, count int, random *rand.Rand) []Specimen {
+	selected := make([]Specimen, count)
+
+	for i := range selected {
+		best := population[random.Intn(len(population))]
+		for j := 1; j < t.Size; j++ {
+			if contender := population[random.Intn(len(population))]; contender.Fitness > best.Fitness {
+				best = contender
+			}
+		}
+		selected[i] = best
+	}
+
+	return selected
+}
+
+// Roulette selects parents with a probability growing with their fitness above the worst one. Rejected specimens are
+// only picked when every specimen was rejected.
+type Roulette struct{}
+
+func (Roulette) Select(population []Specimen, count int, random *rand.Rand) []Specimen {
+	eligible := make([]Specimen, 0, len(population))
+	for _, specimen := range population {
+		if specimen.Fitness != fitness.Rejected {
+			eligible = append(eligible, specimen)
+		}
+	}
+	if len(eligible) == 0 {
+		eligible = population
+	}
+
+	worst, best := math.Inf(1), math.Inf(-1)
+	for _, specimen := range eligible {
+		worst = math.Min(worst, specimen.Fitness)
+		best = math.Max(best, specimen.Fitness)
+	}
+
+	// The worst specimen keeps a small chance, and equal fitness gives every specimen the same one.
+	floor := (best - worst) / float64(len(eligible))
+	if floor == 0 {
+		floor = 1
+	}
+
+	weights := make([]float64, len(eligible))
+	for i, specimen := range eligible {
+		weights[i] = specimen.Fitness - worst + floor
+	}
+
+	return spin(eligible, weights, count, random)
+}
+
+// Rank selects parents with a probability decreasing linearly with their rank, whatever the scale of the fitness.
+type Rank struct{}
+
+func (Rank) Select(population []Specimen, count int, random *rand.Rand) []Specimen {
+	sorted := byFitness(population)
+
+	weights := make([]float64, len(sorted))
+	for i := range sorted {
+		weights[i] = float64(len(sorted) - i)
+	}
+
+	return spin(sorted, weights, count, random)
+}
+
+func spin(specimens []Specimen, weights []float64, count int, random *rand.Rand) []Specimen {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	selected := make([]Specimen, count)
+	for i := range selected {
+		target := random.Float64() * total
+		j := 0
+		for ; j < len(weights)-1 && target >= weights[j]; j++ {
+			target -= weights[j]
+		}
+		selected[i] = specimens[j]
+	}
+
+	return selected
+}
+
+func byFitness(population []Specimen) []Specimen {
+	sorted := append([]Specimen{}, population...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Fitness > sorted[j].Fitness
+	})
+	return sorted
+}
+
+// UniformCrossover takes every param from a parent drawn at random.
+type UniformCrossover struct{}
+
+func (UniformCrossover) Cross(parents [][]float64, min []float64, max []float64, random *rand.Rand) []float64 {
+	child := make([]float64, len(min))
+	for i := range child {
+		child[i] = parents[random.Intn(len(parents))][i]
+	}
+	return child
+}
+
+// BlendCrossover (BLX-alpha) draws every param uniformly between the lowest and highest value of the parents, widened
+// on both sides by Alpha times their spread. An Alpha of 0 keeps children between their parents.
+type BlendCrossover struct {
+	Alpha float64
+}
+
+func (b BlendCrossover) Cross(parents [][]float64, min []float64, max []float64, random *rand.Rand) []float64 {
+	child := make([]float64, len(min))
+
+	for i := range child {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, parent := range parents {
+			low, high = math.Min(low, parent[i]), math.Max(high, parent[i])
+		}
+
+		spread := high - low
+		low, high = low-b.Alpha*spread, high+b.Alpha*spread
+		child[i] = clamp(low+random.Float64()*(high-low), min[i], max[i])
+	}
+
+	return child
+}
+
+// SBXCrossover is the simulated binary crossover of two different parents drawn at random, children land close to a
+// parent more often the higher Eta.
+type SBXCrossover struct {
+	Eta float64
+}
+
+func (s SBXCrossover) Cross(parents [][]float64, min []float64, max []float64, random *rand.Rand) []float64 {
+	firstIndex := random.Intn(len(parents))
+	secondIndex := firstIndex
+	if len(parents) > 1 {
+		secondIndex = random.Intn(len(parents) - 1)
+		if secondIndex >= firstIndex {
+			secondIndex++
+		}
+	}
+	first, second := parents[firstIndex], parents[secondIndex]
+
+	child := make([]float64, len(min))
+	for i := range child {
+		u := random.Float64()
+
+		var beta float64
+		if u <= 0.5 {
+			beta = math.Pow(2*u, 1/(s.Eta+1))
+		} else {
+			beta = math.Pow(1/(2*(1-u)), 1/(s.Eta+1))
+		}
+
+		// Either of the two children the crossover makes.
+		if random.Intn(2) == 0 {
+			beta = -beta
+		}
+		child[i] = clamp(0.5*((1+beta)*first[i]+(1-beta)*second[i]), min[i], max[i])
+	}
+
+	return child
+}
+
+// UniformMutation draws a third of the params again over their whole range, as evolutions always did.
+type UniformMutation struct{}
+
+func (UniformMutation) Mutate(params []float64, min []float64, max []float64, generation int, random *rand.Rand) {
+	for i := 0; i < len(params)/3; i++ {
+		param := random.Intn(len(params))
+		params[param] = min[param] + random.Float64()*(max[param]-min[param])
+	}
+}
+
+// GaussianMutation moves every param with probability Probability by a normal step of Sigma times its range, the
+// step shrinking by Decay each generation so late generations refine rather than explore.
+type GaussianMutation struct {
+	Probability float64
+	Sigma       float64
+	Decay       float64
+}
+
+func (g GaussianMutation) Mutate(params []float64, min []float64, max []float64, generation int,
+	random *rand.Rand) {
+	sigma := g.Sigma * math.Pow(g.Decay, float64(generation))
+
+	for i := range params {
+		if random.Float64() < g.Probability {
+			params[i] = clamp(params[i]+random.NormFloat64()*sigma*(max[i]-min[i]), min[i], max[i])
+		}
+	}
+}
+
+// Diversity is the mean standard deviation of the params over the population, measured in the unit space of the schema
+// the optimisers breed in. It tends to 0 as the population converges.
+func Diversity(population []Specimen) float64 {
+	if len(population) < 2 {
+		return 0
+	}
+
+	schema := population[0].Config.Schema()
+	if len(schema) == 0 {
+		return 0
+	}
+
+	unit := make([][]float64, len(population))
+	for j, specimen := range population {
+		unit[j] = schema.ToUnit(specimen.Config.ToSlice())
+	}
+
+	total := 0.0
+	for i := range schema {
+		values := make([]float64, len(population))
+		for j := range population {
+			values[j] = unit[j][i]
+		}
+		total += standardDeviation(values)
+	}
+
+	return total / float64(len(schema))
+}
+
+func standardDeviation(values []float64) float64 {
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	squares := 0.0
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+
+	return math.Sqrt(squares / float64(len(values)))
+}
+
+func clamp(value float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
+
+// NewSelection returns the selection named truncation, tournament, roulette or rank.
+func NewSelection(name string, tournamentSize int) (Selection, error) {
+	switch name {
+	case "truncation":
+		return Truncation{}, nil
+	case "tournament":
+		if tournamentSize < 1 {
+			return nil, errors.New("tournament size must be at least 1")
+		}
+		return Tournament{Size: tournamentSize}, nil
+	case "roulette":
+		return Roulette{}, nil
+	case "rank":
+		return Rank{}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown selection %q, expected truncation, tournament, roulette or rank", name))
+	}
+}
+
+// NewCrossover returns the crossover named uniform, blend (with alpha) or sbx (with eta).
+func NewCrossover(name string, alpha float64, eta float64) (Crossover, error) {
+	switch name {
+	case "uniform":
+		return UniformCrossover{}, nil
+	case "blend":
+		if alpha < 0 {
+			return nil, errors.New("blend alpha can't be negative")
+		}
+		return BlendCrossover{Alpha: alpha}, nil
+	case "sbx":
+		if eta < 0 {
+			return nil, errors.New("sbx eta can't be negative")
+		}
+		return SBXCrossover{Eta: eta}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown crossover %q, expected uniform, blend or sbx", name))
+	}
+}
+
+// NewMutation returns the mutation named uniform or gaussian, the latter moving every param with the given step and decay.
+func NewMutation(name string, sigma float64, decay float64) (Mutation, error) {
+	switch name {
+	case "uniform":
+		return UniformMutation{}, nil
+	case "gaussian":
+		if sigma <= 0 || decay <= 0 || decay > 1 {
+			return nil, errors.New("gaussian mutation needs a positive sigma and a decay in (0, 1]")
+		}
+		return GaussianMutation{Probability: 1, Sigma: sigma, Decay: decay}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown mutation %q, expected uniform or gaussian", name))
+	}
+}
@@ -0,0 +1,176 @@
+package trader
+
+import (
+	"math"
+	"math/rand"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/model/trader"
+	"scoing-trader/trader/model/trader/strategies"
+	"testing"
+)
+
+// logScaleConfig holds a single param spanning orders of magnitude.
+type logScaleConfig struct {
+	Rate float64
+}
+
+func (c *logScaleConfig) Schema() trader.Schema {
+	return trader.Schema{{Name: "Rate", Type: trader.FloatParam, Min: 0.001, Max: 10, LogScale: true, Default: 0.1}}
+}
+
+func (c *logScaleConfig) ToSlice() []float64 {
+	return []float64{c.Rate}
+}
+
+func (c *logScaleConfig) FromSlice(slice []float64) {
+	c.Rate = slice[0]
+}
+
+func testPopulation(t *testing.T, fitnesses ...float64) ([]Specimen, []float64, []float64) {
+	factory, err := strategies.Lookup("basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	population := make([]Specimen, len(fitnesses))
+	for i, specimenFitness := range fitnesses {
+		config := factory.NewConfig()
+		params := make([]float64, len(config.Schema()))
+		for j := range params {
+			params[j] = float64(i) / float64(len(fitnesses))
+		}
+		config.FromSlice(params)
+		population[i] = Specimen{Fitness: specimenFitness, Config: config}
+	}
+
+	min, max := factory.NewConfig().Schema().Ranges()
+	return population, min, max
+}
+
+func TestSelection(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	population, _, _ := testPopulation(t, 1, 5, 3, fitness.Rejected)
+
+	if top := (Truncation{}).Select(population, 2, random); top[0].Fitness != 5 || top[1].Fitness != 3 {
+		t.Error("Expected the two fittest, got ", top)
+	}
+
+	for _, winner := range (Tournament{Size: 64}).Select(population, 10, random) {
+		if winner.Fitness != 5 {
+			t.Error("Expected large tournaments to pick the fittest, got ", winner.Fitness)
+		}
+	}
+
+	counts := make(map[float64]int)
+	for _, parent := range (Roulette{}).Select(population, 2000, random) {
+		counts[parent.Fitness]++
+	}
+	if counts[fitness.Rejected] != 0 || counts[5] <= counts[3] || counts[3] <= counts[1] {
+		t.Error("Expected roulette to favour the fittest and skip the rejected ", counts)
+	}
+
+	counts = make(map[float64]int)
+	for _, parent := range (Rank{}).Select(population, 2000, random) {
+		counts[parent.Fitness]++
+	}
+	if counts[5] <= counts[3] || counts[3] <= counts[1] || counts[1] <= counts[fitness.Rejected] {
+		t.Error("Expected rank to favour better ranks ", counts)
+	}
+}
+
+func TestCrossover(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	min := []float64{0, 0, 0}
+	max := []float64{10, 10, 10}
+	parents := [][]float64{{1, 2, 3}, {3, 2, 1}, {2, 4, 2}}
+
+	for i := 0; i < 100; i++ {
+		uniform := (UniformCrossover{}).Cross(parents, min, max, random)
+		blend := (BlendCrossover{}).Cross(parents, min, max, random)
+		wide := (BlendCrossover{Alpha: 5}).Cross(parents, min, max, random)
+		sbx := (SBXCrossover{Eta: 2}).Cross(parents, min, max, random)
+
+		for j := range min {
+			if uniform[j] != parents[0][j] && uniform[j] != parents[1][j] && uniform[j] != parents[2][j] {
+				t.Fatal("Expected uniform params from a parent ", uniform)
+			}
+			if blend[j] < 1 || blend[j] > 4 {
+				t.Fatal("Expected blend without alpha between the parents ", blend)
+			}
+			if wide[j] < min[j] || wide[j] > max[j] || sbx[j] < min[j] || sbx[j] > max[j] {
+				t.Fatal("Expected children within the ranges ", wide, sbx)
+			}
+		}
+	}
+}
+
+func TestSBXCrossoverParents(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	parents := [][]float64{{0, 0}, {1, 1}}
+
+	for i := 0; i < 100; i++ {
+		child := (SBXCrossover{Eta: 2}).Cross(parents, []float64{-1, -1}, []float64{2, 2}, random)
+		if child[0] == child[1] && (child[0] == 0 || child[0] == 1) {
+			t.Fatal("Expected a blend of two different parents, got a copy ", child)
+		}
+	}
+}
+
+func TestGaussianMutation(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	min := []float64{0, -1}
+	max := []float64{1, 1}
+	mutation := GaussianMutation{Probability: 1, Sigma: 0.5, Decay: 0.5}
+
+	early, late := 0.0, 0.0
+	for i := 0; i < 200; i++ {
+		params := []float64{0.5, 0}
+		mutation.Mutate(params, min, max, 0, random)
+		early += math.Abs(params[0] - 0.5)
+		if params[0] < 0 || params[0] > 1 || params[1] < -1 || params[1] > 1 {
+			t.Fatal("Expected mutated params within the ranges ", params)
+		}
+
+		params = []float64{0.5, 0}
+		mutation.Mutate(params, min, max, 10, random)
+		late += math.Abs(params[0] - 0.5)
+	}
+
+	if late*100 > early {
+		t.Error("Expected the step to decay with the generations ", early, late)
+	}
+}
+
+func TestDiversityUnitSpace(t *testing.T) {
+	population := []Specimen{{Config: &logScaleConfig{Rate: 0.001}}, {Config: &logScaleConfig{Rate: 0.01}},
+		{Config: &logScaleConfig{Rate: 0.1}}}
+
+	if diversity := Diversity(population); math.Abs(diversity-standardDeviation([]float64{0, 0.25, 0.5})) > 1e-9 {
+		t.Error("Expected the diversity of the orders of magnitude, got ", diversity)
+	}
+}
+
+func TestBreed(t *testing.T) {
+	population, _, _ := testPopulation(t, 4, 3, 2, 1)
+	for i := range population {
+		population[i].Config.FromSlice(population[0].Config.ToSlice())
+	}
+
+	if diversity := Diversity(population); diversity != 0 {
+		t.Error("Expected no diversity in identical specimens, got ", diversity)
+	}
+
+	evo := Evolution{StrategyName: "basic", GenerationSize: 10, Elitism: 2, MinDiversity: 0.01}
+	if err := evo.setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := evo.breed(population, 0, 0, rand.New(rand.NewSource(1)))
+	if len(next) != 10 || !next[0].evaluated || next[0].Fitness != 4 || !next[1].evaluated || next[2].evaluated {
+		t.Fatal("Expected the 2 elites first ", next)
+	}
+
+	if diversity := Diversity(next); diversity == 0 {
+		t.Error("Expected random specimens to restore diversity")
+	}
+}
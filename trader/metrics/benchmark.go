@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	CashBenchmark        = "cash"
+	EqualWeightBenchmark = "equal_weight"
+	BuyAndHoldPrefix     = "buy_and_hold_"
+)
+
+// Benchmarks follows passive portfolios over the prices of a run, without fees: cash, the whole initial net worth in
+// each coin from its first price, and an equal weight basket of the coins seen so far rebalanced every Rebalance. Their
+// curves are sampled at the same times as the equity curve of the run so they can be compared point by point, as are the
+// Prices of the coins, their net worth being the price.
+type Benchmarks struct {
+	Initial       float64
+	Rebalance     time.Duration
+	Curves        map[string]*Curve
+	Prices        map[string]*Curve
+	prices        map[string]float64
+	firstPrices   map[string]float64
+	basket        map[string]float64
+	lastRebalance time.Time
+}
+
+func NewBenchmarks(initial float64, rebalance time.Duration) *Benchmarks {
+	return &Benchmarks{
+		Initial:     initial,
+		Rebalance:   rebalance,
+		Curves:      map[string]*Curve{CashBenchmark: NewCurve(), EqualWeightBenchmark: NewCurve()},
+		Prices:      make(map[string]*Curve),
+		prices:      make(map[string]float64),
+		firstPrices: make(map[string]float64),
+		basket:      make(map[string]float64),
+	}
+}
+
+// Observe updates the price of coin, a coin seen for the first time is bought at that price and joins the basket.
+func (b *Benchmarks) Observe(timestamp time.Time, coin string, price float64) {
+	if price <= 0 {
+		return
+	}
+
+	b.prices[coin] = price
+
+	if _, seen := b.firstPrices[coin]; !seen {
+		b.firstPrices[coin] = price
+		b.Curves[BuyAndHoldPrefix+coin] = NewCurve()
+		b.Curves[BuyAndHoldPrefix+coin].Add(timestamp, b.Initial)
+		b.Prices[coin] = NewCurve()
+		b.Prices[coin].Add(timestamp, price)
+		b.rebalance(timestamp)
+	}
+}
+
+// Snapshot adds a point to every benchmark curve.
+func (b *Benchmarks) Snapshot(timestamp time.Time) {
+	if b.Rebalance > 0 && len(b.basket) > 0 && timestamp.Sub(b.lastRebalance) >= b.Rebalance {
+		b.rebalance(timestamp)
+	}
+
+	b.Curves[CashBenchmark].Add(timestamp, b.Initial)
+	b.Curves[EqualWeightBenchmark].Add(timestamp, b.basketValue())
+
+	for coin, first := range b.firstPrices {
+		b.Curves[BuyAndHoldPrefix+coin].Add(timestamp, b.Initial*b.prices[coin]/first)
+		b.Prices[coin].Add(timestamp, b.prices[coin])
+	}
+}
+
+// Names returns the benchmarks sorted by name.
+func (b *Benchmarks) Names() []string {
+	names := make([]string, 0, len(b.Curves))
+	for name := range b.Curves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *Benchmarks) basketValue() float64 {
+	if len(b.basket) == 0 {
+		return b.Initial
+	}
+
+	value := 0.0
+	for coin, qty := range b.basket {
+		value += qty * b.prices[coin]
+	}
+	return value
+}
+
+func (b *Benchmarks) rebalance(timestamp time.Time) {
+	value := b.basketValue()
+	share := value / float64(len(b.prices))
+
+	for coin, price := range b.prices {
+		b.basket[coin] = share / price
+	}
+
+	b.lastRebalance = timestamp
+}
+
+// Comparison measures a run against a benchmark over the points both curves have. Alpha is annualised.
+type Comparison struct {
+	Benchmark    string  `json:"benchmark"`
+	TotalReturn  float64 `json:"total_return"`
+	ExcessReturn float64 `json:"excess_return"`
+	Alpha        float64 `json:"alpha"`
+	Beta         float64 `json:"beta"`
+	Correlation  float64 `json:"correlation"`
+	MaxDrawdown  float64 `json:"max_drawdown"`
+}
+
+func Compare(name string, curve *Curve, benchmark *Curve) Comparison {
+	comparison := Comparison{
+		Benchmark:    name,
+		TotalReturn:  benchmark.TotalReturn(),
+		ExcessReturn: curve.TotalReturn() - benchmark.TotalReturn(),
+		MaxDrawdown:  benchmark.MaxDrawdown(),
+	}
+
+	returns, benchmarkReturns := alignedReturns(curve, benchmark)
+	if len(returns) < 2 {
+		return comparison
+	}
+
+	mean, deviation := meanDeviation(returns)
+	benchmarkMean, benchmarkDeviation := meanDeviation(benchmarkReturns)
+
+	covariance := 0.0
+	for i := range returns {
+		covariance += (returns[i] - mean) * (benchmarkReturns[i] - benchmarkMean)
+	}
+	covariance /= float64(len(returns) - 1)
+
+	if benchmarkDeviation > 0 {
+		comparison.Beta = covariance / (benchmarkDeviation * benchmarkDeviation)
+		if deviation > 0 {
+			comparison.Correlation = covariance / (deviation * benchmarkDeviation)
+		}
+	}
+
+	comparison.Alpha = (mean - comparison.Beta*benchmarkMean) * curve.PeriodsPerYear()
+
+	return comparison
+}
+
+// alignedReturns are the returns of both curves between consecutive timestamps they share.
+func alignedReturns(curve *Curve, benchmark *Curve) ([]float64, []float64) {
+	benchmarkValues := make(map[int64]float64, len(benchmark.Points))
+	for _, point := range benchmark.Points {
+		benchmarkValues[point.Timestamp.UnixNano()] = point.NetWorth
+	}
+
+	returns := make([]float64, 0)
+	benchmarkReturns := make([]float64, 0)
+
+	var previous *Point
+	previousBenchmark := 0.0
+
+	for i := range curve.Points {
+		point := &curve.Points[i]
+		benchmarkValue, shared := benchmarkValues[point.Timestamp.UnixNano()]
+		if !shared {
+			continue
+		}
+
+		if previous != nil && previous.NetWorth > 0 && previousBenchmark > 0 {
+			returns = append(returns, point.NetWorth/previous.NetWorth-1)
+			benchmarkReturns = append(benchmarkReturns, benchmarkValue/previousBenchmark-1)
+		}
+
+		previous = point
+		previousBenchmark = benchmarkValue
+	}
+
+	return returns, benchmarkReturns
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBenchmarks(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices := []map[string]float64{
+		{"BTCUSDT": 100, "ETHUSDT": 10},
+		{"BTCUSDT": 200, "ETHUSDT": 10},
+		{"BTCUSDT": 100, "ETHUSDT": 20},
+	}
+
+	benchmarks := NewBenchmarks(1000, 24*time.Hour)
+	for day, dayPrices := range prices {
+		timestamp := start.Add(time.Duration(day) * 24 * time.Hour)
+		benchmarks.Observe(timestamp, "BTCUSDT", dayPrices["BTCUSDT"])
+		benchmarks.Observe(timestamp, "ETHUSDT", dayPrices["ETHUSDT"])
+		benchmarks.Snapshot(timestamp)
+	}
+
+	expected := map[string]float64{
+		CashBenchmark:                1000,
+		EqualWeightBenchmark:         1875,
+		BuyAndHoldPrefix + "BTCUSDT": 1000,
+		BuyAndHoldPrefix + "ETHUSDT": 2000,
+	}
+
+	if len(benchmarks.Names()) != len(expected) {
+		t.Fatal("Incorrect benchmarks ", benchmarks.Names())
+	}
+
+	for name, value := range expected {
+		curve := benchmarks.Curves[name]
+		if len(curve.Points) != 3 || math.Abs(curve.FinalNetWorth()-value) > 1e-9 {
+			t.Error("Incorrect final net worth of ", name, " ", curve.FinalNetWorth())
+		}
+	}
+
+	// Twice the returns of ETH plus 1% a day.
+	strategy := testCurve(1000, 1010, 3040.1)
+	comparison := Compare("eth", strategy, benchmarks.Curves[BuyAndHoldPrefix+"ETHUSDT"])
+
+	if math.Abs(comparison.Beta-2) > 1e-9 || math.Abs(comparison.Correlation-1) > 1e-9 {
+		t.Error("Incorrect beta or correlation ", comparison)
+	}
+
+	if math.Abs(comparison.Alpha-0.01*365) > 1e-9 || math.Abs(comparison.ExcessReturn-(2.0401-1)) > 1e-9 {
+		t.Error("Incorrect alpha or excess return ", comparison)
+	}
+
+	comparison = Compare(CashBenchmark, strategy, benchmarks.Curves[CashBenchmark])
+	if comparison.Beta != 0 || math.Abs(comparison.ExcessReturn-2.0401) > 1e-9 {
+		t.Error("Incorrect comparison with cash ", comparison)
+	}
+}
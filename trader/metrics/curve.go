@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"scoing-trader/trader/model/trader"
+	"time"
+)
+
+const year = 365 * 24 * time.Hour
+
+// Point is the net worth at a time, with the market value held in each coin when known.
+type Point struct {
+	Timestamp time.Time
+	NetWorth  float64
+	Exposure  map[string]float64
+}
+
+type Trade struct {
+	Timestamp time.Time
+	Coin      string
+	Event     trader.DecisionType
+	Price     float64
+	Profit    float64
+}
+
+// Curve is the equity curve of a run along with the trades made. It is a trader.Recorder, so it can be attached to a
+// trader and fed as the run goes.
+type Curve struct {
+	Points []Point
+	Trades []Trade
+}
+
+func NewCurve() *Curve {
+	return &Curve{
+		Points: make([]Point, 0),
+		Trades: make([]Trade, 0),
+	}
+}
+
+func (c *Curve) RecordTrade(record trader.TradeRecord, balance decimal.Decimal, netWorth decimal.Decimal,
+	positions map[string]decimal.Decimal) error {
+	profit, _ := record.Profit.Float64()
+	price, _ := record.Value.Float64()
+
+	c.Trades = append(c.Trades, Trade{
+		Timestamp: record.Timestamp,
+		Coin:      record.Coin,
+		Event:     record.Event,
+		Price:     price,
+		Profit:    profit,
+	})
+
+	return nil
+}
+
+// RecordSnapshot adds a point to the curve, a snapshot with the timestamp of the last point replaces it.
+func (c *Curve) RecordSnapshot(timestamp time.Time, balance decimal.Decimal, netWorth decimal.Decimal,
+	positions map[string]map[string]decimal.Decimal) error {
+	value, _ := netWorth.Float64()
+	c.Add(timestamp, value)
+
+	return nil
+}
+
+func (c *Curve) Add(timestamp time.Time, netWorth float64) {
+	if last := len(c.Points) - 1; last >= 0 && !timestamp.After(c.Points[last].Timestamp) {
+		c.Points[last].NetWorth = netWorth
+		return
+	}
+
+	c.Points = append(c.Points, Point{Timestamp: timestamp, NetWorth: netWorth})
+}
+
+// SetExposure records the value held in each coin at the last point.
+func (c *Curve) SetExposure(exposure map[string]float64) {
+	if len(c.Points) > 0 {
+		c.Points[len(c.Points)-1].Exposure = exposure
+	}
+}
+
+// Returns are the simple returns between consecutive points.
+func (c *Curve) Returns() []float64 {
+	returns := make([]float64, 0, len(c.Points))
+
+	for i := 1; i < len(c.Points); i++ {
+		if c.Points[i-1].NetWorth == 0 {
+			returns = append(returns, 0)
+			continue
+		}
+		returns = append(returns, c.Points[i].NetWorth/c.Points[i-1].NetWorth-1)
+	}
+
+	return returns
+}
+
+func (c *Curve) Duration() time.Duration {
+	if len(c.Points) < 2 {
+		return 0
+	}
+	return c.Points[len(c.Points)-1].Timestamp.Sub(c.Points[0].Timestamp)
+}
+
+// PeriodsPerYear is the number of points a year of the curve would hold, used to annualise per point statistics.
+func (c *Curve) PeriodsPerYear() float64 {
+	if c.Duration() <= 0 {
+		return 0
+	}
+	return float64(len(c.Points)-1) * float64(year) / float64(c.Duration())
+}
+
+func (c *Curve) FinalNetWorth() float64 {
+	if len(c.Points) == 0 {
+		return 0
+	}
+	return c.Points[len(c.Points)-1].NetWorth
+}
+
+func (c *Curve) TotalReturn() float64 {
+	if len(c.Points) == 0 || c.Points[0].NetWorth == 0 {
+		return 0
+	}
+	return c.FinalNetWorth()/c.Points[0].NetWorth - 1
+}
+
+// AnnualReturn is the compound annual growth rate of the curve.
+func (c *Curve) AnnualReturn() float64 {
+	if c.Duration() <= 0 || c.TotalReturn() <= -1 {
+		return c.TotalReturn()
+	}
+	return math.Pow(1+c.TotalReturn(), float64(year)/float64(c.Duration())) - 1
+}
+
+func (c *Curve) Volatility() float64 {
+	_, deviation := meanDeviation(c.Returns())
+	return deviation * math.Sqrt(c.PeriodsPerYear())
+}
+
+// Sharpe is the annualised Sharpe ratio with a risk free rate of 0.
+func (c *Curve) Sharpe() float64 {
+	mean, deviation := meanDeviation(c.Returns())
+	if deviation == 0 {
+		return 0
+	}
+	return mean / deviation * math.Sqrt(c.PeriodsPerYear())
+}
+
+// Sortino is the annualised Sortino ratio, only returns below 0 count as risk.
+func (c *Curve) Sortino() float64 {
+	returns := c.Returns()
+	mean, _ := meanDeviation(returns)
+
+	downside := 0.0
+	for _, ret := range returns {
+		if ret < 0 {
+			downside += ret * ret
+		}
+	}
+	if downside == 0 {
+		return 0
+	}
+
+	return mean / math.Sqrt(downside/float64(len(returns))) * math.Sqrt(c.PeriodsPerYear())
+}
+
+// MaxDrawdown is the largest fall from a peak of the curve, as a fraction of the peak.
+func (c *Curve) MaxDrawdown() float64 {
+	peak := 0.0
+	drawdown := 0.0
+
+	for _, point := range c.Points {
+		peak = math.Max(peak, point.NetWorth)
+		if peak > 0 {
+			drawdown = math.Max(drawdown, 1-point.NetWorth/peak)
+		}
+	}
+
+	return drawdown
+}
+
+// MaxDrawdownDuration is the longest time the curve spent below a previous peak, up to its end if it never recovered.
+func (c *Curve) MaxDrawdownDuration() time.Duration {
+	var longest time.Duration
+	var peak Point
+
+	for i, point := range c.Points {
+		if i == 0 || point.NetWorth >= peak.NetWorth {
+			peak = point
+		}
+		if underwater := point.Timestamp.Sub(peak.Timestamp); underwater > longest {
+			longest = underwater
+		}
+	}
+
+	return longest
+}
+
+// Calmar is the annual return over the max drawdown, 0 for a curve that never fell.
+func (c *Curve) Calmar() float64 {
+	drawdown := c.MaxDrawdown()
+	if drawdown == 0 {
+		return 0
+	}
+	return c.AnnualReturn() / drawdown
+}
+
+// ProfitFactor is the gross profit of the sells over their gross loss, +Inf when no sell lost.
+func (c *Curve) ProfitFactor() float64 {
+	profits, losses := 0.0, 0.0
+
+	for _, trade := range c.Trades {
+		if trade.Event != trader.SELL {
+			continue
+		}
+		if trade.Profit > 0 {
+			profits += trade.Profit
+		} else {
+			losses -= trade.Profit
+		}
+	}
+
+	if losses == 0 {
+		if profits == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+
+	return profits / losses
+}
+
+// NumTrades counts the buys and sells.
+func (c *Curve) NumTrades() int {
+	count := 0
+	for _, trade := range c.Trades {
+		if trade.Event != trader.HOLD {
+			count++
+		}
+	}
+	return count
+}
+
+// WinRate is the fraction of sells made at a profit.
+func (c *Curve) WinRate() float64 {
+	sells, wins := 0, 0
+	for _, trade := range c.Trades {
+		if trade.Event == trader.SELL {
+			sells++
+			if trade.Profit > 0 {
+				wins++
+			}
+		}
+	}
+
+	if sells == 0 {
+		return 0
+	}
+	return float64(wins) / float64(sells)
+}
+
+func meanDeviation(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	squares := 0.0
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"scoing-trader/trader/model/trader"
+	"testing"
+	"time"
+)
+
+func testCurve(values ...float64) *Curve {
+	curve := NewCurve()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, value := range values {
+		curve.Add(start.Add(time.Duration(i)*24*time.Hour), value)
+	}
+	return curve
+}
+
+func TestCurveStatistics(t *testing.T) {
+	curve := testCurve(100, 110, 99, 121, 110)
+
+	if math.Abs(curve.TotalReturn()-0.1) > 1e-9 {
+		t.Error("Incorrect total return ", curve.TotalReturn())
+	}
+
+	if math.Abs(curve.MaxDrawdown()-0.1) > 1e-9 {
+		t.Error("Incorrect max drawdown ", curve.MaxDrawdown())
+	}
+
+	if math.Abs(curve.PeriodsPerYear()-365) > 1e-9 {
+		t.Error("Incorrect periods per year ", curve.PeriodsPerYear())
+	}
+
+	returns := curve.Returns()
+	if len(returns) != 4 || math.Abs(returns[1]+0.1) > 1e-9 {
+		t.Error("Incorrect returns ", returns)
+	}
+
+	if curve.Sharpe() <= 0 || curve.Sortino() <= curve.Sharpe() || curve.Calmar() <= 0 {
+		t.Error("Incorrect ratios ", curve.Sharpe(), curve.Sortino(), curve.Calmar())
+	}
+
+	flat := testCurve(100, 100, 100)
+	if flat.Sharpe() != 0 || flat.Sortino() != 0 || flat.Calmar() != 0 || flat.MaxDrawdown() != 0 {
+		t.Error("Expected a flat curve to have no risk adjusted return")
+	}
+}
+
+func TestCurveRecorder(t *testing.T) {
+	var recorder trader.Recorder = NewCurve()
+	curve := recorder.(*Curve)
+	timestamp := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	recorder.RecordSnapshot(timestamp, decimal.Zero, decimal.NewFromInt(100), nil)
+	recorder.RecordSnapshot(timestamp, decimal.Zero, decimal.NewFromInt(101), nil)
+
+	if len(curve.Points) != 1 || curve.FinalNetWorth() != 101 {
+		t.Error("Expected a snapshot with the same timestamp to replace the point ", curve.Points)
+	}
+
+	for _, profit := range []int64{0, 30, -10, 0, 5} {
+		event := trader.SELL
+		if profit == 0 {
+			event = trader.BUY
+		}
+		recorder.RecordTrade(trader.TradeRecord{Timestamp: timestamp, Event: event, Profit: decimal.NewFromInt(profit)},
+			decimal.Zero, decimal.Zero, nil)
+	}
+
+	if curve.NumTrades() != 5 || curve.ProfitFactor() != 3.5 || math.Abs(curve.WinRate()-2.0/3) > 1e-9 {
+		t.Error("Incorrect trade statistics ", curve.NumTrades(), curve.ProfitFactor(), curve.WinRate())
+	}
+}
@@ -0,0 +1,277 @@
+package metrics
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"scoing-trader/trader/model/trader"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 960
+	chartHeight  = 300
+	marginLeft   = 80
+	marginRight  = 190
+	marginTop    = 16
+	marginBottom = 32
+	axisTicks    = 5
+)
+
+var palette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// HTMLReport renders a run as a single HTML page: the equity curve against the benchmarks, its drawdown, the price of
+// every coin with the buys and sells made, and the metrics of the report. The charts are inline SVG so the page needs no
+// network. Benchmarks is optional, without it the coin charts only show the trades.
+type HTMLReport struct {
+	Title      string
+	Report     Report
+	Curve      *Curve
+	Benchmarks *Benchmarks
+}
+
+type htmlChart struct {
+	Title string
+	SVG   template.HTML
+}
+
+type htmlPage struct {
+	Title  string
+	Charts []htmlChart
+	Rows   [][2]string
+}
+
+var pageTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+h2 { font-size: 16px; margin: 24px 0 4px; }
+table { border-collapse: collapse; font-size: 13px; }
+td { padding: 3px 12px; border-bottom: 1px solid #eee; }
+td:first-child { color: #555; }
+svg text { font-size: 11px; fill: #444; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Charts}}<h2>{{.Title}}</h2>
+{{.SVG}}
+{{end}}<h2>Metrics</h2>
+<table>
+{{range .Rows}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (h HTMLReport) Write(w io.Writer) error {
+	page := htmlPage{Title: h.Title, Rows: h.Report.rows()}
+
+	equity := []series{{Name: "strategy", Points: h.Curve.Points}}
+	if h.Benchmarks != nil {
+		for _, name := range h.Benchmarks.Names() {
+			equity = append(equity, series{Name: name, Points: h.Benchmarks.Curves[name].Points, Dashed: true})
+		}
+	}
+	page.Charts = append(page.Charts,
+		htmlChart{Title: "Equity", SVG: lineChart(equity, nil, "%.2f")},
+		htmlChart{Title: "Drawdown", SVG: lineChart([]series{{Name: "drawdown %", Points: drawdowns(h.Curve)}}, nil, "%.1f")},
+	)
+
+	for _, coin := range h.coins() {
+		prices := make([]series, 0, 1)
+		if h.Benchmarks != nil && h.Benchmarks.Prices[coin] != nil {
+			prices = append(prices, series{Name: coin, Points: h.Benchmarks.Prices[coin].Points})
+		}
+
+		trades := make([]Trade, 0)
+		for _, trade := range h.Curve.Trades {
+			if trade.Coin == coin && trade.Event != trader.HOLD {
+				trades = append(trades, trade)
+			}
+		}
+
+		page.Charts = append(page.Charts, htmlChart{Title: coin, SVG: lineChart(prices, trades, "%.4g")})
+	}
+
+	return pageTemplate.Execute(w, page)
+}
+
+func (h HTMLReport) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// coins are the coins with a price or a trade, sorted.
+func (h HTMLReport) coins() []string {
+	seen := make(map[string]float64)
+	if h.Benchmarks != nil {
+		for coin := range h.Benchmarks.Prices {
+			seen[coin] = 0
+		}
+	}
+	for _, trade := range h.Curve.Trades {
+		if trade.Event != trader.HOLD {
+			seen[trade.Coin] = 0
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// drawdowns is the fall of the curve from its running peak at every point, in percent.
+func drawdowns(curve *Curve) []Point {
+	points := make([]Point, len(curve.Points))
+	peak := 0.0
+
+	for i, point := range curve.Points {
+		peak = math.Max(peak, point.NetWorth)
+		points[i] = Point{Timestamp: point.Timestamp}
+		if peak > 0 {
+			points[i].NetWorth = (point.NetWorth/peak - 1) * 100
+		}
+	}
+
+	return points
+}
+
+type series struct {
+	Name   string
+	Points []Point
+	Dashed bool
+}
+
+// lineChart draws the series over a shared time axis, with trades marked as up (buy) and down (sell) triangles at the
+// price they were made at. format is used for the value axis labels.
+func lineChart(lines []series, trades []Trade, format string) template.HTML {
+	var start, end time.Time
+	low, high := math.Inf(1), math.Inf(-1)
+
+	extend := func(timestamp time.Time, value float64) {
+		if start.IsZero() || timestamp.Before(start) {
+			start = timestamp
+		}
+		if timestamp.After(end) {
+			end = timestamp
+		}
+		low, high = math.Min(low, value), math.Max(high, value)
+	}
+
+	for _, line := range lines {
+		for _, point := range line.Points {
+			extend(point.Timestamp, point.NetWorth)
+		}
+	}
+	for _, trade := range trades {
+		extend(trade.Timestamp, trade.Price)
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+
+	if start.IsZero() {
+		fmt.Fprintf(&svg, `<text x="%d" y="%d">no data</text></svg>`, marginLeft, chartHeight/2)
+		return template.HTML(svg.String())
+	}
+
+	if high == low {
+		high, low = high+1, low-1
+	}
+	pad := (high - low) * 0.05
+	high, low = high+pad, low-pad
+
+	span := end.Sub(start)
+	if span <= 0 {
+		span = time.Hour
+	}
+
+	plotWidth := float64(chartWidth - marginLeft - marginRight)
+	plotHeight := float64(chartHeight - marginTop - marginBottom)
+
+	x := func(timestamp time.Time) float64 {
+		return marginLeft + plotWidth*float64(timestamp.Sub(start))/float64(span)
+	}
+	y := func(value float64) float64 {
+		return marginTop + plotHeight*(high-value)/(high-low)
+	}
+
+	for i := 0; i <= axisTicks; i++ {
+		value := low + (high-low)*float64(i)/axisTicks
+		timestamp := start.Add(time.Duration(float64(span) * float64(i) / axisTicks))
+
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#eee"/>`,
+			marginLeft, y(value), marginLeft+plotWidth, y(value))
+		fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`,
+			marginLeft-6, y(value)+4, html.EscapeString(fmt.Sprintf(format, value)))
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+			x(timestamp), chartHeight-10, timestamp.UTC().Format("2006-01-02 15:04"))
+	}
+
+	fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%.1f" height="%.1f" fill="none" stroke="#999"/>`,
+		marginLeft, marginTop, plotWidth, plotHeight)
+
+	for i, line := range lines {
+		color := palette[i%len(palette)]
+		dash := ""
+		if line.Dashed {
+			dash = ` stroke-dasharray="4 3"`
+		}
+
+		points := make([]string, len(line.Points))
+		for j, point := range line.Points {
+			points[j] = fmt.Sprintf("%.1f,%.1f", x(point.Timestamp), y(point.NetWorth))
+		}
+
+		fmt.Fprintf(&svg, `<polyline fill="none" stroke="%s" stroke-width="1.2"%s points="%s"/>`,
+			color, dash, strings.Join(points, " "))
+
+		legendY := marginTop + 8 + 16*i
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"%s/>`,
+			chartWidth-marginRight+12, legendY, chartWidth-marginRight+32, legendY, color, dash)
+		fmt.Fprintf(&svg, `<text x="%d" y="%d">%s</text>`,
+			chartWidth-marginRight+38, legendY+4, html.EscapeString(line.Name))
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Timestamp.Before(trades[j].Timestamp)
+	})
+	for _, trade := range trades {
+		tradeX, tradeY := x(trade.Timestamp), y(trade.Price)
+		if trade.Event == trader.BUY {
+			fmt.Fprintf(&svg, `<path d="M%.1f,%.1f l-5,9 h10 z" fill="#2ca02c"><title>BUY %s</title></path>`,
+				tradeX, tradeY, trade.Timestamp.UTC().Format(time.RFC3339))
+		} else {
+			fmt.Fprintf(&svg, `<path d="M%.1f,%.1f l-5,-9 h10 z" fill="#d62728"><title>SELL %s %.4f</title></path>`,
+				tradeX, tradeY, trade.Timestamp.UTC().Format(time.RFC3339), trade.Profit)
+		}
+	}
+
+	if len(trades) > 0 {
+		legendY := marginTop + 8 + 16*len(lines)
+		fmt.Fprintf(&svg, `<path d="M%d,%d l-5,9 h10 z" fill="#2ca02c"/><text x="%d" y="%d">buy</text>`,
+			chartWidth-marginRight+22, legendY-4, chartWidth-marginRight+38, legendY+4)
+		fmt.Fprintf(&svg, `<path d="M%d,%d l-5,-9 h10 z" fill="#d62728"/><text x="%d" y="%d">sell</text>`,
+			chartWidth-marginRight+22, legendY+20, chartWidth-marginRight+38, legendY+20)
+	}
+
+	svg.WriteString(`</svg>`)
+
+	return template.HTML(svg.String())
+}
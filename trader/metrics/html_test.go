@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"bytes"
+	"scoing-trader/trader/model/trader"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLReport(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	benchmarks := NewBenchmarks(100, 24*time.Hour)
+	for hour, price := range []float64{10, 11, 12, 11} {
+		timestamp := start.Add(time.Duration(hour) * time.Hour)
+		benchmarks.Observe(timestamp, "BTCUSDT", price)
+		benchmarks.Snapshot(timestamp)
+	}
+
+	curve := testCurve(100, 105, 110)
+	curve.Trades = []Trade{
+		{Timestamp: start, Coin: "BTCUSDT", Event: trader.BUY, Price: 10},
+		{Timestamp: start.Add(2 * time.Hour), Coin: "BTCUSDT", Event: trader.SELL, Price: 12, Profit: 2},
+		{Timestamp: start.Add(2 * time.Hour), Coin: "<ETH>", Event: trader.HOLD, Price: 12},
+	}
+
+	page := HTMLReport{Title: "Backtest", Report: NewReport(curve, Costs{}), Curve: curve, Benchmarks: benchmarks}
+
+	var output bytes.Buffer
+	if err := page.Write(&output); err != nil {
+		t.Fatal(err)
+	}
+	document := output.String()
+
+	for _, expected := range []string{"<h2>Equity</h2>", "<h2>Drawdown</h2>", "<h2>BTCUSDT</h2>", "buy_and_hold_BTCUSDT",
+		"<title>BUY 2020-01-01T00:00:00Z</title>", "<title>SELL 2020-01-01T02:00:00Z", "<td>Total return</td>"} {
+		if !strings.Contains(document, expected) {
+			t.Error("Expected the report to contain ", expected)
+		}
+	}
+
+	if strings.Contains(document, "ETH") {
+		t.Error("Expected no chart for a coin without prices or trades")
+	}
+
+	if strings.Contains(document, "src=") || strings.Contains(document, "href=") {
+		t.Error("Expected a self-contained page")
+	}
+}
@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"scoing-trader/trader/model/trader"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Costs are what the trades of a run cost, as kept by the accountant. Volume and Fees are valued in the quote asset,
+// Commissions in the asset they were charged in.
+type Costs struct {
+	Volume      float64
+	Fees        float64
+	Commissions map[string]float64
+}
+
+// Report summarises the performance of a finished run. Ratios are annualised from the spacing of the curve points and
+// ProfitFactor is null when no sell lost.
+type Report struct {
+	Start               time.Time          `json:"start"`
+	End                 time.Time          `json:"end"`
+	InitialNetWorth     float64            `json:"initial_net_worth"`
+	FinalNetWorth       float64            `json:"final_net_worth"`
+	TotalReturn         float64            `json:"total_return"`
+	AnnualReturn        float64            `json:"annual_return"`
+	Volatility          float64            `json:"volatility"`
+	Sharpe              float64            `json:"sharpe"`
+	Sortino             float64            `json:"sortino"`
+	MaxDrawdown         float64            `json:"max_drawdown"`
+	MaxDrawdownDuration time.Duration      `json:"-"`
+	MaxDrawdownDays     float64            `json:"max_drawdown_days"`
+	Trades              int                `json:"trades"`
+	Buys                int                `json:"buys"`
+	Sells               int                `json:"sells"`
+	WinRate             float64            `json:"win_rate"`
+	AverageWin          float64            `json:"average_win"`
+	AverageLoss         float64            `json:"average_loss"`
+	ProfitFactor        *float64           `json:"profit_factor"`
+	Exposure            map[string]float64 `json:"exposure"`
+	Volume              float64            `json:"volume"`
+	Turnover            float64            `json:"turnover"`
+	Fees                float64            `json:"fees"`
+	Commissions         map[string]float64 `json:"commissions"`
+	Benchmarks          []Comparison       `json:"benchmarks,omitempty"`
+}
+
+func NewReport(curve *Curve, costs Costs) Report {
+	report := Report{
+		FinalNetWorth:       curve.FinalNetWorth(),
+		TotalReturn:         curve.TotalReturn(),
+		AnnualReturn:        curve.AnnualReturn(),
+		Volatility:          curve.Volatility(),
+		Sharpe:              curve.Sharpe(),
+		Sortino:             curve.Sortino(),
+		MaxDrawdown:         curve.MaxDrawdown(),
+		MaxDrawdownDuration: curve.MaxDrawdownDuration(),
+		Trades:              curve.NumTrades(),
+		WinRate:             curve.WinRate(),
+		Exposure:            exposure(curve),
+		Volume:              costs.Volume,
+		Fees:                costs.Fees,
+		Commissions:         costs.Commissions,
+	}
+	report.MaxDrawdownDays = report.MaxDrawdownDuration.Hours() / 24
+
+	if len(curve.Points) > 0 {
+		report.Start = curve.Points[0].Timestamp
+		report.End = curve.Points[len(curve.Points)-1].Timestamp
+		report.InitialNetWorth = curve.Points[0].NetWorth
+	}
+
+	if profitFactor := curve.ProfitFactor(); !math.IsInf(profitFactor, 1) {
+		report.ProfitFactor = &profitFactor
+	}
+
+	wins, losses := 0, 0
+	for _, trade := range curve.Trades {
+		switch trade.Event {
+		case trader.BUY:
+			report.Buys++
+		case trader.SELL:
+			report.Sells++
+			if trade.Profit > 0 {
+				wins++
+				report.AverageWin += trade.Profit
+			} else if trade.Profit < 0 {
+				losses++
+				report.AverageLoss += trade.Profit
+			}
+		}
+	}
+	if wins > 0 {
+		report.AverageWin /= float64(wins)
+	}
+	if losses > 0 {
+		report.AverageLoss /= float64(losses)
+	}
+
+	if average := averageNetWorth(curve); average > 0 {
+		report.Turnover = costs.Volume / average
+	}
+
+	return report
+}
+
+// exposure is the average fraction of the net worth held in each coin over the points with a known exposure.
+func exposure(curve *Curve) map[string]float64 {
+	exposure := make(map[string]float64)
+	points := 0
+
+	for _, point := range curve.Points {
+		if point.Exposure == nil || point.NetWorth <= 0 {
+			continue
+		}
+		points++
+		for coin, value := range point.Exposure {
+			exposure[coin] += value / point.NetWorth
+		}
+	}
+
+	for coin := range exposure {
+		exposure[coin] /= float64(points)
+	}
+
+	return exposure
+}
+
+func averageNetWorth(curve *Curve) float64 {
+	if len(curve.Points) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, point := range curve.Points {
+		sum += point.NetWorth
+	}
+
+	return sum / float64(len(curve.Points))
+}
+
+func (r Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), os.FileMode(0644))
+}
+
+// String is a human readable summary of the report.
+func (r Report) String() string {
+	var summary strings.Builder
+
+	for _, row := range r.rows() {
+		summary.WriteString(fmt.Sprintf("%-26s %s\n", row[0]+":", row[1]))
+	}
+
+	return summary.String()
+}
+
+// rows are the name and formatted value of every line of the summary.
+func (r Report) rows() [][2]string {
+	rows := make([][2]string, 0)
+
+	line := func(name string, format string, args ...interface{}) {
+		rows = append(rows, [2]string{name, fmt.Sprintf(format, args...)})
+	}
+
+	line("Period", "%s - %s", r.Start.UTC().Format("2006-01-02 15:04"), r.End.UTC().Format("2006-01-02 15:04"))
+	line("Net worth", "%.4f -> %.4f", r.InitialNetWorth, r.FinalNetWorth)
+	line("Total return", "%.2f%%", r.TotalReturn*100)
+	line("Annual return", "%.2f%%", r.AnnualReturn*100)
+	line("Volatility", "%.2f%%", r.Volatility*100)
+	line("Sharpe", "%.3f", r.Sharpe)
+	line("Sortino", "%.3f", r.Sortino)
+	line("Max drawdown", "%.2f%% over %.1f days", r.MaxDrawdown*100, r.MaxDrawdownDays)
+	line("Trades", "%d (%d buys, %d sells)", r.Trades, r.Buys, r.Sells)
+	line("Win rate", "%.2f%%", r.WinRate*100)
+	line("Average win / loss", "%.4f / %.4f", r.AverageWin, r.AverageLoss)
+	if r.ProfitFactor != nil {
+		line("Profit factor", "%.3f", *r.ProfitFactor)
+	} else {
+		line("Profit factor", "no losing sells")
+	}
+	line("Volume", "%.4f (turnover %.2fx)", r.Volume, r.Turnover)
+	line("Fees", "%.4f", r.Fees)
+
+	for _, asset := range sortedKeys(r.Commissions) {
+		line("Commission "+asset, "%.8f", r.Commissions[asset])
+	}
+	for _, coin := range sortedKeys(r.Exposure) {
+		line("Exposure "+coin, "%.2f%%", r.Exposure[coin]*100)
+	}
+	for _, benchmark := range r.Benchmarks {
+		line("vs "+benchmark.Benchmark, "return %.2f%%, excess %.2f%%, alpha %.4f, beta %.3f", benchmark.TotalReturn*100,
+			benchmark.ExcessReturn*100, benchmark.Alpha, benchmark.Beta)
+	}
+
+	return rows
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"scoing-trader/trader/model/trader"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	curve := testCurve(100, 110, 99, 121, 110)
+	curve.Points[1].Exposure = map[string]float64{"BTCUSDT": 55}
+	curve.Points[2].Exposure = map[string]float64{"BTCUSDT": 49.5, "ETHUSDT": 49.5}
+
+	curve.Trades = []Trade{
+		{Event: trader.BUY},
+		{Event: trader.SELL, Profit: 6},
+		{Event: trader.SELL, Profit: 2},
+		{Event: trader.SELL, Profit: -4},
+	}
+
+	report := NewReport(curve, Costs{Volume: 540, Fees: 0.54, Commissions: map[string]float64{"USDT": 0.54}})
+
+	if report.Buys != 1 || report.Sells != 3 || report.AverageWin != 4 || report.AverageLoss != -4 {
+		t.Error("Incorrect trade statistics ", report)
+	}
+
+	if report.ProfitFactor == nil || *report.ProfitFactor != 2 {
+		t.Error("Incorrect profit factor ", report.ProfitFactor)
+	}
+
+	if math.Abs(report.Exposure["BTCUSDT"]-0.5) > 1e-9 || math.Abs(report.Exposure["ETHUSDT"]-0.25) > 1e-9 {
+		t.Error("Incorrect exposure ", report.Exposure)
+	}
+
+	if report.MaxDrawdownDuration != 24*time.Hour || math.Abs(report.Turnover-5) > 1e-9 {
+		t.Error("Incorrect drawdown duration or turnover ", report.MaxDrawdownDuration, report.Turnover)
+	}
+
+	curve.Trades = curve.Trades[:2]
+	report = NewReport(curve, Costs{})
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"profit_factor":null`) || !strings.Contains(report.String(), "no losing sells") {
+		t.Error("Expected a profit factor without losses to be left out ", string(data))
+	}
+}
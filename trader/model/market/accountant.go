@@ -13,10 +13,28 @@ type Accountant struct {
 	InitialBalance decimal.Decimal
 	Fee            decimal.Decimal
 	Balance        decimal.Decimal
+	QuoteAsset     string
 	Market         model.Market
 	Positions      map[string]map[string]decimal.Decimal
 	Assets         map[string]decimal.Decimal
 	AssetValues    map[string]decimal.Decimal
+	PendingOrders  map[string]*PendingOrder
+	Commissions    map[string]decimal.Decimal
+	FeesPaid       decimal.Decimal
+	Volume         decimal.Decimal
+	LastUpdate     time.Time
+	lotFees        map[string]map[string]decimal.Decimal
+	orderCount     int64
+}
+
+type PendingOrder struct {
+	ClientOrderId string
+	Coin          string
+	Side          model.OrderSide
+	Quantity      decimal.Decimal
+	Reserved      decimal.Decimal
+	Lots          map[string]decimal.Decimal
+	SettledFills  int
 }
 
 func NewAccountant(market model.Market, initialBalance decimal.Decimal, fee decimal.Decimal) *Accountant {
@@ -24,10 +42,14 @@ func NewAccountant(market model.Market, initialBalance decimal.Decimal, fee deci
 		InitialBalance: initialBalance,
 		Fee:            fee,
 		Balance:        initialBalance,
+		QuoteAsset:     model.DefaultQuoteAsset,
 		Market:         market,
 		Positions:      make(map[string]map[string]decimal.Decimal),
 		Assets:         make(map[string]decimal.Decimal),
 		AssetValues:    make(map[string]decimal.Decimal),
+		PendingOrders:  make(map[string]*PendingOrder),
+		Commissions:    make(map[string]decimal.Decimal),
+		lotFees:        make(map[string]map[string]decimal.Decimal),
 	}
 }
 
@@ -37,6 +59,10 @@ func (a *Accountant) Buy(coin string, quantity decimal.Decimal) (decimal.Decimal
 		return decimal.Zero, errors.New(fmt.Sprintf("negative buy quantity %s", quantity))
 	}
 
+	if err := a.checkQuote(coin); err != nil {
+		return decimal.Zero, err
+	}
+
 	transactionValue := a.AssetValues[coin].Mul(quantity).Mul(a.Fee.Add(decimal.NewFromInt(1)))
 
 	if transactionValue.GreaterThan(a.Balance) {
@@ -44,36 +70,37 @@ func (a *Accountant) Buy(coin string, quantity decimal.Decimal) (decimal.Decimal
 	}
 
 	buyOrder := model.OrderRequest{
-		Symbol:    coin,
-		Side:      model.BUY,
-		Type:      model.MARKET,
-		Timestamp: a.GetTimeStamp(),
-		Quantity:  quantity,
+		Symbol:        coin,
+		Side:          model.BUY,
+		Type:          model.MARKET,
+		Timestamp:     a.GetTimeStamp(),
+		Quantity:      quantity,
+		ClientOrderId: a.newClientOrderId(coin),
 	}
 
 	if err := a.Market.NewOrder(buyOrder); err != nil {
 		return decimal.Zero, err
 	}
 
-	a.Balance = a.Balance.Sub(transactionValue)
-
-	if _, hasKey := a.Positions[coin]; !hasKey {
-		a.Positions[coin] = make(map[string]decimal.Decimal)
+	pending := &PendingOrder{
+		ClientOrderId: buyOrder.ClientOrderId,
+		Coin:          coin,
+		Side:          model.BUY,
+		Quantity:      quantity,
+		Reserved:      transactionValue,
 	}
 
-	if _, hasKey := a.Positions[coin][a.AssetValues[coin].String()]; hasKey {
-		a.Positions[coin][a.AssetValues[coin].String()] = a.Positions[coin][a.AssetValues[coin].String()].Add(quantity)
-	} else {
-		a.Positions[coin][a.AssetValues[coin].String()] = quantity
-	}
+	a.Balance = a.Balance.Sub(transactionValue)
+	a.PendingOrders[pending.ClientOrderId] = pending
 
-	if _, hasKey := a.Assets[coin]; !hasKey {
-		a.Assets[coin] = quantity
-	} else {
-		a.Assets[coin] = a.Assets[coin].Add(quantity)
+	// A fill that can't be settled yet stays pending, Reconcile retries it and SyncWithMarket reports the error.
+	transaction, _, settled, _ := a.settle(pending)
+
+	if !settled {
+		return transactionValue, nil
 	}
 
-	return a.AssetValues[coin].Mul(quantity.Mul(decimal.NewFromInt(1).Add(a.Fee))), nil
+	return transaction, nil
 }
 
 func (a *Accountant) Sell(coin string, quantity decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
@@ -81,24 +108,163 @@ func (a *Accountant) Sell(coin string, quantity decimal.Decimal) (decimal.Decima
 		return decimal.Zero, decimal.Zero, errors.New(fmt.Sprintf("negative buy quantity %s", quantity))
 	}
 
+	if err := a.checkQuote(coin); err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+
 	if quantity.GreaterThan(a.Assets[coin]) {
 		return decimal.Zero, decimal.Zero, errors.New(fmt.Sprintf("sell quantity: %s exceeds available: %s", quantity, a.Assets[coin]))
 	}
 
 	sellOrder := model.OrderRequest{
-		Symbol:    coin,
-		Side:      model.SELL,
-		Type:      model.MARKET,
-		Timestamp: a.GetTimeStamp(),
-		Quantity:  quantity,
+		Symbol:        coin,
+		Side:          model.SELL,
+		Type:          model.MARKET,
+		Timestamp:     a.GetTimeStamp(),
+		Quantity:      quantity,
+		ClientOrderId: a.newClientOrderId(coin),
 	}
 
 	if err := a.Market.NewOrder(sellOrder); err != nil {
 		return decimal.Zero, decimal.Zero, err
 	}
 
-	positionBuyValues := make([]string, 0, len(a.Positions[coin]))
-	for pbv := range a.Positions[coin] {
+	pending := &PendingOrder{
+		ClientOrderId: sellOrder.ClientOrderId,
+		Coin:          coin,
+		Side:          model.SELL,
+		Quantity:      quantity,
+		Lots:          make(map[string]decimal.Decimal),
+	}
+
+	a.Assets[coin] = a.Assets[coin].Sub(quantity)
+	a.consumeLots(coin, a.Positions[coin], quantity, pending.Lots)
+	a.PendingOrders[pending.ClientOrderId] = pending
+
+	transaction, profit, _, _ := a.settle(pending)
+
+	return transaction, profit, nil
+}
+
+// Reconcile settles the fills of the pending orders, in order of their ids so that runs repeat. It returns the first
+// fill that couldn't be settled, the orders after it are settled all the same.
+func (a *Accountant) Reconcile() error {
+	ids := make([]string, 0, len(a.PendingOrders))
+	for id := range a.PendingOrders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var firstErr error
+	for _, id := range ids {
+		if _, _, _, err := a.settle(a.PendingOrders[id]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (a *Accountant) settle(pending *PendingOrder) (decimal.Decimal, decimal.Decimal, bool, error) {
+	order := a.findOrder(pending.ClientOrderId)
+
+	if order == nil {
+		return decimal.Zero, decimal.Zero, false, nil
+	}
+
+	var transaction decimal.Decimal
+	var profit decimal.Decimal
+
+	for _, fill := range order.Fills[pending.SettledFills:] {
+		commissionValue, err := a.commissionValue(pending.Coin, fill)
+		if err != nil {
+			return transaction, profit, false, err
+		}
+		a.Commissions[fill.CommissionAsset] = a.Commissions[fill.CommissionAsset].Add(fill.Commission)
+		a.FeesPaid = a.FeesPaid.Add(commissionValue)
+		a.Volume = a.Volume.Add(fill.Price.Mul(fill.Qty))
+
+		if pending.Side == model.BUY {
+			cost := fill.Price.Mul(fill.Qty)
+			received := fill.Qty
+
+			if fill.CommissionAsset == a.QuoteAsset {
+				cost = cost.Add(fill.Commission)
+			} else if fill.CommissionAsset == a.baseAsset(pending.Coin) {
+				received = received.Sub(fill.Commission)
+			}
+
+			pending.Reserved = pending.Reserved.Sub(cost)
+			a.addPosition(pending.Coin, fill.Price, received, commissionValue)
+			transaction = transaction.Add(cost)
+		} else if pending.Side == model.SELL {
+			proceeds := fill.Price.Mul(fill.Qty)
+			if fill.CommissionAsset == a.QuoteAsset {
+				proceeds = proceeds.Sub(fill.Commission)
+			}
+
+			costBasis := a.consumeLots(pending.Coin, pending.Lots, fill.Qty, nil)
+			if fill.CommissionAsset != a.QuoteAsset {
+				costBasis = costBasis.Add(commissionValue)
+			}
+
+			a.Balance = a.Balance.Add(proceeds)
+			transaction = transaction.Add(proceeds)
+			profit = profit.Add(proceeds.Sub(costBasis))
+		}
+		pending.Quantity = pending.Quantity.Sub(fill.Qty)
+		pending.SettledFills++
+	}
+
+	if order.Status.IsOpen() {
+		return transaction, profit, false, nil
+	}
+
+	if pending.Side == model.BUY {
+		a.Balance = a.Balance.Add(pending.Reserved)
+	} else if pending.Side == model.SELL {
+		for pbv, qty := range pending.Lots {
+			pbvDecimal, _ := decimal.NewFromString(pbv)
+			a.addPosition(pending.Coin, pbvDecimal, qty, a.lotFee(pending.Coin, pbv).Mul(qty))
+		}
+		a.pruneLotFees(pending.Coin)
+	}
+
+	delete(a.PendingOrders, pending.ClientOrderId)
+
+	return transaction, profit, order.Status == model.FILLED, nil
+}
+
+func (a *Accountant) addPosition(coin string, price decimal.Decimal, quantity decimal.Decimal, commissionValue decimal.Decimal) {
+	if _, hasKey := a.Positions[coin]; !hasKey {
+		a.Positions[coin] = make(map[string]decimal.Decimal)
+	}
+
+	if _, hasKey := a.lotFees[coin]; !hasKey {
+		a.lotFees[coin] = make(map[string]decimal.Decimal)
+	}
+
+	if lotQty := a.Positions[coin][price.String()].Add(quantity); lotQty.GreaterThan(decimal.Zero) {
+		lotCommission := a.lotFee(coin, price.String()).Mul(a.Positions[coin][price.String()]).Add(commissionValue)
+		a.lotFees[coin][price.String()] = lotCommission.Div(lotQty)
+	}
+
+	if _, hasKey := a.Positions[coin][price.String()]; hasKey {
+		a.Positions[coin][price.String()] = a.Positions[coin][price.String()].Add(quantity)
+	} else {
+		a.Positions[coin][price.String()] = quantity
+	}
+
+	if _, hasKey := a.Assets[coin]; !hasKey {
+		a.Assets[coin] = quantity
+	} else {
+		a.Assets[coin] = a.Assets[coin].Add(quantity)
+	}
+}
+
+func (a *Accountant) consumeLots(coin string, lots map[string]decimal.Decimal, quantity decimal.Decimal, taken map[string]decimal.Decimal) decimal.Decimal {
+	positionBuyValues := make([]string, 0, len(lots))
+	for pbv := range lots {
 		positionBuyValues = append(positionBuyValues, pbv)
 	}
 
@@ -113,27 +279,124 @@ func (a *Accountant) Sell(coin string, quantity decimal.Decimal) (decimal.Decima
 	var positionTransactionSum decimal.Decimal
 
 	for _, pbv := range positionBuyValues {
-		if remainingQty.GreaterThanOrEqual(a.Positions[coin][pbv]) {
-			remainingQty = remainingQty.Sub(a.Positions[coin][pbv])
-			pbvDecimal, _ := decimal.NewFromString(pbv)
-			positionTransactionSum = positionTransactionSum.Add(a.Positions[coin][pbv].Mul(pbvDecimal).Mul(decimal.NewFromInt(1).Add(a.Fee)))
-			delete(a.Positions[coin], pbv)
+		pbvDecimal, _ := decimal.NewFromString(pbv)
+		takenQty := decimal.Min(remainingQty, lots[pbv])
+
+		if remainingQty.GreaterThanOrEqual(lots[pbv]) {
+			delete(lots, pbv)
 		} else {
-			a.Positions[coin][pbv] = a.Positions[coin][pbv].Sub(remainingQty)
-			positionTransactionSum = positionTransactionSum.Add(a.Positions[coin][pbv].Mul(remainingQty).Mul(decimal.NewFromInt(1).Add(a.Fee)))
-			remainingQty = decimal.Zero
+			lots[pbv] = lots[pbv].Sub(remainingQty)
 		}
+
+		if taken != nil {
+			taken[pbv] = taken[pbv].Add(takenQty)
+		}
+
+		remainingQty = remainingQty.Sub(takenQty)
+		positionTransactionSum = positionTransactionSum.Add(takenQty.Mul(pbvDecimal.Add(a.lotFee(coin, pbv))))
+
 		if remainingQty.IsZero() {
 			break
 		}
 	}
 
-	a.Assets[coin] = a.Assets[coin].Sub(quantity)
-	transaction := a.AssetValues[coin].Mul(quantity.Mul(decimal.NewFromInt(1).Sub(a.Fee)))
-	profit := transaction.Sub(positionTransactionSum)
-	a.Balance = a.Balance.Add(transaction)
+	return positionTransactionSum
+}
 
-	return transaction, profit, nil
+// lotFee is the commission paid per unit of the lot bought at pbv, valued in the quote asset. Lots without a recorded
+// commission are assumed to have paid the accountant fee.
+func (a *Accountant) lotFee(coin string, pbv string) decimal.Decimal {
+	if fee, hasKey := a.lotFees[coin][pbv]; hasKey {
+		return fee
+	}
+
+	pbvDecimal, _ := decimal.NewFromString(pbv)
+	return pbvDecimal.Mul(a.Fee)
+}
+
+func (a *Accountant) pruneLotFees(coin string) {
+	for pbv := range a.lotFees[coin] {
+		if _, hasKey := a.Positions[coin][pbv]; hasKey {
+			continue
+		}
+
+		held := false
+		for _, pending := range a.PendingOrders {
+			if _, hasKey := pending.Lots[pbv]; hasKey && pending.Coin == coin {
+				held = true
+				break
+			}
+		}
+
+		if !held {
+			delete(a.lotFees[coin], pbv)
+		}
+	}
+}
+
+// commissionValue values the commission of a fill in the quote asset, whichever asset it was charged in. It fails when
+// the commission asset has no symbol or price against the quote asset.
+func (a *Accountant) commissionValue(coin string, fill model.Fill) (decimal.Decimal, error) {
+	switch fill.CommissionAsset {
+	case "", a.QuoteAsset:
+		return fill.Commission, nil
+	case a.baseAsset(coin):
+		return fill.Commission.Mul(fill.Price), nil
+	}
+
+	pair, err := a.Market.Symbols().Pair(fill.CommissionAsset, a.QuoteAsset)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	assetPrice, hasKey := a.AssetValues[pair.Symbol]
+	if !hasKey {
+		assetPrice, err = a.Market.CoinValue(pair.Symbol)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("valuing the %s commission: %w", fill.CommissionAsset, err)
+		}
+	}
+
+	return fill.Commission.Mul(assetPrice), nil
+}
+
+func (a *Accountant) baseAsset(coin string) string {
+	symbol, err := a.Market.Symbols().Get(coin)
+	if err != nil {
+		return ""
+	}
+	return symbol.BaseAsset
+}
+
+func (a *Accountant) findOrder(clientOrderId string) *model.OrderResponseFull {
+	history := a.Market.OrderHistory()
+
+	for idx := len(history) - 1; idx >= 0; idx-- {
+		if history[idx].ClientOrderId == clientOrderId {
+			return history[idx]
+		}
+	}
+
+	return nil
+}
+
+func (a *Accountant) checkQuote(coin string) error {
+	symbol, err := a.Market.Symbols().Get(coin)
+	if err != nil {
+		return err
+	}
+
+	if symbol.QuoteAsset != a.QuoteAsset {
+		return fmt.Errorf("%w: %s is quoted in %s but balance is kept in %s", model.ErrInvalidSymbol, coin,
+			symbol.QuoteAsset, a.QuoteAsset)
+	}
+
+	return nil
+}
+
+func (a *Accountant) newClientOrderId(coin string) string {
+	a.orderCount++
+	return fmt.Sprintf("%s-%d-%d", coin, a.GetTimeStamp(), a.orderCount)
 }
 
 func (a *Accountant) UpdateAssetValue(coin string, value decimal.Decimal, timestamp time.Time) error {
@@ -141,7 +404,10 @@ func (a *Accountant) UpdateAssetValue(coin string, value decimal.Decimal, timest
 		return errors.New(fmt.Sprintf("negative asset value (%s,%s)", coin, value))
 	}
 	a.AssetValues[coin] = value
-	a.Market.UpdateCoinValue(coin, value)
+	if timestamp.After(a.LastUpdate) {
+		a.LastUpdate = timestamp
+	}
+	a.Market.UpdateCoinValue(coin, value, timestamp)
 	return nil
 }
 
@@ -165,8 +431,20 @@ func (a *Accountant) TotalAssetValue() decimal.Decimal {
 	return totalValue
 }
 
+func (a *Accountant) PendingValue() decimal.Decimal {
+	var pendingValue decimal.Decimal
+	for _, pending := range a.PendingOrders {
+		if pending.Side == model.BUY {
+			pendingValue = pendingValue.Add(pending.Reserved)
+		} else if pending.Side == model.SELL {
+			pendingValue = pendingValue.Add(a.AssetValues[pending.Coin].Mul(pending.Quantity))
+		}
+	}
+	return pendingValue
+}
+
 func (a *Accountant) NetWorth() decimal.Decimal {
-	return a.Balance.Add(a.TotalAssetValue())
+	return a.Balance.Add(a.TotalAssetValue()).Add(a.PendingValue())
 }
 
 func (a *Accountant) AssetQty(asset string) decimal.Decimal {
@@ -206,13 +484,23 @@ func (a *Accountant) ToString() string {
 }
 
 func (a *Accountant) GetTimeStamp() int64 {
+	if !a.LastUpdate.IsZero() {
+		return a.LastUpdate.UnixNano() / int64(time.Millisecond)
+	}
 	return time.Now().UnixNano() / int64(time.Millisecond)
 }
 
-func (a *Accountant) SyncWithMarket() {
-	marketBalance, err := a.Market.Balance("USDT")
+// SyncWithMarket reconciles the pending orders and checks the free balance of the market matches the accounted one.
+func (a *Accountant) SyncWithMarket() error {
+	if err := a.Reconcile(); err != nil {
+		return err
+	}
+
+	marketBalance, err := a.Market.Balance(a.QuoteAsset)
 
 	if err == nil && !a.Balance.Equal(marketBalance.Free) {
-		panic(fmt.Sprintf("Incoherent balance Acc:%s Market:%s", a.Balance, marketBalance.Free))
+		return errors.New(fmt.Sprintf("incoherent balance Acc:%s Market:%s", a.Balance, marketBalance.Free))
 	}
+
+	return nil
 }
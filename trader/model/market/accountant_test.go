@@ -3,6 +3,7 @@ package market
 import (
 	"fmt"
 	"github.com/shopspring/decimal"
+	"scoing-trader/trader/model/market/model"
 	"testing"
 	"time"
 )
@@ -160,3 +161,168 @@ func TestPositionValue(t *testing.T) {
 		t.Error("Expected Position Value 170, got ", accountant.NetWorth())
 	}
 }
+
+func TestPendingOrderReconciliation(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	market := NewSimulatedMarket(1, decimal.Zero)
+	market.SetOrderLifecycle(OrderLifecycle{MinLatency: time.Minute, MaxLatency: time.Minute})
+	market.Deposit("USDT", decimal.NewFromInt(100))
+	accountant := NewAccountant(market, decimal.NewFromInt(100), decimal.Zero)
+
+	err := accountant.UpdateAssetValue("BTCUSDT", decimal.NewFromInt(10), start)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = accountant.Buy("BTCUSDT", decimal.NewFromInt(2))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(accountant.PendingOrders) != 1 || !accountant.AssetQty("BTCUSDT").IsZero() {
+		t.Fatal("Expected buy order to be pending")
+	}
+
+	if !accountant.NetWorth().Equal(decimal.NewFromInt(100)) {
+		t.Error("Expected net worth 100 while order is pending, got ", accountant.NetWorth())
+	}
+
+	if err := accountant.SyncWithMarket(); err != nil {
+		t.Error(err)
+	}
+
+	err = accountant.UpdateAssetValue("BTCUSDT", decimal.NewFromInt(8), start.Add(time.Minute))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := accountant.SyncWithMarket(); err != nil {
+		t.Error(err)
+	}
+
+	if len(accountant.PendingOrders) != 0 {
+		t.Fatal("Filled order still pending")
+	}
+
+	if !accountant.Balance.Equal(decimal.NewFromInt(84)) {
+		t.Error("Expected balance=84, got ", accountant.Balance)
+	}
+
+	if !accountant.Positions["BTCUSDT"][decimal.NewFromInt(8).String()].Equal(decimal.NewFromInt(2)) {
+		t.Error("Expected position of 2 at fill price 8, got ", accountant.Positions["BTCUSDT"])
+	}
+
+	market.SetOrderLifecycle(OrderLifecycle{MinLatency: time.Hour, MaxLatency: time.Hour, Expiry: time.Minute})
+
+	_, _, err = accountant.Sell("BTCUSDT", decimal.NewFromInt(2))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(accountant.Positions["BTCUSDT"]) != 0 {
+		t.Error("Positions of pending sell still available")
+	}
+
+	err = accountant.UpdateAssetValue("BTCUSDT", decimal.NewFromInt(9), start.Add(5*time.Minute))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := accountant.SyncWithMarket(); err != nil {
+		t.Error(err)
+	}
+
+	if len(accountant.PendingOrders) != 0 || market.OrderHistory()[1].Status != model.EXPIRED {
+		t.Fatal("Expected sell order to expire")
+	}
+
+	if !accountant.AssetQty("BTCUSDT").Equal(decimal.NewFromInt(2)) ||
+		!accountant.Positions["BTCUSDT"][decimal.NewFromInt(8).String()].Equal(decimal.NewFromInt(2)) {
+		t.Error("Expired sell did not restore positions")
+	}
+}
+
+func TestCommissionAsset(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.NewFromFloat(0.001))
+	market.Deposit("USDT", decimal.NewFromInt(100))
+	market.Deposit("BNB", decimal.NewFromInt(1))
+	market.SetCommissionAsset("BNB", decimal.NewFromFloat(0.25))
+	accountant := NewAccountant(market, decimal.NewFromInt(100), decimal.NewFromFloat(0.001))
+
+	start := time.Unix(1000, 0)
+	if err := accountant.UpdateAssetValue("BNBUSDT", decimal.NewFromInt(15), start); err != nil {
+		t.Fatal(err)
+	}
+	if err := accountant.UpdateAssetValue("BTCUSDT", decimal.NewFromInt(10), start); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := accountant.Buy("BTCUSDT", decimal.NewFromInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !transaction.Equal(decimal.NewFromInt(50)) || !accountant.Balance.Equal(decimal.NewFromInt(50)) {
+		t.Error(fmt.Sprintf("Expected transaction=50 and balance=50 got %s and %s", transaction, accountant.Balance))
+	}
+
+	if !accountant.Commissions["BNB"].Equal(decimal.NewFromFloat(0.0025)) {
+		t.Error("Expected 0.0025 BNB commission got ", accountant.Commissions["BNB"])
+	}
+
+	if err := accountant.UpdateAssetValue("BTCUSDT", decimal.NewFromInt(12), start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, profit, err := accountant.Sell("BTCUSDT", decimal.NewFromInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !transaction.Equal(decimal.NewFromInt(60)) {
+		t.Error("Expected transaction=60 got ", transaction)
+	}
+
+	if !profit.Equal(decimal.NewFromFloat(9.9175)) {
+		t.Error("Expected profit=9.9175 got ", profit)
+	}
+
+	if !accountant.FeesPaid.Equal(decimal.NewFromFloat(0.0825)) || !accountant.Volume.Equal(decimal.NewFromInt(110)) {
+		t.Error(fmt.Sprintf("Expected fees=0.0825 and volume=110 got %s and %s", accountant.FeesPaid, accountant.Volume))
+	}
+
+	if err := accountant.SyncWithMarket(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCommissionValue(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	accountant := NewAccountant(market, decimal.Zero, decimal.Zero)
+	fill := model.Fill{Price: decimal.NewFromInt(10), Commission: decimal.NewFromInt(2), CommissionAsset: "BNB"}
+
+	if _, err := accountant.commissionValue("BTCUSDT", fill); err == nil {
+		t.Error("Expected a commission without a BNB price to fail")
+	}
+
+	market.UpdateCoinValue("BNBUSDT", decimal.NewFromInt(15), time.Now())
+	if value, err := accountant.commissionValue("BTCUSDT", fill); err != nil || !value.Equal(decimal.NewFromInt(30)) {
+		t.Error("Expected the commission valued at the BNBUSDT price got ", value, err)
+	}
+}
+
+func TestSyncWithMarketDrift(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.Deposit("USDT", decimal.NewFromInt(100))
+	accountant := NewAccountant(market, decimal.NewFromInt(100), decimal.Zero)
+
+	if err := accountant.SyncWithMarket(); err != nil {
+		t.Error(err)
+	}
+
+	market.Deposit("USDT", decimal.NewFromInt(1))
+	if err := accountant.SyncWithMarket(); err == nil {
+		t.Error("Expected a balance drift to fail the sync")
+	}
+}
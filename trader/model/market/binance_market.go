@@ -0,0 +1,546 @@
+package market
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log"
+	"net/http"
+	"net/url"
+	"scoing-trader/trader/model/market/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const binanceRecvWindow = 5000
+
+type BinanceMarket struct {
+	httpClient  http.Client
+	endpoint    string
+	apiKey      string
+	secretKey   string
+	timeOffset  int64
+	symbols     *model.SymbolRegistry
+	accountInfo model.AccountInformation
+	orderList   []*model.OrderResponseFull
+	tradeList   []*model.Trade
+	coinValues  map[string]decimal.Decimal
+}
+
+type APIError struct {
+	StatusCode int
+	Code       int64  `json:"code"`
+	Message    string `json:"msg"`
+}
+
+type binanceOrder struct {
+	Symbol              string          `json:"symbol"`
+	OrderId             int64           `json:"orderId"`
+	OrderListId         int64           `json:"orderListId"`
+	ClientOrderId       string          `json:"clientOrderId"`
+	TransactTime        int64           `json:"transactTime"`
+	Time                int64           `json:"time"`
+	Price               decimal.Decimal `json:"price"`
+	OrigQty             decimal.Decimal `json:"origQty"`
+	ExecutedQty         decimal.Decimal `json:"executedQty"`
+	CummulativeQuoteQty decimal.Decimal `json:"cummulativeQuoteQty"`
+	Status              string          `json:"status"`
+	TimeInForce         string          `json:"timeInForce"`
+	Type                string          `json:"type"`
+	Side                string          `json:"side"`
+	Fills               []struct {
+		TradeId         int64           `json:"tradeId"`
+		Price           decimal.Decimal `json:"price"`
+		Qty             decimal.Decimal `json:"qty"`
+		Commission      decimal.Decimal `json:"commission"`
+		CommissionAsset string          `json:"commissionAsset"`
+	} `json:"fills"`
+}
+
+func NewBinanceMarket(endpoint string, apiKey string, secretKey string, timeout int) (*BinanceMarket, error) {
+	b := &BinanceMarket{
+		httpClient:  http.Client{Timeout: time.Duration(timeout) * time.Second},
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		symbols:     model.NewSymbolRegistry(),
+		accountInfo: model.AccountInformation{},
+		orderList:   make([]*model.OrderResponseFull, 0),
+		tradeList:   make([]*model.Trade, 0),
+		coinValues:  make(map[string]decimal.Decimal),
+	}
+
+	if err := b.SyncTime(); err != nil {
+		return nil, err
+	}
+
+	if err := b.LoadExchangeInfo(); err != nil {
+		return nil, err
+	}
+
+	if err := b.updateAccount(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *BinanceMarket) SyncTime() error {
+	var serverTime struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+
+	before := localMillis()
+	if err := b.send(http.MethodGet, "/api/v3/time", nil, false, &serverTime); err != nil {
+		return err
+	}
+	after := localMillis()
+
+	b.timeOffset = serverTime.ServerTime - (before+after)/2
+
+	return nil
+}
+
+func (b *BinanceMarket) LoadExchangeInfo() error {
+	var exchangeInfo json.RawMessage
+
+	if err := b.request(http.MethodGet, "/api/v3/exchangeInfo", nil, false, &exchangeInfo); err != nil {
+		return err
+	}
+
+	symbols, err := model.ParseExchangeInfo(bytes.NewReader(exchangeInfo))
+	if err != nil {
+		return err
+	}
+
+	b.symbols = symbols
+
+	return nil
+}
+
+func (b *BinanceMarket) Symbols() *model.SymbolRegistry {
+	return b.symbols
+}
+
+func (b *BinanceMarket) NewOrder(order model.OrderRequest) error {
+	symbol, err := b.symbols.Lookup(order.Symbol)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("symbol", order.Symbol)
+	params.Set("side", string(order.Side))
+	params.Set("type", string(order.Type))
+	params.Set("newOrderRespType", string(model.FULL))
+
+	if order.ClientOrderId != "" {
+		params.Set("newClientOrderId", order.ClientOrderId)
+	}
+
+	quantity := symbol.RoundQty(order.Quantity)
+
+	if quantity.GreaterThan(decimal.Zero) {
+		if quantity.LessThan(symbol.MinQty) {
+			return fmt.Errorf("%w: quantity %s below minimum %s for %s", model.ErrFilterFailure, quantity,
+				symbol.MinQty, order.Symbol)
+		}
+		params.Set("quantity", quantity.String())
+	} else if order.Type == model.MARKET && order.QuoteOrderQty.GreaterThan(decimal.Zero) {
+		params.Set("quoteOrderQty", order.QuoteOrderQty.String())
+	} else {
+		return fmt.Errorf("%w: quantity %s below step size %s for %s", model.ErrFilterFailure, order.Quantity,
+			symbol.StepSize, order.Symbol)
+	}
+
+	price := b.coinValues[order.Symbol]
+
+	if order.Type.HasLimitPrice() {
+		price = symbol.RoundPrice(order.Price)
+		params.Set("price", price.String())
+
+		if order.Type != model.LIMIT_MAKER {
+			timeInForce := order.TimeInForce
+			if timeInForce == "" {
+				timeInForce = model.GTC
+			}
+			params.Set("timeInForce", string(timeInForce))
+		}
+	}
+
+	if order.Type.HasStopPrice() {
+		params.Set("stopPrice", symbol.RoundPrice(order.StopPrice).String())
+	}
+
+	if order.IcebergQty.GreaterThan(decimal.Zero) {
+		params.Set("icebergQty", symbol.RoundQty(order.IcebergQty).String())
+	}
+
+	notional := quantity.Mul(price)
+	if notional.GreaterThan(decimal.Zero) && notional.LessThan(symbol.MinNotional) {
+		return fmt.Errorf("%w: notional %s below minimum %s for %s", model.ErrFilterFailure, notional,
+			symbol.MinNotional, order.Symbol)
+	}
+
+	var response binanceOrder
+
+	if err := b.request(http.MethodPost, "/api/v3/order", params, true, &response); err != nil {
+		return err
+	}
+
+	orderResp := response.toModel()
+	b.orderList = append(b.orderList, orderResp)
+
+	for idx, fill := range orderResp.Fills {
+		b.tradeList = append(b.tradeList, &model.Trade{
+			Symbol:          orderResp.Symbol,
+			Id:              response.Fills[idx].TradeId,
+			OrderId:         orderResp.OrderId,
+			OrderListId:     orderResp.OrderListId,
+			Price:           fill.Price,
+			Qty:             fill.Qty,
+			Commission:      fill.Commission,
+			CommissionAsset: fill.CommissionAsset,
+			Time:            orderResp.TransactionTime,
+			IsBuyer:         orderResp.Side == model.BUY,
+			IsMaker:         false,
+			IsBestMatch:     true,
+		})
+	}
+
+	return b.updateAccount()
+}
+
+func (b *BinanceMarket) OpenOrders(symbol string) []*model.OrderResponseFull {
+	var openOrders []*model.OrderResponseFull
+
+	for _, order := range b.orderList {
+		if order.Status.IsOpen() && order.Symbol == symbol {
+			openOrders = append(openOrders, order)
+		}
+	}
+
+	return openOrders
+}
+
+func (b *BinanceMarket) OrderHistory() []*model.OrderResponseFull {
+	return b.orderList
+}
+
+func (b *BinanceMarket) CancelOrder(orderId string) error {
+	for _, order := range b.orderList {
+		if order.ClientOrderId == orderId {
+			params := url.Values{}
+			params.Set("symbol", order.Symbol)
+			params.Set("origClientOrderId", orderId)
+
+			var response binanceOrder
+
+			if err := b.request(http.MethodDelete, "/api/v3/order", params, true, &response); err != nil {
+				return err
+			}
+
+			order.Status = statusFromBinance(response.Status)
+			order.ExecutedQty = response.ExecutedQty
+			order.CummulativeQuoteQty = response.CummulativeQuoteQty
+
+			return b.updateAccount()
+		}
+	}
+	return model.ErrUnknownOrder
+}
+
+func (b *BinanceMarket) AccountInformation() model.AccountInformation {
+	return b.accountInfo
+}
+
+func (b *BinanceMarket) Balance(asset string) (model.Balance, error) {
+	for _, balance := range b.accountInfo.Balances {
+		if balance.Asset == asset {
+			return balance, nil
+		}
+	}
+	return model.Balance{}, errors.New("balance for asset " + asset + " does not exist")
+}
+
+func (b *BinanceMarket) Trades() []*model.Trade {
+	return b.tradeList
+}
+
+func (b *BinanceMarket) UpdateInformation() error {
+	if err := b.updateAccount(); err != nil {
+		return err
+	}
+
+	if err := b.updateOrders(); err != nil {
+		return err
+	}
+
+	return b.updateTrades()
+}
+
+func (b *BinanceMarket) CoinValue(asset string) (decimal.Decimal, error) {
+	var ticker struct {
+		Price decimal.Decimal `json:"price"`
+	}
+
+	params := url.Values{}
+	params.Set("symbol", asset)
+
+	if err := b.request(http.MethodGet, "/api/v3/ticker/price", params, false, &ticker); err != nil {
+		return decimal.Zero, err
+	}
+
+	b.coinValues[asset] = ticker.Price
+
+	return ticker.Price, nil
+}
+
+func (b *BinanceMarket) Deposit(asset string, qty decimal.Decimal) {
+	log.Printf("Ignoring deposit of %s %s, deposits must be made on the exchange", qty, asset)
+}
+
+func (b *BinanceMarket) UpdateCoinValue(asset string, value decimal.Decimal, timestamp time.Time) {
+	b.coinValues[asset] = value
+}
+
+func (b *BinanceMarket) updateAccount() error {
+	var accountInfo model.AccountInformation
+
+	if err := b.request(http.MethodGet, "/api/v3/account", nil, true, &accountInfo); err != nil {
+		return err
+	}
+
+	b.accountInfo = accountInfo
+
+	return nil
+}
+
+func (b *BinanceMarket) updateOrders() error {
+	var openOrders []binanceOrder
+
+	if err := b.request(http.MethodGet, "/api/v3/openOrders", nil, true, &openOrders); err != nil {
+		return err
+	}
+
+	stillOpen := make(map[int64]bool)
+
+	for _, openOrder := range openOrders {
+		stillOpen[openOrder.OrderId] = true
+		if known := b.findOrder(openOrder.OrderId); known != nil {
+			*known = *openOrder.toModel()
+		} else {
+			b.orderList = append(b.orderList, openOrder.toModel())
+		}
+	}
+
+	for _, order := range b.orderList {
+		if !order.Status.IsOpen() || stillOpen[order.OrderId] {
+			continue
+		}
+
+		params := url.Values{}
+		params.Set("symbol", order.Symbol)
+		params.Set("orderId", strconv.FormatInt(order.OrderId, 10))
+
+		var response binanceOrder
+
+		if err := b.request(http.MethodGet, "/api/v3/order", params, true, &response); err != nil {
+			return err
+		}
+
+		order.Status = statusFromBinance(response.Status)
+		order.ExecutedQty = response.ExecutedQty
+		order.CummulativeQuoteQty = response.CummulativeQuoteQty
+	}
+
+	return nil
+}
+
+func (b *BinanceMarket) updateTrades() error {
+	var symbols []string
+	seen := make(map[string]bool)
+
+	for _, order := range b.orderList {
+		if !seen[order.Symbol] {
+			seen[order.Symbol] = true
+			symbols = append(symbols, order.Symbol)
+		}
+	}
+
+	tradeList := make([]*model.Trade, 0)
+
+	for _, symbol := range symbols {
+		var trades []*model.Trade
+
+		params := url.Values{}
+		params.Set("symbol", symbol)
+
+		if err := b.request(http.MethodGet, "/api/v3/myTrades", params, true, &trades); err != nil {
+			return err
+		}
+
+		tradeList = append(tradeList, trades...)
+	}
+
+	b.tradeList = tradeList
+
+	return nil
+}
+
+func (b *BinanceMarket) findOrder(orderId int64) *model.OrderResponseFull {
+	for _, order := range b.orderList {
+		if order.OrderId == orderId {
+			return order
+		}
+	}
+	return nil
+}
+
+func (b *BinanceMarket) request(method string, path string, params url.Values, signed bool, out interface{}) error {
+	err := b.send(method, path, params, signed, out)
+
+	if signed && errors.Is(err, model.ErrTimestampOutOfSync) {
+		if syncErr := b.SyncTime(); syncErr != nil {
+			return syncErr
+		}
+		err = b.send(method, path, params, signed, out)
+	}
+
+	return err
+}
+
+func (b *BinanceMarket) send(method string, path string, params url.Values, signed bool, out interface{}) error {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+
+	if signed {
+		query.Set("recvWindow", strconv.Itoa(binanceRecvWindow))
+		query.Set("timestamp", strconv.FormatInt(localMillis()+b.timeOffset, 10))
+	}
+
+	encoded := query.Encode()
+
+	if signed {
+		encoded += "&signature=" + b.sign(encoded)
+	}
+
+	endpoint := b.endpoint + path
+	if encoded != "" {
+		endpoint += "?" + encoded
+	}
+
+	req, err := http.NewRequest(method, endpoint, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if b.apiKey != "" {
+		req.Header.Set("X-MBX-APIKEY", b.apiKey)
+	}
+
+	resp, err := b.httpClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (b *BinanceMarket) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(b.secretKey))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (o *binanceOrder) toModel() *model.OrderResponseFull {
+	transactionTime := o.TransactTime
+	if transactionTime == 0 {
+		transactionTime = o.Time
+	}
+
+	orderResp := &model.OrderResponseFull{
+		Symbol:              o.Symbol,
+		OrderId:             o.OrderId,
+		OrderListId:         o.OrderListId,
+		ClientOrderId:       o.ClientOrderId,
+		TransactionTime:     transactionTime,
+		Price:               o.Price,
+		OrigQty:             o.OrigQty,
+		ExecutedQty:         o.ExecutedQty,
+		CummulativeQuoteQty: o.CummulativeQuoteQty,
+		Status:              statusFromBinance(o.Status),
+		TimeInForce:         model.OrderTimeInForce(o.TimeInForce),
+		Type:                model.OrderType(o.Type),
+		Side:                model.OrderSide(o.Side),
+		Fills:               nil,
+	}
+
+	for _, fill := range o.Fills {
+		orderResp.Fills = append(orderResp.Fills, model.Fill{
+			Price:           fill.Price,
+			Qty:             fill.Qty,
+			Commission:      fill.Commission,
+			CommissionAsset: fill.CommissionAsset,
+		})
+	}
+
+	return orderResp
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("binance error %d (http %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusTeapot || e.Code == -1003:
+		return model.ErrRateLimited
+	case e.Code == -1021:
+		return model.ErrTimestampOutOfSync
+	case e.Code == -1022 || e.Code == -2014 || e.Code == -2015:
+		return model.ErrInvalidCredentials
+	case e.Code == -1121:
+		return model.ErrInvalidSymbol
+	case e.Code == -1013:
+		return model.ErrFilterFailure
+	case e.Code == -2011 || e.Code == -2013:
+		return model.ErrUnknownOrder
+	case e.Code == -2010 && strings.Contains(strings.ToLower(e.Message), "insufficient balance"):
+		return model.ErrInsufficientBalance
+	}
+	return nil
+}
+
+func statusFromBinance(status string) model.OrderResponseStatus {
+	if status == "CANCELED" || status == "PENDING_CANCEL" {
+		return model.CANCELLED
+	}
+	return model.OrderResponseStatus(status)
+}
+
+func localMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,319 @@
+package market
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"scoing-trader/trader/model/market/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testApiKey = "test-key"
+const testSecretKey = "test-secret"
+
+type fakeExchange struct {
+	serverTimeOffset int64
+	orders           []map[string]string
+	rejectTimestamps int
+}
+
+func (f *fakeExchange) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"serverTime":%d}`, localMillis()+f.serverTimeOffset)
+	})
+
+	mux.HandleFunc("/api/v3/exchangeInfo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT",
+			"filters":[{"filterType":"PRICE_FILTER","minPrice":"0.01","tickSize":"0.01"},
+			{"filterType":"LOT_SIZE","minQty":"0.001","stepSize":"0.001"},
+			{"filterType":"MIN_NOTIONAL","minNotional":"10.00"}]}]}`)
+	})
+
+	mux.HandleFunc("/api/v3/account", func(w http.ResponseWriter, r *http.Request) {
+		if !f.authorize(t, w, r) {
+			return
+		}
+		fmt.Fprint(w, `{"makerCommission":10,"takerCommission":10,"canTrade":true,"accountType":"SPOT",
+			"balances":[{"asset":"USDT","free":"1000.00","locked":"0.00"},{"asset":"BTC","free":"0.5","locked":"0"}]}`)
+	})
+
+	mux.HandleFunc("/api/v3/openOrders", func(w http.ResponseWriter, r *http.Request) {
+		if f.authorize(t, w, r) {
+			fmt.Fprint(w, `[]`)
+		}
+	})
+
+	mux.HandleFunc("/api/v3/myTrades", func(w http.ResponseWriter, r *http.Request) {
+		if f.authorize(t, w, r) {
+			fmt.Fprint(w, `[]`)
+		}
+	})
+
+	mux.HandleFunc("/api/v3/order", func(w http.ResponseWriter, r *http.Request) {
+		if !f.authorize(t, w, r) {
+			return
+		}
+		query := r.URL.Query()
+
+		switch r.Method {
+		case http.MethodPost:
+			if query.Get("symbol") != "BTCUSDT" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code":-1121,"msg":"Invalid symbol."}`)
+				return
+			}
+			if query.Get("quantity") == "0.9" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code":-2010,"msg":"Account has insufficient balance for requested action."}`)
+				return
+			}
+			order := make(map[string]string)
+			for key := range query {
+				order[key] = query.Get(key)
+			}
+			f.orders = append(f.orders, order)
+			fmt.Fprintf(w, `{"symbol":"BTCUSDT","orderId":%d,"orderListId":-1,"clientOrderId":"%s","transactTime":1,
+				"price":"0","origQty":"%s","executedQty":"%s","cummulativeQuoteQty":"100","status":"FILLED",
+				"timeInForce":"GTC","type":"%s","side":"%s",
+				"fills":[{"price":"10000","qty":"%s","commission":"0.1","commissionAsset":"USDT","tradeId":7}]}`,
+				len(f.orders), query.Get("newClientOrderId"), query.Get("quantity"), query.Get("quantity"),
+				query.Get("type"), query.Get("side"), query.Get("quantity"))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-2011,"msg":"Unknown order sent."}`)
+		}
+	})
+
+	return mux
+}
+
+func (f *fakeExchange) authorize(t *testing.T, w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-MBX-APIKEY") != testApiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`)
+		return false
+	}
+
+	rawQuery := r.URL.RawQuery
+	sigIdx := strings.LastIndex(rawQuery, "&signature=")
+	if sigIdx == -1 {
+		t.Error("Missing signature on signed endpoint " + r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(testSecretKey))
+	mac.Write([]byte(rawQuery[:sigIdx]))
+	if hex.EncodeToString(mac.Sum(nil)) != rawQuery[sigIdx+len("&signature="):] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":-1022,"msg":"Signature for this request is not valid."}`)
+		return false
+	}
+
+	if f.rejectTimestamps > 0 {
+		f.rejectTimestamps--
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":-1021,"msg":"Timestamp for this request is outside of the recvWindow."}`)
+		return false
+	}
+
+	return true
+}
+
+func newTestBinanceMarket(t *testing.T, exchange *fakeExchange) (*BinanceMarket, *httptest.Server) {
+	server := httptest.NewServer(exchange.handler(t))
+
+	binance, err := NewBinanceMarket(server.URL, testApiKey, testSecretKey, 5)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return binance, server
+}
+
+func TestBinanceTimeSync(t *testing.T) {
+	exchange := &fakeExchange{serverTimeOffset: int64(time.Hour / time.Millisecond)}
+	binance, server := newTestBinanceMarket(t, exchange)
+	defer server.Close()
+
+	offset := binance.timeOffset - exchange.serverTimeOffset
+	if offset < -1000 || offset > 1000 {
+		t.Error(fmt.Sprintf("Incorrect time offset expected ~%d got %d", exchange.serverTimeOffset, binance.timeOffset))
+	}
+}
+
+func TestBinanceAccountInformation(t *testing.T) {
+	binance, server := newTestBinanceMarket(t, &fakeExchange{})
+	defer server.Close()
+
+	if binance.AccountInformation().MakerCommission != 10 {
+		t.Error(fmt.Sprintf("Incorrect maker commission expected 10 got %d", binance.AccountInformation().MakerCommission))
+	}
+
+	balance, err := binance.Balance("USDT")
+	if err != nil {
+		t.Error(err)
+	} else if !balance.Free.Equal(decimal.NewFromInt(1000)) {
+		t.Error(fmt.Sprintf("Incorrect free balance got %s expected 1000", balance.Free))
+	}
+}
+
+func TestBinanceOrderRounding(t *testing.T) {
+	exchange := &fakeExchange{}
+	binance, server := newTestBinanceMarket(t, exchange)
+	defer server.Close()
+
+	binance.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(10000), time.Now())
+
+	err := binance.NewOrder(model.OrderRequest{
+		Symbol:        "BTCUSDT",
+		Side:          model.BUY,
+		Type:          model.LIMIT,
+		Quantity:      decimal.NewFromFloat(0.0123456),
+		Price:         decimal.NewFromFloat(10000.12345),
+		ClientOrderId: "abc",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exchange.orders) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 order on exchange got %d", len(exchange.orders)))
+	}
+
+	sent := exchange.orders[0]
+	if sent["quantity"] != "0.012" {
+		t.Error("Expected quantity rounded to 0.012 got " + sent["quantity"])
+	}
+	if sent["price"] != "10000.12" {
+		t.Error("Expected price rounded to 10000.12 got " + sent["price"])
+	}
+	if sent["timeInForce"] != string(model.GTC) {
+		t.Error("Expected default time in force GTC got " + sent["timeInForce"])
+	}
+
+	if len(binance.OrderHistory()) != 1 || binance.OrderHistory()[0].Status != model.FILLED {
+		t.Error("Order not recorded as filled")
+	}
+
+	if len(binance.Trades()) != 1 || binance.Trades()[0].Id != 7 {
+		t.Error("Fill not recorded as trade")
+	}
+}
+
+func TestBinanceMinNotional(t *testing.T) {
+	exchange := &fakeExchange{}
+	binance, server := newTestBinanceMarket(t, exchange)
+	defer server.Close()
+
+	binance.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(5000), time.Now())
+
+	err := binance.NewOrder(model.OrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     model.BUY,
+		Type:     model.MARKET,
+		Quantity: decimal.NewFromFloat(0.001),
+	})
+
+	if !errors.Is(err, model.ErrFilterFailure) {
+		t.Error("Expected filter failure got ", err)
+	}
+
+	err = binance.NewOrder(model.OrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     model.BUY,
+		Type:     model.MARKET,
+		Quantity: decimal.NewFromFloat(0.0005),
+	})
+
+	if !errors.Is(err, model.ErrFilterFailure) {
+		t.Error("Expected filter failure got ", err)
+	}
+
+	if len(exchange.orders) != 0 {
+		t.Error("Order violating filters reached the exchange")
+	}
+
+	err = binance.NewOrder(model.OrderRequest{
+		Symbol:   "ETHUSDT",
+		Side:     model.BUY,
+		Type:     model.MARKET,
+		Quantity: decimal.NewFromInt(1),
+	})
+
+	if !errors.Is(err, model.ErrInvalidSymbol) || len(exchange.orders) != 0 {
+		t.Error("Expected a symbol missing from the exchange info to be rejected got ", err)
+	}
+}
+
+func TestBinanceErrorMapping(t *testing.T) {
+	binance, server := newTestBinanceMarket(t, &fakeExchange{})
+	defer server.Close()
+
+	binance.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(10000), time.Now())
+
+	err := binance.NewOrder(model.OrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     model.BUY,
+		Type:     model.MARKET,
+		Quantity: decimal.NewFromFloat(0.9),
+	})
+
+	if !errors.Is(err, model.ErrInsufficientBalance) {
+		t.Error("Expected insufficient balance got ", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != -2010 {
+		t.Error("Expected api error with code -2010 got ", err)
+	}
+
+	binance.orderList = append(binance.orderList, &model.OrderResponseFull{Symbol: "BTCUSDT", ClientOrderId: "gone",
+		Status: model.NEW})
+
+	if err := binance.CancelOrder("gone"); !errors.Is(err, model.ErrUnknownOrder) {
+		t.Error("Expected unknown order got ", err)
+	}
+
+	if err := binance.CancelOrder("never-placed"); !errors.Is(err, model.ErrUnknownOrder) {
+		t.Error("Expected unknown order got ", err)
+	}
+}
+
+func TestBinanceInvalidCredentials(t *testing.T) {
+	server := httptest.NewServer((&fakeExchange{}).handler(t))
+	defer server.Close()
+
+	_, err := NewBinanceMarket(server.URL, "wrong-key", testSecretKey, 5)
+
+	if !errors.Is(err, model.ErrInvalidCredentials) {
+		t.Error("Expected invalid credentials got ", err)
+	}
+}
+
+func TestBinanceTimestampResync(t *testing.T) {
+	exchange := &fakeExchange{}
+	binance, server := newTestBinanceMarket(t, exchange)
+	defer server.Close()
+
+	exchange.rejectTimestamps = 1
+
+	if err := binance.UpdateInformation(); err != nil {
+		t.Error(err)
+	}
+
+	if !binance.AccountInformation().CanTrade {
+		t.Error("Account information not refreshed after resync")
+	}
+}
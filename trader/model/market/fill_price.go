@@ -0,0 +1,217 @@
+package market
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"scoing-trader/trader/model/market/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var bpsDivisor = decimal.NewFromInt(10000)
+
+// FillPriceModel decides the price a taker order executes at given the reference price of the symbol, so that
+// backtests pay a realistic execution cost instead of trading exactly at the close value.
+type FillPriceModel interface {
+	FillPrice(symbol string, side model.OrderSide, qty decimal.Decimal, price decimal.Decimal,
+		timestamp time.Time) decimal.Decimal
+}
+
+// FixedSlippage moves every fill a constant number of basis points against the order.
+type FixedSlippage struct {
+	Bps decimal.Decimal
+}
+
+// VolumeImpact moves the fill against the order by Bps for every ReferenceNotional traded, so bigger orders pay
+// proportionally more.
+type VolumeImpact struct {
+	Bps               decimal.Decimal
+	ReferenceNotional decimal.Decimal
+}
+
+// SpreadBook fills orders against a recorded top of book. Buys pay the ask and sells receive the bid, the quantity
+// that exceeds the quoted size is filled one spread further away.
+type SpreadBook struct {
+	quotes map[string][]bookQuote
+}
+
+type bookQuote struct {
+	timestamp time.Time
+	bid       decimal.Decimal
+	ask       decimal.Decimal
+	bidQty    decimal.Decimal
+	askQty    decimal.Decimal
+}
+
+func NewFixedSlippage(bps float64) *FixedSlippage {
+	return &FixedSlippage{Bps: decimal.NewFromFloat(bps)}
+}
+
+func (f *FixedSlippage) FillPrice(symbol string, side model.OrderSide, qty decimal.Decimal, price decimal.Decimal,
+	timestamp time.Time) decimal.Decimal {
+	return slip(side, price, f.Bps)
+}
+
+func NewVolumeImpact(bps float64, referenceNotional float64) *VolumeImpact {
+	return &VolumeImpact{Bps: decimal.NewFromFloat(bps), ReferenceNotional: decimal.NewFromFloat(referenceNotional)}
+}
+
+func (v *VolumeImpact) FillPrice(symbol string, side model.OrderSide, qty decimal.Decimal, price decimal.Decimal,
+	timestamp time.Time) decimal.Decimal {
+	if !v.ReferenceNotional.GreaterThan(decimal.Zero) {
+		return price
+	}
+
+	impact := v.Bps.Mul(qty.Mul(price)).Div(v.ReferenceNotional)
+
+	return slip(side, price, impact)
+}
+
+func NewSpreadBook() *SpreadBook {
+	return &SpreadBook{quotes: make(map[string][]bookQuote)}
+}
+
+// LoadSpreadBook reads a CSV file with a header and the columns timestamp, symbol, bid, ask and optionally bid_qty and
+// ask_qty. Timestamps are either RFC3339 or unix milliseconds.
+func LoadSpreadBook(path string) (*SpreadBook, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseSpreadBook(file)
+}
+
+func ParseSpreadBook(reader io.Reader) (*SpreadBook, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid top of book file: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for idx, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+
+	for _, required := range []string{"timestamp", "symbol", "bid", "ask"} {
+		if _, exists := columns[required]; !exists {
+			return nil, errors.New("top of book file is missing column " + required)
+		}
+	}
+
+	book := NewSpreadBook()
+	line := 1
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("invalid top of book file: %w", err)
+		}
+
+		timestamp, err := parseBookTimestamp(record[columns["timestamp"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		quote := bookQuote{timestamp: timestamp}
+
+		if quote.bid, err = decimal.NewFromString(record[columns["bid"]]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if quote.ask, err = decimal.NewFromString(record[columns["ask"]]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if idx, exists := columns["bid_qty"]; exists && idx < len(record) && record[idx] != "" {
+			if quote.bidQty, err = decimal.NewFromString(record[idx]); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		if idx, exists := columns["ask_qty"]; exists && idx < len(record) && record[idx] != "" {
+			if quote.askQty, err = decimal.NewFromString(record[idx]); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		book.Add(record[columns["symbol"]], quote.timestamp, quote.bid, quote.ask, quote.bidQty, quote.askQty)
+	}
+
+	return book, nil
+}
+
+func (b *SpreadBook) Add(symbol string, timestamp time.Time, bid, ask, bidQty, askQty decimal.Decimal) {
+	quotes := b.quotes[symbol]
+
+	idx := sort.Search(len(quotes), func(i int) bool {
+		return quotes[i].timestamp.After(timestamp)
+	})
+
+	quotes = append(quotes, bookQuote{})
+	copy(quotes[idx+1:], quotes[idx:])
+	quotes[idx] = bookQuote{timestamp: timestamp, bid: bid, ask: ask, bidQty: bidQty, askQty: askQty}
+
+	b.quotes[symbol] = quotes
+}
+
+func (b *SpreadBook) FillPrice(symbol string, side model.OrderSide, qty decimal.Decimal, price decimal.Decimal,
+	timestamp time.Time) decimal.Decimal {
+	quote, exists := b.quoteAt(symbol, timestamp)
+	if !exists {
+		return price
+	}
+
+	spread := quote.ask.Sub(quote.bid)
+	top, topQty, worse := quote.ask, quote.askQty, quote.ask.Add(spread)
+	if side == model.SELL {
+		top, topQty, worse = quote.bid, quote.bidQty, quote.bid.Sub(spread)
+	}
+
+	if topQty.IsZero() || !qty.GreaterThan(topQty) {
+		return top
+	}
+
+	return top.Mul(topQty).Add(worse.Mul(qty.Sub(topQty))).Div(qty)
+}
+
+func (b *SpreadBook) quoteAt(symbol string, timestamp time.Time) (bookQuote, bool) {
+	quotes := b.quotes[symbol]
+
+	idx := sort.Search(len(quotes), func(i int) bool {
+		return quotes[i].timestamp.After(timestamp)
+	})
+
+	if idx == 0 {
+		return bookQuote{}, false
+	}
+
+	return quotes[idx-1], true
+}
+
+func parseBookTimestamp(value string) (time.Time, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, millis*int64(time.Millisecond)), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func slip(side model.OrderSide, price decimal.Decimal, bps decimal.Decimal) decimal.Decimal {
+	factor := bps.Div(bpsDivisor)
+	if side == model.SELL {
+		return price.Mul(decimal.NewFromInt(1).Sub(factor))
+	}
+	return price.Mul(decimal.NewFromInt(1).Add(factor))
+}
@@ -0,0 +1,103 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"scoing-trader/trader/model/market/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFixedSlippage(t *testing.T) {
+	slippage := NewFixedSlippage(10)
+	price := decimal.NewFromInt(1000)
+
+	if buy := slippage.FillPrice("BTCUSDT", model.BUY, decimal.NewFromInt(1), price, time.Time{}); !buy.Equal(decimal.NewFromInt(1001)) {
+		t.Error(fmt.Sprintf("Incorrect buy fill price got %s expected 1001", buy))
+	}
+
+	if sell := slippage.FillPrice("BTCUSDT", model.SELL, decimal.NewFromInt(1), price, time.Time{}); !sell.Equal(decimal.NewFromInt(999)) {
+		t.Error(fmt.Sprintf("Incorrect sell fill price got %s expected 999", sell))
+	}
+}
+
+func TestVolumeImpact(t *testing.T) {
+	impact := NewVolumeImpact(10, 1000)
+	price := decimal.NewFromInt(100)
+
+	if small := impact.FillPrice("BTCUSDT", model.BUY, decimal.NewFromInt(10), price, time.Time{}); !small.Equal(decimal.NewFromFloat(100.1)) {
+		t.Error(fmt.Sprintf("Incorrect fill price got %s expected 100.1", small))
+	}
+
+	if large := impact.FillPrice("BTCUSDT", model.SELL, decimal.NewFromInt(50), price, time.Time{}); !large.Equal(decimal.NewFromFloat(99.5)) {
+		t.Error(fmt.Sprintf("Incorrect fill price got %s expected 99.5", large))
+	}
+}
+
+func TestSpreadBook(t *testing.T) {
+	book, err := ParseSpreadBook(strings.NewReader(`timestamp,symbol,bid,ask,bid_qty,ask_qty
+1000,BTCUSDT,99,101,1,2
+2000-01-01T00:00:10Z,BTCUSDT,98,102,,
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	price := decimal.NewFromInt(100)
+	first := time.Unix(2, 0)
+
+	if fill := book.FillPrice("BTCUSDT", model.BUY, decimal.NewFromInt(1), price, time.Unix(0, 0)); !fill.Equal(price) {
+		t.Error(fmt.Sprintf("Expected reference price before first quote got %s", fill))
+	}
+
+	if fill := book.FillPrice("ETHUSDT", model.BUY, decimal.NewFromInt(1), price, first); !fill.Equal(price) {
+		t.Error(fmt.Sprintf("Expected reference price for unknown symbol got %s", fill))
+	}
+
+	if fill := book.FillPrice("BTCUSDT", model.BUY, decimal.NewFromInt(2), price, first); !fill.Equal(decimal.NewFromInt(101)) {
+		t.Error(fmt.Sprintf("Incorrect buy fill price got %s expected 101", fill))
+	}
+
+	if fill := book.FillPrice("BTCUSDT", model.SELL, decimal.NewFromInt(2), price, first); !fill.Equal(decimal.NewFromInt(98)) {
+		t.Error(fmt.Sprintf("Incorrect depth sell fill price got %s expected 98", fill))
+	}
+
+	later := time.Date(2000, 1, 1, 0, 1, 0, 0, time.UTC)
+	if fill := book.FillPrice("BTCUSDT", model.SELL, decimal.NewFromInt(5), price, later); !fill.Equal(decimal.NewFromInt(98)) {
+		t.Error(fmt.Sprintf("Incorrect sell fill price got %s expected 98", fill))
+	}
+}
+
+func TestMarketSlippage(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.NewFromFloat(0.001))
+	market.Deposit("USDT", decimal.NewFromInt(1000))
+	market.SetFillPriceModel(NewFixedSlippage(100))
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(100), time.Unix(1, 0))
+
+	err := market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.BUY, Quantity: decimal.NewFromInt(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fill := market.OrderHistory()[0].Fills[0].Price; !fill.Equal(decimal.NewFromInt(101)) {
+		t.Error(fmt.Sprintf("Incorrect market buy fill price got %s expected 101", fill))
+	}
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.SELL, Type: model.LIMIT,
+		Quantity: decimal.NewFromInt(5), Price: decimal.NewFromFloat(99.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fill := market.OrderHistory()[1].Fills[0].Price; !fill.Equal(decimal.NewFromFloat(99.5)) {
+		t.Error(fmt.Sprintf("Taker limit fill not capped at limit price got %s expected 99.5", fill))
+	}
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.BUY, Quantity: decimal.NewFromFloat(9.85)})
+	if !errors.Is(err, model.ErrInsufficientBalance) {
+		t.Error("Expected slipped order to exceed balance got ", err)
+	}
+}
@@ -35,3 +35,12 @@ type Trade struct {
 	IsMaker         bool
 	IsBestMatch     bool
 }
+
+// CommissionRate converts the commission expressed in basis points by AccountInformation to a fee rate.
+func CommissionRate(commission int64) decimal.Decimal {
+	return decimal.New(commission, -4)
+}
+
+func CommissionBps(rate decimal.Decimal) int64 {
+	return rate.Shift(4).Round(0).IntPart()
+}
@@ -38,7 +38,22 @@ const (
 type OrderResponseStatus string
 
 const (
-	NEW       OrderResponseStatus = "NEW"
-	FILLED    OrderResponseStatus = "FILLED"
-	CANCELLED OrderResponseStatus = "CANCELLED"
+	NEW              OrderResponseStatus = "NEW"
+	PARTIALLY_FILLED OrderResponseStatus = "PARTIALLY_FILLED"
+	FILLED           OrderResponseStatus = "FILLED"
+	CANCELLED        OrderResponseStatus = "CANCELLED"
+	REJECTED         OrderResponseStatus = "REJECTED"
+	EXPIRED          OrderResponseStatus = "EXPIRED"
 )
+
+func (t OrderType) HasLimitPrice() bool {
+	return t == LIMIT || t == STOP_LOSS_LIMIT || t == TAKE_PROFIT_LIMIT || t == LIMIT_MAKER
+}
+
+func (t OrderType) HasStopPrice() bool {
+	return t == STOP_LOSS || t == STOP_LOSS_LIMIT || t == TAKE_PROFIT || t == TAKE_PROFIT_LIMIT
+}
+
+func (s OrderResponseStatus) IsOpen() bool {
+	return s == NEW || s == PARTIALLY_FILLED
+}
@@ -0,0 +1,13 @@
+package model
+
+import "errors"
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrUnknownOrder        = errors.New("unknown order")
+	ErrInvalidSymbol       = errors.New("invalid symbol")
+	ErrFilterFailure       = errors.New("order violates symbol filters")
+	ErrRateLimited         = errors.New("rate limit exceeded")
+	ErrTimestampOutOfSync  = errors.New("timestamp outside of receive window")
+	ErrInvalidCredentials  = errors.New("invalid api credentials")
+)
@@ -1,6 +1,9 @@
 package model
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
 
 type Market interface {
 	NewOrder(order OrderRequest) error
@@ -10,8 +13,9 @@ type Market interface {
 	AccountInformation() AccountInformation
 	Balance(asset string) (Balance, error)
 	Trades() []*Trade
-	UpdateInformation()
+	UpdateInformation() error
 	CoinValue(asset string) (decimal.Decimal, error)
 	Deposit(asset string, qty decimal.Decimal)
-	UpdateCoinValue(asset string, value decimal.Decimal)
+	UpdateCoinValue(asset string, value decimal.Decimal, timestamp time.Time)
+	Symbols() *SymbolRegistry
 }
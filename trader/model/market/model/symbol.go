@@ -0,0 +1,190 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DefaultQuoteAsset = "USDT"
+
+var knownQuoteAssets = []string{"USDT", "BUSD", "USDC", "TUSD", "PAX", "DAI", "BTC", "ETH", "BNB", "EUR", "TRY"}
+
+type Symbol struct {
+	Symbol      string
+	BaseAsset   string
+	QuoteAsset  string
+	MinQty      decimal.Decimal
+	MinNotional decimal.Decimal
+	StepSize    decimal.Decimal
+	TickSize    decimal.Decimal
+}
+
+type SymbolRegistry struct {
+	mutex   sync.RWMutex
+	symbols map[string]Symbol
+}
+
+type exchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+		Filters    []struct {
+			FilterType  string          `json:"filterType"`
+			MinQty      decimal.Decimal `json:"minQty"`
+			StepSize    decimal.Decimal `json:"stepSize"`
+			TickSize    decimal.Decimal `json:"tickSize"`
+			MinNotional decimal.Decimal `json:"minNotional"`
+		} `json:"filters"`
+	} `json:"symbols"`
+}
+
+func NewSymbolRegistry() *SymbolRegistry {
+	return &SymbolRegistry{symbols: make(map[string]Symbol)}
+}
+
+func LoadSymbolRegistry(path string) (*SymbolRegistry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseExchangeInfo(file)
+}
+
+func ParseExchangeInfo(reader io.Reader) (*SymbolRegistry, error) {
+	var info exchangeInfo
+
+	if err := json.NewDecoder(reader).Decode(&info); err != nil {
+		return nil, fmt.Errorf("invalid exchange info: %w", err)
+	}
+
+	registry := NewSymbolRegistry()
+
+	for _, entry := range info.Symbols {
+		symbol := Symbol{
+			Symbol:     entry.Symbol,
+			BaseAsset:  entry.BaseAsset,
+			QuoteAsset: entry.QuoteAsset,
+		}
+
+		for _, filter := range entry.Filters {
+			switch filter.FilterType {
+			case "PRICE_FILTER":
+				symbol.TickSize = filter.TickSize
+			case "LOT_SIZE":
+				symbol.MinQty = filter.MinQty
+				symbol.StepSize = filter.StepSize
+			case "MIN_NOTIONAL", "NOTIONAL":
+				symbol.MinNotional = filter.MinNotional
+			}
+		}
+
+		registry.Register(symbol)
+	}
+
+	return registry, nil
+}
+
+func (r *SymbolRegistry) Register(symbol Symbol) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.symbols[symbol.Symbol] = symbol
+}
+
+// Lookup finds a registered symbol, failing with ErrInvalidSymbol for any other. Orders sent to an exchange need the
+// filters it published.
+func (r *SymbolRegistry) Lookup(symbol string) (Symbol, error) {
+	r.mutex.RLock()
+	registered, exists := r.symbols[symbol]
+	r.mutex.RUnlock()
+
+	if !exists {
+		return Symbol{}, fmt.Errorf("%w: %s", ErrInvalidSymbol, symbol)
+	}
+
+	return registered, nil
+}
+
+// Get finds a symbol like Lookup, guessing the assets of an unregistered one from the quote asset it ends in, with no
+// filters. Simulations use it to trade the coins of the predictions without exchange info.
+func (r *SymbolRegistry) Get(symbol string) (Symbol, error) {
+	if registered, err := r.Lookup(symbol); err == nil {
+		return registered, nil
+	}
+
+	quote := ""
+	for _, knownQuote := range knownQuoteAssets {
+		if strings.HasSuffix(symbol, knownQuote) && len(knownQuote) > len(quote) && len(symbol) > len(knownQuote) {
+			quote = knownQuote
+		}
+	}
+
+	if quote == "" {
+		return Symbol{}, fmt.Errorf("%w: %s", ErrInvalidSymbol, symbol)
+	}
+
+	return Symbol{
+		Symbol:     symbol,
+		BaseAsset:  strings.TrimSuffix(symbol, quote),
+		QuoteAsset: quote,
+	}, nil
+}
+
+// Pair finds the symbol trading base against quote, e.g. BNB against USDT to value commissions. Like Get, it guesses an
+// unregistered symbol from the assets when the guess splits back into them.
+func (r *SymbolRegistry) Pair(base string, quote string) (Symbol, error) {
+	r.mutex.RLock()
+	for _, symbol := range r.symbols {
+		if symbol.BaseAsset == base && symbol.QuoteAsset == quote {
+			r.mutex.RUnlock()
+			return symbol, nil
+		}
+	}
+	r.mutex.RUnlock()
+
+	symbol, err := r.Get(base + quote)
+	if err != nil || symbol.BaseAsset != base || symbol.QuoteAsset != quote {
+		return Symbol{}, fmt.Errorf("%w: no symbol trades %s against %s", ErrInvalidSymbol, base, quote)
+	}
+
+	return symbol, nil
+}
+
+func (r *SymbolRegistry) Symbols() []Symbol {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	symbols := make([]Symbol, 0, len(r.symbols))
+	for _, symbol := range r.symbols {
+		symbols = append(symbols, symbol)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Symbol < symbols[j].Symbol
+	})
+
+	return symbols
+}
+
+func (s Symbol) RoundQty(qty decimal.Decimal) decimal.Decimal {
+	if s.StepSize.IsZero() {
+		return qty
+	}
+	return qty.Div(s.StepSize).Floor().Mul(s.StepSize)
+}
+
+func (s Symbol) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	if s.TickSize.IsZero() {
+		return price
+	}
+	return price.Div(s.TickSize).Floor().Mul(s.TickSize)
+}
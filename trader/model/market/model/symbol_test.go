@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+func TestSymbolInference(t *testing.T) {
+	registry := NewSymbolRegistry()
+
+	expected := map[string][]string{
+		"BTCUSDT":   {"BTC", "USDT"},
+		"DOGEUSDT":  {"DOGE", "USDT"},
+		"BTCBUSD":   {"BTC", "BUSD"},
+		"1INCHUSDT": {"1INCH", "USDT"},
+		"ETHBTC":    {"ETH", "BTC"},
+	}
+
+	for name, assets := range expected {
+		symbol, err := registry.Get(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if symbol.BaseAsset != assets[0] || symbol.QuoteAsset != assets[1] {
+			t.Error(fmt.Sprintf("Invalid split of %s Expected: %s/%s Got: %s/%s", name, assets[0], assets[1],
+				symbol.BaseAsset, symbol.QuoteAsset))
+		}
+	}
+
+	if _, err := registry.Get("UNKNOWN"); !errors.Is(err, ErrInvalidSymbol) {
+		t.Error("Expected invalid symbol error got ", err)
+	}
+
+	if _, err := registry.Lookup("BTCUSDT"); !errors.Is(err, ErrInvalidSymbol) {
+		t.Error("Expected the strict lookup not to guess got ", err)
+	}
+}
+
+func TestParseExchangeInfo(t *testing.T) {
+	registry, err := ParseExchangeInfo(strings.NewReader(`{"symbols":[{"symbol":"XBTUSD","baseAsset":"XBT",
+		"quoteAsset":"USD","filters":[{"filterType":"PRICE_FILTER","tickSize":"0.50"},
+		{"filterType":"LOT_SIZE","minQty":"0.001","stepSize":"0.001"},
+		{"filterType":"MIN_NOTIONAL","minNotional":"5"}]}]}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	symbol, err := registry.Get("XBTUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if symbol.BaseAsset != "XBT" || symbol.QuoteAsset != "USD" || !symbol.MinNotional.Equal(decimal.NewFromInt(5)) {
+		t.Error("Incorrect symbol parsed ", symbol)
+	}
+
+	if qty := symbol.RoundQty(decimal.NewFromFloat(1.23456)); !qty.Equal(decimal.NewFromFloat(1.234)) {
+		t.Error(fmt.Sprintf("Rounding quantity failed Expected: 1.234 Got: %s", qty))
+	}
+
+	if price := symbol.RoundPrice(decimal.NewFromFloat(100.74)); !price.Equal(decimal.NewFromFloat(100.5)) {
+		t.Error(fmt.Sprintf("Rounding price failed Expected: 100.5 Got: %s", price))
+	}
+
+	if pair, err := registry.Pair("XBT", "USD"); err != nil || pair.Symbol != "XBTUSD" {
+		t.Error("Expected the registered pair got ", pair, err)
+	}
+
+	if pair, err := registry.Pair("BNB", "USDT"); err != nil || pair.Symbol != "BNBUSDT" {
+		t.Error("Expected a guessed pair got ", pair, err)
+	}
+
+	if _, err := registry.Pair("BNB", "XYZ"); !errors.Is(err, ErrInvalidSymbol) {
+		t.Error("Expected an unknown pair to fail got ", err)
+	}
+}
@@ -6,47 +6,170 @@ import (
 	"github.com/shopspring/decimal"
 	"math/rand"
 	"scoing-trader/trader/model/market/model"
+	"strconv"
+	"time"
 )
 
 type SimulatedMarket struct {
-	accountInfo  model.AccountInformation
-	orderList    []*model.OrderResponseFull
-	tradeList    []*model.Trade
-	coinValues   map[string]decimal.Decimal
-	unfilledRate float64
-	fee          decimal.Decimal
+	accountInfo        model.AccountInformation
+	orderList          []*model.OrderResponseFull
+	tradeList          []*model.Trade
+	coinValues         map[string]decimal.Decimal
+	restingOrders      []*restingOrder
+	symbols            *model.SymbolRegistry
+	unfilledRate       float64
+	makerFee           decimal.Decimal
+	takerFee           decimal.Decimal
+	commissionAsset    string
+	commissionDiscount decimal.Decimal
+	fillLimit          decimal.Decimal
+	fillPriceModel     FillPriceModel
+	lifecycle          OrderLifecycle
+	now                time.Time
+	nextOrderId        int64
+	nextTradeId        int64
+	random             *rand.Rand
+}
+
+type OrderLifecycle struct {
+	MinLatency time.Duration
+	MaxLatency time.Duration
+	Expiry     time.Duration
+}
+
+type restingOrder struct {
+	request   model.OrderRequest
+	response  *model.OrderResponseFull
+	locked    decimal.Decimal
+	triggered bool
+	fillAt    time.Time
+	expireAt  time.Time
 }
 
 func NewSimulatedMarket(unfilledRate float64, fee decimal.Decimal) *SimulatedMarket {
 	return &SimulatedMarket{
-		accountInfo:  model.AccountInformation{},
-		orderList:    make([]*model.OrderResponseFull, 0),
-		tradeList:    make([]*model.Trade, 0),
-		coinValues:   make(map[string]decimal.Decimal),
-		unfilledRate: unfilledRate,
-		fee:          fee,
+		accountInfo: model.AccountInformation{
+			MakerCommission: model.CommissionBps(fee),
+			TakerCommission: model.CommissionBps(fee),
+		},
+		orderList:     make([]*model.OrderResponseFull, 0),
+		tradeList:     make([]*model.Trade, 0),
+		coinValues:    make(map[string]decimal.Decimal),
+		restingOrders: make([]*restingOrder, 0),
+		symbols:       model.NewSymbolRegistry(),
+		unfilledRate:  unfilledRate,
+		makerFee:      fee,
+		takerFee:      fee,
+		fillLimit:     decimal.Zero,
+		lifecycle:     OrderLifecycle{},
+		nextOrderId:   1,
+		nextTradeId:   1,
+		random:        rand.New(rand.NewSource(1)),
 	}
 }
 
+func (s *SimulatedMarket) SetFillLimit(qty decimal.Decimal) {
+	s.fillLimit = qty
+}
+
+func (s *SimulatedMarket) SetCommissionRates(maker decimal.Decimal, taker decimal.Decimal) {
+	s.makerFee = maker
+	s.takerFee = taker
+	s.accountInfo.MakerCommission = model.CommissionBps(maker)
+	s.accountInfo.TakerCommission = model.CommissionBps(taker)
+}
+
+// SetCommissionAsset makes fills pay their commission in asset (e.g. BNB) at its current price with the given
+// discount, as long as there is enough free balance of it. Otherwise the commission is charged in the quote asset.
+func (s *SimulatedMarket) SetCommissionAsset(asset string, discount decimal.Decimal) {
+	s.commissionAsset = asset
+	s.commissionDiscount = discount
+}
+
+func (s *SimulatedMarket) SetFillPriceModel(fillPriceModel FillPriceModel) {
+	s.fillPriceModel = fillPriceModel
+}
+
+func (s *SimulatedMarket) SetOrderLifecycle(lifecycle OrderLifecycle) {
+	s.lifecycle = lifecycle
+}
+
+// SetRandom sets the source of unfilled market orders and order latencies, one of its own seeded with 1 by
+// default so that runs repeat.
+func (s *SimulatedMarket) SetRandom(random *rand.Rand) {
+	s.random = random
+}
+
+func (s *SimulatedMarket) SetSymbolRegistry(symbols *model.SymbolRegistry) {
+	s.symbols = symbols
+}
+
+func (s *SimulatedMarket) Symbols() *model.SymbolRegistry {
+	return s.symbols
+}
+
 func (s *SimulatedMarket) NewOrder(order model.OrderRequest) error {
-	if len(s.OpenOrders(order.Symbol)) > 0 {
-		return errors.New("Order already open for symbol: " + order.Symbol)
+	symbol, err := s.symbols.Get(order.Symbol)
+	if err != nil {
+		return err
 	}
 
-	status := model.NEW
-	var execQty decimal.Decimal
-	var cumQty decimal.Decimal
-
 	assetBalanceIdx, asset, quoteBalanceIdx, quote := s.getAssetQuoteIdx(order.Symbol)
+	currentPrice := s.coinValues[order.Symbol]
 
-	if order.Price.IsZero() {
-		order.Price = s.coinValues[order.Symbol]
+	if order.Type == "" {
+		order.Type = model.MARKET
+	}
+
+	if order.TimeInForce == "" {
+		order.TimeInForce = model.GTC
+	}
+
+	if order.Type == model.MARKET && order.Price.IsZero() {
+		order.Price = currentPrice
+	}
+
+	if order.Type.HasLimitPrice() && !order.Price.GreaterThan(decimal.Zero) {
+		return errors.New(fmt.Sprintf("%s order requires a positive price", order.Type))
+	}
+
+	if order.Type.HasStopPrice() && !order.StopPrice.GreaterThan(decimal.Zero) {
+		return errors.New(fmt.Sprintf("%s order requires a positive stop price", order.Type))
+	}
+
+	order.Quantity = symbol.RoundQty(order.Quantity)
+	if order.Type.HasLimitPrice() {
+		order.Price = symbol.RoundPrice(order.Price)
+	}
+	if order.Type.HasStopPrice() {
+		order.StopPrice = symbol.RoundPrice(order.StopPrice)
+	}
+
+	if !order.Quantity.GreaterThan(decimal.Zero) || order.Quantity.LessThan(symbol.MinQty) {
+		return fmt.Errorf("%w: quantity %s below minimum %s for %s", model.ErrFilterFailure, order.Quantity,
+			decimal.Max(symbol.MinQty, symbol.StepSize), order.Symbol)
+	}
+
+	if notional := order.Quantity.Mul(order.Price); notional.GreaterThan(decimal.Zero) && notional.LessThan(symbol.MinNotional) {
+		return fmt.Errorf("%w: notional %s below minimum %s for %s", model.ErrFilterFailure, notional,
+			symbol.MinNotional, order.Symbol)
+	}
+
+	if order.Type == model.LIMIT_MAKER && currentPrice.GreaterThan(decimal.Zero) &&
+		limitReached(order.Side, order.Price, currentPrice) {
+		return errors.New("LIMIT_MAKER order would immediately match and take")
 	}
 
 	if quoteBalanceIdx == -1 {
 		return errors.New(fmt.Sprintf("No balance of asset: %s ", quote))
 	}
 
+	if s.commissionAsset != "" && s.commissionAsset != quote {
+		if _, err := s.commissionPrice(quote); err != nil {
+			return err
+		}
+	}
+
 	if assetBalanceIdx == -1 {
 		s.accountInfo.Balances = append(s.accountInfo.Balances, model.Balance{
 			Asset:  asset,
@@ -57,78 +180,84 @@ func (s *SimulatedMarket) NewOrder(order model.OrderRequest) error {
 		assetBalanceIdx = len(s.accountInfo.Balances) - 1
 	}
 
-	if order.Side == model.SELL && s.accountInfo.Balances[assetBalanceIdx].Free.LessThan(order.Quantity) {
-		return errors.New(fmt.Sprintf("asset balance for %s insufficent (%s)", asset, order.Quantity))
-	}
+	immediate := order.Type == model.MARKET && s.random.Float64() >= s.unfilledRate
 
-	if order.Side == model.BUY && s.accountInfo.Balances[quoteBalanceIdx].Free.LessThan(order.Quantity.Mul(order.Price).Mul(decimal.NewFromInt(1).Add(s.fee))) {
-		return errors.New(fmt.Sprintf("balance for %s (%s) doesn't cover transaction (%s)", quote,
-			s.accountInfo.Balances[quoteBalanceIdx].Free, order.Quantity.Mul(order.Price).Mul(decimal.NewFromInt(1).Add(s.fee))))
+	reservePrice := order.Price
+	if !order.Type.HasLimitPrice() && order.Type.HasStopPrice() {
+		reservePrice = order.StopPrice
+	} else if immediate {
+		reservePrice = s.takerPrice(order, order.Quantity, order.Price)
 	}
 
-	if rand.Float64() >= s.unfilledRate {
-		status = model.FILLED
-		execQty = order.Quantity
-		cumQty = order.QuoteOrderQty
+	var locked decimal.Decimal
 
-		if order.Side == model.BUY {
-			s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Sub(
-				order.Quantity.Mul(order.Price).Mul(decimal.NewFromInt(1).Add(s.fee)))
-			s.accountInfo.Balances[assetBalanceIdx].Free = s.accountInfo.Balances[assetBalanceIdx].Free.Add(order.Quantity)
-		} else if order.Side == model.SELL {
-			s.accountInfo.Balances[assetBalanceIdx].Free = s.accountInfo.Balances[assetBalanceIdx].Free.Sub(order.Quantity)
-			s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Add(
-				order.Quantity.Mul(order.Price).Mul(decimal.NewFromInt(1).Sub(s.fee)))
+	if order.Side == model.SELL {
+		if s.accountInfo.Balances[assetBalanceIdx].Free.LessThan(order.Quantity) {
+			return fmt.Errorf("%w: asset balance for %s insufficent (%s)", model.ErrInsufficientBalance, asset, order.Quantity)
 		}
 
-		//TODO: discount using BNB as commission asset
-		trade := model.Trade{
-			Symbol:          order.Symbol,
-			Id:              0,
-			OrderId:         0,
-			OrderListId:     0,
-			Price:           order.Price,
-			Qty:             order.Quantity,
-			Commission:      order.Price.Mul(order.Quantity).Mul(s.fee),
-			CommissionAsset: "USDT",
-			Time:            0,
-			IsBuyer:         order.Side == model.BUY,
-			IsMaker:         false,
-			IsBestMatch:     true,
-		}
+		locked = order.Quantity
+		s.accountInfo.Balances[assetBalanceIdx].Free = s.accountInfo.Balances[assetBalanceIdx].Free.Sub(locked)
+		s.accountInfo.Balances[assetBalanceIdx].Locked = s.accountInfo.Balances[assetBalanceIdx].Locked.Add(locked)
+	} else if order.Side == model.BUY {
+		locked = order.Quantity.Mul(reservePrice).Mul(decimal.NewFromInt(1).Add(decimal.Max(s.makerFee, s.takerFee)))
 
-		s.tradeList = append(s.tradeList, &trade)
-	} else {
-		if order.Side == model.BUY {
-			s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Sub(
-				order.Quantity.Mul(order.Price).Mul(decimal.NewFromInt(1).Add(s.fee)))
-			s.accountInfo.Balances[quoteBalanceIdx].Locked = s.accountInfo.Balances[quoteBalanceIdx].Locked.Add(
-				order.Quantity.Mul(order.Price).Mul(decimal.NewFromInt(1).Add(s.fee)))
-		} else if order.Side == model.SELL {
-			s.accountInfo.Balances[assetBalanceIdx].Free = s.accountInfo.Balances[assetBalanceIdx].Free.Sub(order.Quantity)
-			s.accountInfo.Balances[assetBalanceIdx].Locked = s.accountInfo.Balances[assetBalanceIdx].Locked.Add(order.Quantity)
+		if s.accountInfo.Balances[quoteBalanceIdx].Free.LessThan(locked) {
+			return fmt.Errorf("%w: balance for %s (%s) doesn't cover transaction (%s)", model.ErrInsufficientBalance,
+				quote, s.accountInfo.Balances[quoteBalanceIdx].Free, locked)
 		}
+
+		s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Sub(locked)
+		s.accountInfo.Balances[quoteBalanceIdx].Locked = s.accountInfo.Balances[quoteBalanceIdx].Locked.Add(locked)
+	}
+
+	if order.ClientOrderId == "" {
+		order.ClientOrderId = strconv.FormatInt(s.nextOrderId, 10)
 	}
 
 	orderResp := model.OrderResponseFull{
 		Symbol:              order.Symbol,
-		OrderId:             -1,
+		OrderId:             s.nextOrderId,
 		OrderListId:         -1,
 		ClientOrderId:       order.ClientOrderId,
-		TransactionTime:     -1,
+		TransactionTime:     toMillis(s.now),
 		Price:               order.Price,
 		OrigQty:             order.Quantity,
-		ExecutedQty:         execQty,
-		CummulativeQuoteQty: cumQty,
-		Status:              status,
+		ExecutedQty:         decimal.Zero,
+		CummulativeQuoteQty: decimal.Zero,
+		Status:              model.NEW,
 		TimeInForce:         order.TimeInForce,
 		Type:                order.Type,
 		Side:                order.Side,
 		Fills:               nil,
 	}
 
+	s.nextOrderId++
 	s.orderList = append(s.orderList, &orderResp)
 
+	resting := &restingOrder{
+		request:  order,
+		response: &orderResp,
+		locked:   locked,
+	}
+
+	if order.Type == model.MARKET {
+		if immediate {
+			s.fill(resting, order.Quantity, reservePrice, false)
+		} else {
+			s.delay(resting)
+			s.restingOrders = append(s.restingOrders, resting)
+		}
+		return nil
+	}
+
+	s.restingOrders = append(s.restingOrders, resting)
+
+	if currentPrice.GreaterThan(decimal.Zero) {
+		s.match(resting, currentPrice, false)
+		s.pruneRestingOrders()
+	}
+
 	return nil
 }
 
@@ -136,7 +265,7 @@ func (s *SimulatedMarket) OpenOrders(symbol string) []*model.OrderResponseFull {
 	var openOrders []*model.OrderResponseFull
 
 	for _, order := range s.orderList {
-		if order.Status == model.NEW && order.Symbol == symbol {
+		if order.Status.IsOpen() && order.Symbol == symbol {
 			openOrders = append(openOrders, order)
 		}
 	}
@@ -149,22 +278,14 @@ func (s *SimulatedMarket) OrderHistory() []*model.OrderResponseFull {
 }
 
 func (s *SimulatedMarket) CancelOrder(orderId string) error {
-	for _, order := range s.orderList {
-		if order.ClientOrderId == orderId {
-			order.Status = model.CANCELLED
-
-			_, _, quoteBalanceIdx, quote := s.getAssetQuoteIdx(order.Symbol)
-			if quoteBalanceIdx == -1 {
-				return errors.New(fmt.Sprintf("Could not find balances for quote:%s", quote))
-			}
-
-			s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Add(s.accountInfo.Balances[quoteBalanceIdx].Locked)
-			s.accountInfo.Balances[quoteBalanceIdx].Locked = decimal.Zero
-
+	for _, resting := range s.restingOrders {
+		if resting.response.ClientOrderId == orderId {
+			s.close(resting, model.CANCELLED)
+			s.pruneRestingOrders()
 			return nil
 		}
 	}
-	return errors.New("unknown order id")
+	return model.ErrUnknownOrder
 }
 
 func (s *SimulatedMarket) AccountInformation() model.AccountInformation {
@@ -184,7 +305,9 @@ func (s *SimulatedMarket) Trades() []*model.Trade {
 	return s.tradeList
 }
 
-func (s *SimulatedMarket) UpdateInformation() {}
+func (s *SimulatedMarket) UpdateInformation() error {
+	return nil
+}
 
 func (s *SimulatedMarket) CoinValue(asset string) (decimal.Decimal, error) {
 	coinVal, exists := s.coinValues[asset]
@@ -215,14 +338,268 @@ func (s *SimulatedMarket) Deposit(asset string, qty decimal.Decimal) {
 	}
 }
 
-func (s *SimulatedMarket) UpdateCoinValue(asset string, value decimal.Decimal) {
+func (s *SimulatedMarket) UpdateCoinValue(asset string, value decimal.Decimal, timestamp time.Time) {
 	s.coinValues[asset] = value
+
+	if timestamp.After(s.now) {
+		s.now = timestamp
+	}
+
+	for _, resting := range s.restingOrders {
+		if resting.request.Symbol != asset {
+			continue
+		}
+
+		if resting.request.Type == model.MARKET {
+			if !resting.expireAt.IsZero() && !s.now.Before(resting.expireAt) && resting.fillAt.After(resting.expireAt) {
+				s.close(resting, model.EXPIRED)
+			} else if !s.now.Before(resting.fillAt) {
+				s.match(resting, value, false)
+			}
+		} else {
+			s.match(resting, value, true)
+		}
+	}
+
+	s.pruneRestingOrders()
+}
+
+func (s *SimulatedMarket) takerPrice(order model.OrderRequest, qty decimal.Decimal, price decimal.Decimal) decimal.Decimal {
+	if s.fillPriceModel == nil {
+		return price
+	}
+	return s.fillPriceModel.FillPrice(order.Symbol, order.Side, qty, price, s.now)
+}
+
+func (s *SimulatedMarket) delay(resting *restingOrder) {
+	latency := s.lifecycle.MinLatency
+	if s.lifecycle.MaxLatency > s.lifecycle.MinLatency {
+		latency += time.Duration(s.random.Int63n(int64(s.lifecycle.MaxLatency - s.lifecycle.MinLatency)))
+	}
+
+	resting.fillAt = s.now.Add(latency)
+
+	if s.lifecycle.Expiry > 0 {
+		resting.expireAt = s.now.Add(s.lifecycle.Expiry)
+	}
+}
+
+func (s *SimulatedMarket) match(resting *restingOrder, price decimal.Decimal, isMaker bool) {
+	order := resting.request
+
+	if order.Type.HasStopPrice() && !resting.triggered {
+		if !stopReached(order.Side, order.Type, order.StopPrice, price) {
+			return
+		}
+		resting.triggered = true
+	}
+
+	if order.Type.HasLimitPrice() {
+		if !limitReached(order.Side, order.Price, price) {
+			s.expireIfImmediate(resting)
+			return
+		}
+	} else {
+		isMaker = false
+	}
+
+	remaining := resting.response.OrigQty.Sub(resting.response.ExecutedQty)
+	fillQty := remaining
+
+	if s.fillLimit.GreaterThan(decimal.Zero) && fillQty.GreaterThan(s.fillLimit) {
+		fillQty = s.fillLimit
+	}
+
+	if order.TimeInForce == model.FOK && fillQty.LessThan(remaining) {
+		s.close(resting, model.EXPIRED)
+		return
+	}
+
+	fillPrice := order.Price
+	if !isMaker {
+		fillPrice = s.takerPrice(order, fillQty, price)
+		if order.Type.HasLimitPrice() && !limitReached(order.Side, order.Price, fillPrice) {
+			fillPrice = order.Price
+		}
+	}
+
+	if order.Side == model.BUY {
+		available := resting.locked.Add(s.freeBalance(s.quoteOf(order.Symbol)))
+		cost := fillQty.Mul(fillPrice).Mul(decimal.NewFromInt(1).Add(s.feeRate(isMaker)))
+
+		if cost.GreaterThan(available) {
+			s.close(resting, model.EXPIRED)
+			return
+		}
+	}
+
+	s.fill(resting, fillQty, fillPrice, isMaker)
+	s.expireIfImmediate(resting)
+}
+
+func (s *SimulatedMarket) fill(resting *restingOrder, qty decimal.Decimal, price decimal.Decimal, isMaker bool) {
+	assetBalanceIdx, _, quoteBalanceIdx, quote := s.getAssetQuoteIdx(resting.request.Symbol)
+	order := resting.response
+
+	commission, commissionAsset := s.chargeCommission(qty.Mul(price), quote, isMaker)
+
+	quoteCommission := decimal.Zero
+	if commissionAsset == quote {
+		quoteCommission = commission
+	}
+
+	if order.Side == model.BUY {
+		cost := qty.Mul(price).Add(quoteCommission)
+		fromLocked := decimal.Min(cost, resting.locked)
+
+		resting.locked = resting.locked.Sub(fromLocked)
+		s.accountInfo.Balances[quoteBalanceIdx].Locked = s.accountInfo.Balances[quoteBalanceIdx].Locked.Sub(fromLocked)
+		s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Sub(cost.Sub(fromLocked))
+		s.accountInfo.Balances[assetBalanceIdx].Free = s.accountInfo.Balances[assetBalanceIdx].Free.Add(qty)
+	} else if order.Side == model.SELL {
+		resting.locked = resting.locked.Sub(qty)
+		s.accountInfo.Balances[assetBalanceIdx].Locked = s.accountInfo.Balances[assetBalanceIdx].Locked.Sub(qty)
+		s.accountInfo.Balances[quoteBalanceIdx].Free = s.accountInfo.Balances[quoteBalanceIdx].Free.Add(
+			qty.Mul(price).Sub(quoteCommission))
+	}
+
+	order.ExecutedQty = order.ExecutedQty.Add(qty)
+	order.CummulativeQuoteQty = order.CummulativeQuoteQty.Add(qty.Mul(price))
+	order.Fills = append(order.Fills, model.Fill{
+		Price:           price,
+		Qty:             qty,
+		Commission:      commission,
+		CommissionAsset: commissionAsset,
+	})
+
+	trade := model.Trade{
+		Symbol:          order.Symbol,
+		Id:              s.nextTradeId,
+		OrderId:         order.OrderId,
+		OrderListId:     order.OrderListId,
+		Price:           price,
+		Qty:             qty,
+		Commission:      commission,
+		CommissionAsset: commissionAsset,
+		Time:            toMillis(s.now),
+		IsBuyer:         order.Side == model.BUY,
+		IsMaker:         isMaker,
+		IsBestMatch:     true,
+	}
+
+	s.nextTradeId++
+	s.tradeList = append(s.tradeList, &trade)
+
+	if order.ExecutedQty.GreaterThanOrEqual(order.OrigQty) {
+		s.close(resting, model.FILLED)
+	} else {
+		order.Status = model.PARTIALLY_FILLED
+	}
+}
+
+func (s *SimulatedMarket) feeRate(isMaker bool) decimal.Decimal {
+	if isMaker {
+		return s.makerFee
+	}
+	return s.takerFee
+}
+
+func (s *SimulatedMarket) chargeCommission(notional decimal.Decimal, quote string, isMaker bool) (decimal.Decimal, string) {
+	commission := notional.Mul(s.feeRate(isMaker))
+
+	if s.commissionAsset == "" || s.commissionAsset == quote {
+		return commission, quote
+	}
+
+	// NewOrder checked the commission asset has a price, which stays known once seen.
+	assetPrice, err := s.commissionPrice(quote)
+	if err != nil {
+		return commission, quote
+	}
+
+	discounted := commission.Mul(decimal.NewFromInt(1).Sub(s.commissionDiscount)).Div(assetPrice)
+
+	for idx := range s.accountInfo.Balances {
+		if s.accountInfo.Balances[idx].Asset == s.commissionAsset {
+			if s.accountInfo.Balances[idx].Free.LessThan(discounted) {
+				break
+			}
+			s.accountInfo.Balances[idx].Free = s.accountInfo.Balances[idx].Free.Sub(discounted)
+			return discounted, s.commissionAsset
+		}
+	}
+
+	return commission, quote
+}
+
+// commissionPrice is the price of the commission asset in quote, failing when its symbol or price is unknown.
+func (s *SimulatedMarket) commissionPrice(quote string) (decimal.Decimal, error) {
+	pair, err := s.symbols.Pair(s.commissionAsset, quote)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	assetPrice := s.coinValues[pair.Symbol]
+	if !assetPrice.GreaterThan(decimal.Zero) {
+		return decimal.Zero, fmt.Errorf("%w: no price of %s to pay commissions in %s", model.ErrInvalidSymbol,
+			pair.Symbol, s.commissionAsset)
+	}
+
+	return assetPrice, nil
+}
+
+func (s *SimulatedMarket) close(resting *restingOrder, status model.OrderResponseStatus) {
+	assetBalanceIdx, _, quoteBalanceIdx, _ := s.getAssetQuoteIdx(resting.request.Symbol)
+
+	if resting.locked.GreaterThan(decimal.Zero) {
+		balanceIdx := quoteBalanceIdx
+		if resting.request.Side == model.SELL {
+			balanceIdx = assetBalanceIdx
+		}
+
+		s.accountInfo.Balances[balanceIdx].Locked = s.accountInfo.Balances[balanceIdx].Locked.Sub(resting.locked)
+		s.accountInfo.Balances[balanceIdx].Free = s.accountInfo.Balances[balanceIdx].Free.Add(resting.locked)
+		resting.locked = decimal.Zero
+	}
+
+	resting.response.Status = status
+}
+
+func (s *SimulatedMarket) expireIfImmediate(resting *restingOrder) {
+	if resting.request.TimeInForce != model.GTC && resting.response.Status.IsOpen() {
+		s.close(resting, model.EXPIRED)
+	}
+}
+
+func (s *SimulatedMarket) pruneRestingOrders() {
+	open := s.restingOrders[:0]
+
+	for _, resting := range s.restingOrders {
+		if resting.response.Status.IsOpen() {
+			open = append(open, resting)
+		}
+	}
+
+	s.restingOrders = open
+}
+
+func (s *SimulatedMarket) freeBalance(asset string) decimal.Decimal {
+	balance, err := s.Balance(asset)
+	if err != nil {
+		return decimal.Zero
+	}
+	return balance.Free
+}
+
+func (s *SimulatedMarket) quoteOf(symbol string) string {
+	_, _, _, quote := s.getAssetQuoteIdx(symbol)
+	return quote
 }
 
 func (s *SimulatedMarket) getAssetQuoteIdx(symbol string) (int, string, int, string) {
-	//TODO: generalize
-	asset := symbol[0:3]
-	quote := symbol[3:]
+	symbolInfo, _ := s.symbols.Get(symbol)
+	asset := symbolInfo.BaseAsset
+	quote := symbolInfo.QuoteAsset
 
 	assetBalanceIdx := -1
 	quoteBalanceIdx := -1
@@ -237,3 +614,26 @@ func (s *SimulatedMarket) getAssetQuoteIdx(symbol string) (int, string, int, str
 
 	return assetBalanceIdx, asset, quoteBalanceIdx, quote
 }
+
+func toMillis(timestamp time.Time) int64 {
+	if timestamp.IsZero() {
+		return 0
+	}
+	return timestamp.UnixNano() / int64(time.Millisecond)
+}
+
+func limitReached(side model.OrderSide, limit decimal.Decimal, price decimal.Decimal) bool {
+	if side == model.BUY {
+		return price.LessThanOrEqual(limit)
+	}
+	return price.GreaterThanOrEqual(limit)
+}
+
+func stopReached(side model.OrderSide, orderType model.OrderType, stop decimal.Decimal, price decimal.Decimal) bool {
+	isStopLoss := orderType == model.STOP_LOSS || orderType == model.STOP_LOSS_LIMIT
+
+	if (side == model.SELL) == isStopLoss {
+		return price.LessThanOrEqual(stop)
+	}
+	return price.GreaterThanOrEqual(stop)
+}
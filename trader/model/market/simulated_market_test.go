@@ -1,10 +1,12 @@
 package market
 
 import (
+	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"scoing-trader/trader/model/market/model"
 	"testing"
+	"time"
 )
 
 func TestMarketDeposit(t *testing.T) {
@@ -104,7 +106,7 @@ func TestMarketUnfilled(t *testing.T) {
 		t.Error("Order 1 failed")
 	}
 
-	if len(market.OpenOrders("BTCUSDT")) < 0 {
+	if len(market.OpenOrders("BTCUSDT")) != 1 {
 		t.Error("Order is not open ")
 	}
 
@@ -125,8 +127,8 @@ func TestMarketUnfilled(t *testing.T) {
 
 	err2 := market.NewOrder(order2)
 
-	if err2 == nil {
-		t.Error("Order 2 was accepted")
+	if err2 != nil {
+		t.Error("Order 2 was rejected while order 1 is open")
 	}
 
 	btcBalance, errBalBTC := market.Balance("BTC")
@@ -142,11 +144,11 @@ func TestMarketUnfilled(t *testing.T) {
 	if errBalUSDT != nil {
 		t.Error(usdtBalance)
 	} else {
-		if !usdtBalance.Free.Equal(decimal.NewFromFloat(899.9)) {
-			t.Error(fmt.Sprintf("Inavlid USDT balance. Expected 899.90 got %s", btcBalance.Free))
+		if !usdtBalance.Free.Equal(decimal.NewFromFloat(799.8)) {
+			t.Error(fmt.Sprintf("Inavlid USDT balance. Expected 799.80 got %s", usdtBalance.Free))
 		}
-		if !usdtBalance.Locked.Equal(decimal.NewFromFloat(100.1)) {
-			t.Error(fmt.Sprintf("Incorrect locked balance. Expected 100.10 got %s", usdtBalance.Locked))
+		if !usdtBalance.Locked.Equal(decimal.NewFromFloat(200.2)) {
+			t.Error(fmt.Sprintf("Incorrect locked balance. Expected 200.20 got %s", usdtBalance.Locked))
 		}
 	}
 
@@ -157,11 +159,419 @@ func TestMarketUnfilled(t *testing.T) {
 	} else {
 		usdtBalance, _ := market.Balance("USDT")
 
-		if !usdtBalance.Free.Equal(decimal.NewFromInt(1000)) {
-			t.Error(fmt.Sprintf("Inavlid USDT balance. Expected 1000.00 got %s", usdtBalance.Free))
+		if !usdtBalance.Free.Equal(decimal.NewFromFloat(899.9)) {
+			t.Error(fmt.Sprintf("Inavlid USDT balance. Expected 899.90 got %s", usdtBalance.Free))
 		}
-		if !usdtBalance.Locked.IsZero() {
-			t.Error(fmt.Sprintf("Incorrect locked balance. Expected 0.00 got %s", usdtBalance.Locked))
+		if !usdtBalance.Locked.Equal(decimal.NewFromFloat(100.1)) {
+			t.Error(fmt.Sprintf("Incorrect locked balance. Expected 100.10 got %s", usdtBalance.Locked))
 		}
 	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	if market.OrderHistory()[1].Status != model.FILLED {
+		t.Error("Unfilled order was not filled on the next price update")
+	}
+
+	usdtBalance, _ = market.Balance("USDT")
+	if !usdtBalance.Free.Equal(decimal.NewFromFloat(899.9)) || !usdtBalance.Locked.IsZero() {
+		t.Error(fmt.Sprintf("Incorrect USDT balance free:%s locked:%s", usdtBalance.Free, usdtBalance.Locked))
+	}
+}
+
+func TestMarketOrderLifecycle(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	market := NewSimulatedMarket(1, decimal.Zero)
+	market.SetOrderLifecycle(OrderLifecycle{MinLatency: 2 * time.Minute, MaxLatency: 2 * time.Minute,
+		Expiry: 10 * time.Minute})
+	market.Deposit("USDT", decimal.NewFromInt(1000))
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(100), start)
+
+	err := market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.BUY, Type: model.MARKET,
+		Quantity: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(110), start.Add(time.Minute))
+
+	if market.OrderHistory()[0].Status != model.NEW {
+		t.Error("Order filled before its latency elapsed")
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(120), start.Add(2*time.Minute))
+
+	order := market.OrderHistory()[0]
+	if order.Status != model.FILLED || !order.Fills[0].Price.Equal(decimal.NewFromInt(120)) {
+		t.Fatal("Delayed order not filled at the price after its latency")
+	}
+
+	usdtBalance, _ := market.Balance("USDT")
+	if !usdtBalance.Free.Equal(decimal.NewFromInt(880)) || !usdtBalance.Locked.IsZero() {
+		t.Error(fmt.Sprintf("Incorrect USDT balance free:%s locked:%s", usdtBalance.Free, usdtBalance.Locked))
+	}
+
+	market.SetOrderLifecycle(OrderLifecycle{MinLatency: time.Hour, MaxLatency: time.Hour, Expiry: 10 * time.Minute})
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.SELL, Type: model.MARKET,
+		Quantity: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(120), start.Add(15*time.Minute))
+
+	if market.OrderHistory()[1].Status != model.EXPIRED {
+		t.Error("Order did not expire before its latency elapsed")
+	}
+
+	btcBalance, _ := market.Balance("BTC")
+	if !btcBalance.Free.Equal(decimal.NewFromInt(1)) || !btcBalance.Locked.IsZero() {
+		t.Error(fmt.Sprintf("Incorrect BTC balance free:%s locked:%s", btcBalance.Free, btcBalance.Locked))
+	}
+}
+
+func TestMarketLimitOrder(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.NewFromFloat(0.001))
+	market.Deposit("USDT", decimal.NewFromInt(1000))
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1100), time.Now())
+
+	err := market.NewOrder(model.OrderRequest{
+		Symbol:        "BTCUSDT",
+		Side:          model.BUY,
+		Type:          model.LIMIT,
+		TimeInForce:   model.GTC,
+		Quantity:      decimal.NewFromFloat(0.5),
+		Price:         decimal.NewFromInt(1000),
+		ClientOrderId: "limit",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(market.OpenOrders("BTCUSDT")) != 1 {
+		t.Fatal("Limit order above market price should rest")
+	}
+
+	usdtBalance, _ := market.Balance("USDT")
+	if !usdtBalance.Locked.Equal(decimal.NewFromFloat(500.5)) {
+		t.Error(fmt.Sprintf("Incorrect locked balance. Expected 500.5 got %s", usdtBalance.Locked))
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1050), time.Now())
+
+	if len(market.Trades()) != 0 {
+		t.Error("Limit order filled above limit price")
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(990), time.Now())
+
+	order := market.OrderHistory()[0]
+	if order.Status != model.FILLED {
+		t.Fatal(fmt.Sprintf("Expected order FILLED got %s", order.Status))
+	}
+
+	if len(order.Fills) != 1 || !order.Fills[0].Price.Equal(decimal.NewFromInt(1000)) || !market.Trades()[0].IsMaker {
+		t.Error("Expected a single maker fill at the limit price")
+	}
+
+	usdtBalance, _ = market.Balance("USDT")
+	if !usdtBalance.Free.Equal(decimal.NewFromFloat(499.5)) || !usdtBalance.Locked.IsZero() {
+		t.Error(fmt.Sprintf("Incorrect USDT balance free:%s locked:%s", usdtBalance.Free, usdtBalance.Locked))
+	}
+}
+
+func TestMarketStopLoss(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.Deposit("BTC", decimal.NewFromInt(2))
+	market.Deposit("USDT", decimal.Zero)
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	err := market.NewOrder(model.OrderRequest{
+		Symbol:        "BTCUSDT",
+		Side:          model.SELL,
+		Type:          model.STOP_LOSS,
+		Quantity:      decimal.NewFromInt(1),
+		StopPrice:     decimal.NewFromInt(900),
+		ClientOrderId: "stop",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcBalance, _ := market.Balance("BTC")
+	if !btcBalance.Locked.Equal(decimal.NewFromInt(1)) || !btcBalance.Free.Equal(decimal.NewFromInt(1)) {
+		t.Error(fmt.Sprintf("Incorrect BTC balance free:%s locked:%s", btcBalance.Free, btcBalance.Locked))
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(950), time.Now())
+
+	if market.OrderHistory()[0].Status != model.NEW {
+		t.Error("Stop loss triggered above stop price")
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(880), time.Now())
+
+	if market.OrderHistory()[0].Status != model.FILLED {
+		t.Fatal("Stop loss did not trigger below stop price")
+	}
+
+	usdtBalance, _ := market.Balance("USDT")
+	if !usdtBalance.Free.Equal(decimal.NewFromInt(880)) {
+		t.Error(fmt.Sprintf("Expected stop loss to sell at market price 880 got %s", usdtBalance.Free))
+	}
+}
+
+func TestMarketTakeProfitLimit(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.Deposit("BTC", decimal.NewFromInt(1))
+	market.Deposit("USDT", decimal.Zero)
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	err := market.NewOrder(model.OrderRequest{
+		Symbol:      "BTCUSDT",
+		Side:        model.SELL,
+		Type:        model.TAKE_PROFIT_LIMIT,
+		TimeInForce: model.GTC,
+		Quantity:    decimal.NewFromInt(1),
+		StopPrice:   decimal.NewFromInt(1200),
+		Price:       decimal.NewFromInt(1250),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1210), time.Now())
+
+	if market.OrderHistory()[0].Status != model.NEW {
+		t.Error("Take profit limit filled below its limit price")
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1260), time.Now())
+
+	if market.OrderHistory()[0].Status != model.FILLED {
+		t.Fatal("Take profit limit not filled above its limit price")
+	}
+
+	usdtBalance, _ := market.Balance("USDT")
+	if !usdtBalance.Free.Equal(decimal.NewFromInt(1250)) {
+		t.Error(fmt.Sprintf("Expected fill at limit price 1250 got %s", usdtBalance.Free))
+	}
+}
+
+func TestMarketPartialFill(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.SetFillLimit(decimal.NewFromInt(1))
+	market.Deposit("BTC", decimal.NewFromFloat(2.5))
+	market.Deposit("USDT", decimal.Zero)
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	err := market.NewOrder(model.OrderRequest{
+		Symbol:        "BTCUSDT",
+		Side:          model.SELL,
+		Type:          model.LIMIT,
+		TimeInForce:   model.GTC,
+		Quantity:      decimal.NewFromFloat(2.5),
+		Price:         decimal.NewFromInt(1000),
+		ClientOrderId: "partial",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := market.OrderHistory()[0]
+	if order.Status != model.PARTIALLY_FILLED || !order.ExecutedQty.Equal(decimal.NewFromInt(1)) {
+		t.Fatal(fmt.Sprintf("Expected partial fill of 1 got %s (%s)", order.ExecutedQty, order.Status))
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	if err := market.CancelOrder("partial"); err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Status != model.CANCELLED || len(order.Fills) != 2 {
+		t.Error(fmt.Sprintf("Expected cancelled order with 2 fills got %s with %d", order.Status, len(order.Fills)))
+	}
+
+	btcBalance, _ := market.Balance("BTC")
+	if !btcBalance.Free.Equal(decimal.NewFromFloat(0.5)) || !btcBalance.Locked.IsZero() {
+		t.Error(fmt.Sprintf("Incorrect BTC balance free:%s locked:%s", btcBalance.Free, btcBalance.Locked))
+	}
+}
+
+func TestMarketTimeInForce(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.SetFillLimit(decimal.NewFromInt(1))
+	market.Deposit("USDT", decimal.NewFromInt(10000))
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	orders := []model.OrderRequest{
+		{Symbol: "BTCUSDT", Side: model.BUY, Type: model.LIMIT, TimeInForce: model.FOK,
+			Quantity: decimal.NewFromInt(2), Price: decimal.NewFromInt(1000)},
+		{Symbol: "BTCUSDT", Side: model.BUY, Type: model.LIMIT, TimeInForce: model.IOC,
+			Quantity: decimal.NewFromInt(2), Price: decimal.NewFromInt(1000)},
+		{Symbol: "BTCUSDT", Side: model.BUY, Type: model.LIMIT, TimeInForce: model.IOC,
+			Quantity: decimal.NewFromInt(2), Price: decimal.NewFromInt(900)},
+	}
+
+	for _, order := range orders {
+		if err := market.NewOrder(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := market.OrderHistory()
+
+	if history[0].Status != model.EXPIRED || !history[0].ExecutedQty.IsZero() {
+		t.Error("FOK order should expire without fills when it cannot fill completely")
+	}
+
+	if history[1].Status != model.EXPIRED || !history[1].ExecutedQty.Equal(decimal.NewFromInt(1)) {
+		t.Error("IOC order should fill available quantity and expire the rest")
+	}
+
+	if history[2].Status != model.EXPIRED || !history[2].ExecutedQty.IsZero() {
+		t.Error("IOC order below market should expire")
+	}
+
+	usdtBalance, _ := market.Balance("USDT")
+	if !usdtBalance.Free.Equal(decimal.NewFromInt(9000)) || !usdtBalance.Locked.IsZero() {
+		t.Error(fmt.Sprintf("Incorrect USDT balance free:%s locked:%s", usdtBalance.Free, usdtBalance.Locked))
+	}
+}
+
+func TestMarketLimitMaker(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.Deposit("USDT", decimal.NewFromInt(10000))
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(1000), time.Now())
+
+	err := market.NewOrder(model.OrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     model.BUY,
+		Type:     model.LIMIT_MAKER,
+		Quantity: decimal.NewFromInt(1),
+		Price:    decimal.NewFromInt(1010),
+	})
+
+	if err == nil {
+		t.Error("Marketable LIMIT_MAKER order was accepted")
+	}
+
+	if len(market.OrderHistory()) != 0 {
+		t.Error("Rejected order recorded in history")
+	}
+}
+
+func TestMarketSymbolRegistry(t *testing.T) {
+	registry := model.NewSymbolRegistry()
+	registry.Register(model.Symbol{
+		Symbol:      "DOGEBUSD",
+		BaseAsset:   "DOGE",
+		QuoteAsset:  "BUSD",
+		StepSize:    decimal.NewFromInt(1),
+		MinNotional: decimal.NewFromInt(10),
+	})
+
+	market := NewSimulatedMarket(0, decimal.Zero)
+	market.SetSymbolRegistry(registry)
+	market.Deposit("BUSD", decimal.NewFromInt(100))
+	market.UpdateCoinValue("DOGEBUSD", decimal.NewFromFloat(0.5), time.Now())
+
+	err := market.NewOrder(model.OrderRequest{Symbol: "DOGEBUSD", Side: model.BUY, Type: model.MARKET,
+		Quantity: decimal.NewFromFloat(10.7)})
+	if !errors.Is(err, model.ErrFilterFailure) {
+		t.Error("Expected min notional failure got ", err)
+	}
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "DOGEBUSD", Side: model.BUY, Type: model.MARKET,
+		Quantity: decimal.NewFromFloat(50.7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dogeBalance, err := market.Balance("DOGE")
+	if err != nil || !dogeBalance.Free.Equal(decimal.NewFromInt(50)) {
+		t.Error(fmt.Sprintf("Expected 50 DOGE got %s", dogeBalance.Free))
+	}
+
+	busdBalance, _ := market.Balance("BUSD")
+	if !busdBalance.Free.Equal(decimal.NewFromInt(75)) {
+		t.Error(fmt.Sprintf("Expected 75 BUSD got %s", busdBalance.Free))
+	}
+}
+
+func TestMarketCommission(t *testing.T) {
+	market := NewSimulatedMarket(0, decimal.NewFromFloat(0.001))
+	market.SetCommissionRates(decimal.NewFromFloat(0.0005), decimal.NewFromFloat(0.002))
+	market.Deposit("USDT", decimal.NewFromInt(1000))
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(100), time.Unix(1, 0))
+
+	if market.AccountInformation().MakerCommission != 5 || market.AccountInformation().TakerCommission != 20 {
+		t.Error(fmt.Sprintf("Incorrect account commissions got %d/%d expected 5/20",
+			market.AccountInformation().MakerCommission, market.AccountInformation().TakerCommission))
+	}
+
+	err := market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.BUY, Quantity: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.BUY, Type: model.LIMIT,
+		Quantity: decimal.NewFromInt(1), Price: decimal.NewFromInt(90)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market.UpdateCoinValue("BTCUSDT", decimal.NewFromInt(89), time.Unix(2, 0))
+
+	if taker := market.Trades()[0]; !taker.Commission.Equal(decimal.NewFromFloat(0.2)) || taker.IsMaker {
+		t.Error(fmt.Sprintf("Incorrect taker commission got %s expected 0.2", taker.Commission))
+	}
+
+	if maker := market.Trades()[1]; !maker.Commission.Equal(decimal.NewFromFloat(0.045)) || !maker.IsMaker {
+		t.Error(fmt.Sprintf("Incorrect maker commission got %s expected 0.045", maker.Commission))
+	}
+
+	market.SetCommissionAsset("BNB", decimal.NewFromFloat(0.25))
+	market.Deposit("BNB", decimal.NewFromFloat(0.01))
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.SELL, Quantity: decimal.NewFromInt(1)})
+	if !errors.Is(err, model.ErrInvalidSymbol) || len(market.OrderHistory()) != 2 {
+		t.Fatal("Expected an order without a BNB price to fail got ", err)
+	}
+
+	market.UpdateCoinValue("BNBUSDT", decimal.NewFromInt(15), time.Unix(3, 0))
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.SELL, Quantity: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill := market.OrderHistory()[2].Fills[0]
+	if fill.CommissionAsset != "BNB" || !fill.Commission.Equal(decimal.NewFromFloat(0.0089)) {
+		t.Error(fmt.Sprintf("Incorrect BNB commission got %s %s expected 0.0089 BNB", fill.Commission, fill.CommissionAsset))
+	}
+
+	if balance, _ := market.Balance("BNB"); !balance.Free.Equal(decimal.NewFromFloat(0.0011)) {
+		t.Error(fmt.Sprintf("Incorrect BNB balance got %s expected 0.0011", balance.Free))
+	}
+
+	if balance, _ := market.Balance("USDT"); !balance.Free.Equal(decimal.NewFromFloat(898.755)) {
+		t.Error(fmt.Sprintf("Incorrect USDT balance got %s expected 898.755", balance.Free))
+	}
+
+	err = market.NewOrder(model.OrderRequest{Symbol: "BTCUSDT", Side: model.SELL, Quantity: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fill := market.OrderHistory()[3].Fills[0]; fill.CommissionAsset != "USDT" {
+		t.Error("Expected commission in USDT once BNB balance is exhausted got ", fill.CommissionAsset)
+	}
 }
@@ -0,0 +1,106 @@
+package predictor
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const latestPath string = "/predictor/latest/"
+
+// LatestSource polls the predictor server for the latest prediction of each coin. Next returns the predictions that are
+// new since the previous poll ordered by Timestamp and then ErrPending, the following call polls again.
+type LatestSource struct {
+	HttpClient http.Client
+	Endpoint   string
+	Coins      []string
+	RetryDelay time.Duration
+	last       map[string]time.Time
+	queue      []Prediction
+	polled     bool
+}
+
+func NewLatestSource(host string, port string, timeout int, coins []string) *LatestSource {
+	return &LatestSource{
+		HttpClient: http.Client{Timeout: time.Duration(timeout) * time.Second},
+		Endpoint:   "http://" + host + ":" + port + latestPath,
+		Coins:      coins,
+		RetryDelay: 30 * time.Second,
+		last:       make(map[string]time.Time),
+	}
+}
+
+func (s *LatestSource) Next() (Prediction, error) {
+	if len(s.queue) == 0 && !s.polled {
+		s.poll()
+		s.polled = true
+	}
+
+	if len(s.queue) == 0 {
+		s.polled = false
+		return Prediction{}, ErrPending
+	}
+
+	prediction := s.queue[0]
+	s.queue = s.queue[1:]
+
+	return prediction, nil
+}
+
+func (s *LatestSource) Close() error {
+	s.HttpClient.CloseIdleConnections()
+	return nil
+}
+
+func (s *LatestSource) poll() {
+	for _, coin := range s.Coins {
+		prediction, ok := s.latest(coin)
+		if !ok {
+			continue
+		}
+
+		if last, exists := s.last[coin]; exists && prediction.Timestamp.Equal(last) {
+			continue
+		}
+
+		s.last[coin] = prediction.Timestamp
+		s.queue = append(s.queue, prediction)
+	}
+
+	sort.SliceStable(s.queue, func(i, j int) bool {
+		return s.queue[i].Timestamp.Before(s.queue[j].Timestamp)
+	})
+}
+
+// latest requests the prediction of coin, retrying until the server answers.
+func (s *LatestSource) latest(coin string) (Prediction, bool) {
+	var prediction Prediction
+
+	for {
+		resp, err := s.HttpClient.Get(s.Endpoint + coin)
+		if err != nil {
+			log.Println("Failed getting latest prediction from server: " + err.Error())
+		} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			resp.Body.Close()
+			log.Println("Failed getting latest prediction from server: " + strconv.Itoa(resp.StatusCode))
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&prediction)
+			resp.Body.Close()
+
+			if err != nil {
+				log.Println("Invalid prediction for " + coin + ": " + err.Error())
+				return prediction, false
+			}
+
+			prediction.Coin = coin
+
+			return prediction, true
+		}
+
+		log.Printf("Sleeping for %s...", s.RetryDelay)
+		time.Sleep(s.RetryDelay)
+	}
+}
@@ -7,19 +7,25 @@ import (
 type SimulatedPredictor struct {
 	NextPrediction Prediction
 	Uncertainty    float64
+	random         *rand.Rand
 }
 
 func NewSimulatedPredictor(uncertainty float64) *SimulatedPredictor {
 	return &SimulatedPredictor{
 		NextPrediction: Prediction{},
 		Uncertainty:    uncertainty,
+		random:         rand.New(rand.NewSource(1)),
 	}
 }
 
+// SetRandom sets the source of the prediction errors, one of its own seeded with 1 by default so that runs repeat.
+func (p *SimulatedPredictor) SetRandom(random *rand.Rand) {
+	p.random = random
+}
+
 func (p *SimulatedPredictor) Predict(coin string) Prediction {
 	if coin != p.NextPrediction.Coin {
 		panic("Prediction coin: " + p.NextPrediction.Coin + " doesnt match " + coin)
-		return Prediction{}
 	} else {
 		return p.NextPrediction
 	}
@@ -33,5 +39,5 @@ func (p *SimulatedPredictor) SetNextPrediction(prediction Prediction) {
 }
 
 func (p *SimulatedPredictor) calcError() float64 {
-	return 1 - (-p.Uncertainty + rand.Float64()*(2*p.Uncertainty))
+	return 1 - (-p.Uncertainty + p.random.Float64()*(2*p.Uncertainty))
 }
@@ -1,6 +1,7 @@
 package predictor
 
 import (
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -71,3 +72,20 @@ func TestUncertainty(t *testing.T) {
 		t.Error("Expected prediction between -1 and 2, got ", pred)
 	}
 }
+
+func TestPredictSeeded(t *testing.T) {
+	prediction := Prediction{Coin: "BTCUSDT", CloseValue: 1, Pred5: 1, Pred10: 1, Pred100: 1}
+
+	first := NewSimulatedPredictor(0.5)
+	first.SetRandom(rand.New(rand.NewSource(3)))
+	second := NewSimulatedPredictor(0.5)
+	second.SetRandom(rand.New(rand.NewSource(3)))
+
+	for i := 0; i < 10; i++ {
+		first.SetNextPrediction(prediction)
+		second.SetNextPrediction(prediction)
+		if first.Predict("BTCUSDT") != second.Predict("BTCUSDT") {
+			t.Fatal("Expected the same errors from the same seed ", first.Predict("BTCUSDT"), second.Predict("BTCUSDT"))
+		}
+	}
+}
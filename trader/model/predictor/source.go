@@ -0,0 +1,347 @@
+package predictor
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ErrOutOfOrder is returned by a source whose predictions go back in time.
+var ErrOutOfOrder = errors.New("predictions out of order")
+
+// ErrPending is returned by live sources when no new prediction is available yet, Next can be called again later.
+var ErrPending = errors.New("no prediction available yet")
+
+// PredictionSource iterates over predictions in Timestamp order, predictions with the same Timestamp may come in any
+// coin order. Next returns io.EOF once the source is exhausted.
+type PredictionSource interface {
+	Next() (Prediction, error)
+	Close() error
+}
+
+// Dataset opens a new source over the same predictions every time, so concurrent simulations iterate it independently.
+type Dataset interface {
+	Open() (PredictionSource, error)
+}
+
+type DatasetFunc func() (PredictionSource, error)
+
+func (f DatasetFunc) Open() (PredictionSource, error) {
+	return f()
+}
+
+// Collect drains and closes the source.
+func Collect(source PredictionSource) ([]Prediction, error) {
+	defer source.Close()
+
+	predictions := make([]Prediction, 0)
+	for {
+		prediction, err := source.Next()
+		if err == io.EOF {
+			return predictions, nil
+		} else if err != nil {
+			return predictions, err
+		}
+		predictions = append(predictions, prediction)
+	}
+}
+
+// Coins lists the coins of dataset sorted by name. Datasets with a Coins method of their own list them, others are read
+// through once.
+func Coins(dataset Dataset) ([]string, error) {
+	if listed, ok := dataset.(interface {
+		Coins() ([]string, error)
+	}); ok {
+		return listed.Coins()
+	}
+
+	source, err := dataset.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	seen := make(map[string]bool)
+	coins := make([]string, 0)
+	for {
+		prediction, err := source.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !seen[prediction.Coin] {
+			seen[prediction.Coin] = true
+			coins = append(coins, prediction.Coin)
+		}
+	}
+
+	sort.Strings(coins)
+	return coins, nil
+}
+
+// Window restricts dataset to [start, end). Datasets with a Window method of their own narrow themselves, others are
+// filtered as they are read.
+func Window(dataset Dataset, start time.Time, end time.Time) Dataset {
+	if windowed, ok := dataset.(interface {
+		Window(start time.Time, end time.Time) Dataset
+	}); ok {
+		return windowed.Window(start, end)
+	}
+
+	return DatasetFunc(func() (PredictionSource, error) {
+		source, err := dataset.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &windowSource{source: source, start: start, end: end}, nil
+	})
+}
+
+type windowSource struct {
+	source PredictionSource
+	start  time.Time
+	end    time.Time
+}
+
+func (w *windowSource) Next() (Prediction, error) {
+	for {
+		prediction, err := w.source.Next()
+		if err != nil {
+			return prediction, err
+		}
+
+		if !prediction.Timestamp.Before(w.end) {
+			return Prediction{}, io.EOF
+		}
+		if !prediction.Timestamp.Before(w.start) {
+			return prediction, nil
+		}
+	}
+}
+
+func (w *windowSource) Close() error {
+	return w.source.Close()
+}
+
+type SliceSource struct {
+	predictions []Prediction
+	position    int
+}
+
+// NewSliceSource iterates over predictions already sorted by Timestamp without copying them.
+func NewSliceSource(predictions []Prediction) *SliceSource {
+	return &SliceSource{predictions: predictions}
+}
+
+func (s *SliceSource) Next() (Prediction, error) {
+	if s.position >= len(s.predictions) {
+		return Prediction{}, io.EOF
+	}
+
+	prediction := s.predictions[s.position]
+	if s.position > 0 && prediction.Timestamp.Before(s.predictions[s.position-1].Timestamp) {
+		return Prediction{}, ErrOutOfOrder
+	}
+	s.position++
+
+	return prediction, nil
+}
+
+func (s *SliceSource) Close() error {
+	return nil
+}
+
+type sliceDataset []Prediction
+
+func SliceDataset(predictions []Prediction) Dataset {
+	return sliceDataset(predictions)
+}
+
+func (d sliceDataset) Open() (PredictionSource, error) {
+	return NewSliceSource(d), nil
+}
+
+// Window slices the predictions, which are sorted by Timestamp, without copying them.
+func (d sliceDataset) Window(start time.Time, end time.Time) Dataset {
+	from := sort.Search(len(d), func(i int) bool {
+		return !d[i].Timestamp.Before(start)
+	})
+	to := sort.Search(len(d), func(i int) bool {
+		return !d[i].Timestamp.Before(end)
+	})
+	if to < from {
+		to = from
+	}
+
+	return d[from:to]
+}
+
+// PagedSource fetches [start, end) a page at a time, so only one page of predictions is held in memory.
+type PagedSource struct {
+	fetch    Fetcher
+	end      time.Time
+	pageSize time.Duration
+	next     time.Time
+	page     []Prediction
+	position int
+	last     time.Time
+}
+
+func NewPagedSource(fetch Fetcher, start time.Time, end time.Time, pageSize time.Duration) *PagedSource {
+	return &PagedSource{
+		fetch:    fetch,
+		end:      end,
+		pageSize: pageSize,
+		next:     start,
+	}
+}
+
+func PagedDataset(fetch Fetcher, start time.Time, end time.Time, pageSize time.Duration) Dataset {
+	return DatasetFunc(func() (PredictionSource, error) {
+		return NewPagedSource(fetch, start, end, pageSize), nil
+	})
+}
+
+func (s *PagedSource) Next() (Prediction, error) {
+	for s.position >= len(s.page) {
+		if !s.next.Before(s.end) {
+			return Prediction{}, io.EOF
+		}
+
+		pageEnd := s.next.Add(s.pageSize)
+		if pageEnd.After(s.end) {
+			pageEnd = s.end
+		}
+
+		page, err := s.fetch(s.next, pageEnd)
+		if err != nil {
+			return Prediction{}, err
+		}
+
+		// Pages may overlap at their bounds, only keep what belongs to [next, pageEnd).
+		s.page = s.page[:0]
+		for _, prediction := range page {
+			if !prediction.Timestamp.Before(s.next) && prediction.Timestamp.Before(pageEnd) {
+				s.page = append(s.page, prediction)
+			}
+		}
+		sort.SliceStable(s.page, func(i, j int) bool {
+			return s.page[i].Timestamp.Before(s.page[j].Timestamp)
+		})
+
+		s.position = 0
+		s.next = pageEnd
+	}
+
+	prediction := s.page[s.position]
+	if prediction.Timestamp.Before(s.last) {
+		return Prediction{}, ErrOutOfOrder
+	}
+	s.position++
+	s.last = prediction.Timestamp
+
+	return prediction, nil
+}
+
+func (s *PagedSource) Close() error {
+	s.page = nil
+	return nil
+}
+
+type mergeHead struct {
+	prediction Prediction
+	input      int
+}
+
+type mergeHeap []mergeHead
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].prediction.Timestamp.Equal(h[j].prediction.Timestamp) {
+		return h[i].input < h[j].input
+	}
+	return h[i].prediction.Timestamp.Before(h[j].prediction.Timestamp)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeHead)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// MergedSource interleaves sources, usually one per coin, by Timestamp. Predictions with the same Timestamp come in the
+// order of their sources.
+type MergedSource struct {
+	inputs  []PredictionSource
+	last    []time.Time
+	heads   mergeHeap
+	started bool
+}
+
+func NewMergedSource(inputs ...PredictionSource) *MergedSource {
+	return &MergedSource{
+		inputs: inputs,
+		last:   make([]time.Time, len(inputs)),
+	}
+}
+
+func (s *MergedSource) Next() (Prediction, error) {
+	if !s.started {
+		s.started = true
+		for input := range s.inputs {
+			if err := s.advance(input); err != nil {
+				return Prediction{}, err
+			}
+		}
+	}
+
+	if s.heads.Len() == 0 {
+		return Prediction{}, io.EOF
+	}
+
+	head := heap.Pop(&s.heads).(mergeHead)
+	if err := s.advance(head.input); err != nil {
+		return Prediction{}, err
+	}
+
+	return head.prediction, nil
+}
+
+func (s *MergedSource) advance(input int) error {
+	prediction, err := s.inputs[input].Next()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if prediction.Timestamp.Before(s.last[input]) {
+		return fmt.Errorf("%s at %s: %w", prediction.Coin, prediction.Timestamp, ErrOutOfOrder)
+	}
+	s.last[input] = prediction.Timestamp
+
+	heap.Push(&s.heads, mergeHead{prediction: prediction, input: input})
+
+	return nil
+}
+
+func (s *MergedSource) Close() error {
+	var closeErr error
+	for _, input := range s.inputs {
+		if err := input.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
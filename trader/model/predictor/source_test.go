@@ -0,0 +1,168 @@
+package predictor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func coinPredictions(coin string, start time.Time, step time.Duration, count int) []Prediction {
+	predictions := make([]Prediction, count)
+	for i := range predictions {
+		predictions[i] = Prediction{Timestamp: start.Add(time.Duration(i) * step), Coin: coin, CloseValue: float64(i)}
+	}
+	return predictions
+}
+
+func TestMergedSource(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	source := NewMergedSource(
+		NewSliceSource(coinPredictions("BTCUSDT", day, 2*time.Minute, 3)),
+		NewSliceSource(coinPredictions("ETHUSDT", day, time.Minute, 4)),
+		NewSliceSource(nil),
+	)
+
+	predictions, err := Collect(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"BTCUSDT", "ETHUSDT", "ETHUSDT", "BTCUSDT", "ETHUSDT", "ETHUSDT", "BTCUSDT"}
+	if len(predictions) != len(expected) {
+		t.Fatal("Incorrect number of predictions ", len(predictions))
+	}
+
+	for i, prediction := range predictions {
+		if prediction.Coin != expected[i] || (i > 0 && prediction.Timestamp.Before(predictions[i-1].Timestamp)) {
+			t.Error("Incorrect order ", predictions)
+			break
+		}
+	}
+
+	unordered := coinPredictions("BTCUSDT", day, time.Minute, 3)
+	unordered[1], unordered[2] = unordered[2], unordered[1]
+
+	if _, err := Collect(NewMergedSource(NewSliceSource(unordered))); !errors.Is(err, ErrOutOfOrder) {
+		t.Error("Expected out of order predictions to fail, got ", err)
+	}
+}
+
+func TestPagedSource(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	all := coinPredictions("BTCUSDT", day, time.Hour, 48)
+
+	pages := 0
+	fetch := func(start time.Time, end time.Time) ([]Prediction, error) {
+		pages++
+		page := make([]Prediction, 0)
+		for _, prediction := range all {
+			// The aggregator includes the end of the range, which the source must drop.
+			if !prediction.Timestamp.Before(start) && !prediction.Timestamp.After(end) {
+				page = append([]Prediction{prediction}, page...)
+			}
+		}
+		return page, nil
+	}
+
+	predictions, err := Collect(NewPagedSource(fetch, day, day.Add(30*time.Hour), 8*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 4 || len(predictions) != 30 || !predictions[29].Timestamp.Equal(day.Add(29*time.Hour)) {
+		t.Error("Incorrect paged predictions ", pages, len(predictions))
+	}
+
+	for i := 1; i < len(predictions); i++ {
+		if !predictions[i].Timestamp.After(predictions[i-1].Timestamp) {
+			t.Fatal("Expected predictions in order without duplicates")
+		}
+	}
+}
+
+func TestLatestSource(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	latest := map[string]Prediction{
+		"BTCUSDT": {Timestamp: day.Add(time.Minute), Coin: "BTCUSDT"},
+		"ETHUSDT": {Timestamp: day, Coin: "ETHUSDT"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		coin := strings.TrimPrefix(r.URL.Path, latestPath)
+		json.NewEncoder(w).Encode(latest[coin])
+	}))
+	defer server.Close()
+
+	address, _ := url.Parse(server.URL)
+	source := NewLatestSource(address.Hostname(), address.Port(), 5, []string{"BTCUSDT", "ETHUSDT"})
+	defer source.Close()
+
+	first, _ := source.Next()
+	second, _ := source.Next()
+	if first.Coin != "ETHUSDT" || second.Coin != "BTCUSDT" {
+		t.Error("Expected the poll ordered by timestamp, got ", first, second)
+	}
+
+	if _, err := source.Next(); err != ErrPending {
+		t.Error("Expected pending after the poll, got ", err)
+	}
+
+	latest["ETHUSDT"] = Prediction{Timestamp: day.Add(2 * time.Minute), Coin: "ETHUSDT"}
+
+	prediction, err := source.Next()
+	if err != nil || prediction.Coin != "ETHUSDT" || !prediction.Timestamp.Equal(day.Add(2*time.Minute)) {
+		t.Error("Expected only the new prediction, got ", prediction, err)
+	}
+
+	if _, err := source.Next(); err != ErrPending {
+		t.Error("Expected pending after the poll, got ", err)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	predictions := coinPredictions("BTCUSDT", day, time.Hour, 24)
+
+	datasets := []Dataset{
+		SliceDataset(predictions),
+		DatasetFunc(func() (PredictionSource, error) {
+			return NewSliceSource(predictions), nil
+		}),
+	}
+
+	for _, dataset := range datasets {
+		source, err := Window(dataset, day.Add(5*time.Hour), day.Add(8*time.Hour)).Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		windowed, err := Collect(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(windowed) != 3 || windowed[0].CloseValue != 5 || windowed[2].CloseValue != 7 {
+			t.Error("Incorrect window ", windowed)
+		}
+	}
+}
+
+func TestCoins(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := NewMergedSource(NewSliceSource(coinPredictions("ETHUSDT", day, time.Hour, 3)),
+		NewSliceSource(coinPredictions("BTCUSDT", day.Add(time.Hour), time.Hour, 3)))
+	predictions, err := Collect(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if coins, err := Coins(SliceDataset(predictions)); err != nil || len(coins) != 2 || coins[0] != "BTCUSDT" {
+		t.Error("Expected the sorted coins read through the dataset, got ", coins, err)
+	}
+}
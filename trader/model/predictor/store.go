@@ -0,0 +1,389 @@
+package predictor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	storeIndexFile    = "index.json"
+	storeFileExt      = ".jsonl"
+	storeIndexVersion = 1
+)
+
+// TimeRange is the half open interval [Start, End).
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (r TimeRange) Empty() bool {
+	return !r.End.After(r.Start)
+}
+
+func (r TimeRange) Duration() time.Duration {
+	if r.Empty() {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+func (r TimeRange) String() string {
+	return r.Start.UTC().Format(time.RFC3339) + " - " + r.End.UTC().Format(time.RFC3339)
+}
+
+// Fetcher obtains the predictions of every coin between start and end, usually from the aggregator.
+type Fetcher func(start time.Time, end time.Time) ([]Prediction, error)
+
+type storeIndex struct {
+	Version int         `json:"version"`
+	Ranges  []TimeRange `json:"ranges"`
+}
+
+// FileStore keeps predictions on disk as one JSON Lines file per coin sorted by time, along with an index of the time
+// ranges already synced so only the missing ones are fetched again.
+type FileStore struct {
+	Dir   string
+	index storeIndex
+}
+
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{
+		Dir:   dir,
+		index: storeIndex{Version: storeIndexVersion},
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, storeIndexFile))
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &store.index); err != nil {
+		return nil, fmt.Errorf("invalid prediction store index in %s: %w", dir, err)
+	}
+
+	if store.index.Version != storeIndexVersion {
+		return nil, errors.New(fmt.Sprintf("prediction store %s has unsupported version %d", dir, store.index.Version))
+	}
+
+	return store, nil
+}
+
+// Ranges returns the synced time ranges, sorted and without overlaps.
+func (s *FileStore) Ranges() []TimeRange {
+	return append([]TimeRange(nil), s.index.Ranges...)
+}
+
+// Missing returns the parts of [start, end) that were never synced.
+func (s *FileStore) Missing(start time.Time, end time.Time) []TimeRange {
+	missing := make([]TimeRange, 0)
+	cursor := start
+
+	for _, synced := range s.index.Ranges {
+		if !synced.End.After(cursor) {
+			continue
+		}
+		if !synced.Start.Before(end) {
+			break
+		}
+		if synced.Start.After(cursor) {
+			missing = append(missing, TimeRange{Start: cursor, End: synced.Start})
+		}
+		cursor = synced.End
+	}
+
+	if cursor.Before(end) {
+		missing = append(missing, TimeRange{Start: cursor, End: end})
+	}
+
+	return missing
+}
+
+// Sync fetches the missing parts of [start, end) and stores them, returning the number of predictions fetched.
+func (s *FileStore) Sync(start time.Time, end time.Time, fetch Fetcher) (int, error) {
+	fetched := 0
+
+	for _, gap := range s.Missing(start, end) {
+		predictions, err := fetch(gap.Start, gap.End)
+		if err != nil {
+			return fetched, err
+		}
+
+		if err := s.Write(predictions, gap); err != nil {
+			return fetched, err
+		}
+
+		fetched += len(predictions)
+	}
+
+	return fetched, nil
+}
+
+// Write merges the predictions into the coin files, replacing those with the same timestamp, and marks synced as
+// covered. The index is written last so an interrupted write is fetched again.
+func (s *FileStore) Write(predictions []Prediction, synced TimeRange) error {
+	byCoin := make(map[string][]Prediction)
+	for _, prediction := range predictions {
+		byCoin[prediction.Coin] = append(byCoin[prediction.Coin], prediction)
+	}
+
+	for coin, coinPredictions := range byCoin {
+		if err := s.mergeCoin(coin, coinPredictions); err != nil {
+			return err
+		}
+	}
+
+	s.index.Ranges = mergeRanges(append(s.index.Ranges, synced))
+
+	return s.saveIndex()
+}
+
+// Load returns the stored predictions of every coin in [start, end), ordered by time and then coin.
+func (s *FileStore) Load(start time.Time, end time.Time) ([]Prediction, error) {
+	source, err := s.Source(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return Collect(source)
+}
+
+// Source streams the stored predictions of every coin in [start, end), reading the coin files side by side instead of
+// loading them.
+func (s *FileStore) Source(start time.Time, end time.Time) (PredictionSource, error) {
+	coins, err := s.Coins()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]PredictionSource, 0, len(coins))
+	for _, coin := range coins {
+		input, err := s.openCoin(coin, start, end)
+		if err != nil {
+			NewMergedSource(inputs...).Close()
+			return nil, err
+		}
+		inputs = append(inputs, input)
+	}
+
+	return NewMergedSource(inputs...), nil
+}
+
+func (s *FileStore) Dataset(start time.Time, end time.Time) Dataset {
+	return storeDataset{store: s, TimeRange: TimeRange{Start: start, End: end}}
+}
+
+type storeDataset struct {
+	TimeRange
+	store *FileStore
+}
+
+func (d storeDataset) Open() (PredictionSource, error) {
+	return d.store.Source(d.Start, d.End)
+}
+
+func (d storeDataset) Window(start time.Time, end time.Time) Dataset {
+	if start.Before(d.Start) {
+		start = d.Start
+	}
+	if end.After(d.End) {
+		end = d.End
+	}
+
+	return d.store.Dataset(start, end)
+}
+
+// Coins are those of the store, whether or not they have predictions in the window.
+func (d storeDataset) Coins() ([]string, error) {
+	return d.store.Coins()
+}
+
+// Coins returns the coins with a file in the store, sorted by name.
+func (s *FileStore) Coins() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*"+storeFileExt))
+	if err != nil {
+		return nil, err
+	}
+
+	coins := make([]string, len(files))
+	for i, file := range files {
+		coins[i] = strings.TrimSuffix(filepath.Base(file), storeFileExt)
+	}
+
+	sort.Strings(coins)
+
+	return coins, nil
+}
+
+func (s *FileStore) coinPath(coin string) (string, error) {
+	if coin == "" || strings.ContainsAny(coin, `/\.`) {
+		return "", errors.New(fmt.Sprintf("invalid coin %q", coin))
+	}
+
+	return filepath.Join(s.Dir, coin+storeFileExt), nil
+}
+
+func (s *FileStore) readCoin(coin string) ([]Prediction, error) {
+	source, err := s.openCoin(coin, time.Time{}, time.Time{})
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return Collect(source)
+}
+
+func (s *FileStore) openCoin(coin string, start time.Time, end time.Time) (*coinFileSource, error) {
+	path, err := s.coinPath(coin)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &coinFileSource{
+		file:    file,
+		decoder: json.NewDecoder(bufio.NewReader(file)),
+		start:   start,
+		end:     end,
+	}, nil
+}
+
+// coinFileSource streams the predictions of one coin file within [start, end), a zero end reads to the end of file.
+type coinFileSource struct {
+	file    *os.File
+	decoder *json.Decoder
+	start   time.Time
+	end     time.Time
+}
+
+func (c *coinFileSource) Next() (Prediction, error) {
+	for {
+		var prediction Prediction
+		if err := c.decoder.Decode(&prediction); err == io.EOF {
+			return Prediction{}, io.EOF
+		} else if err != nil {
+			return Prediction{}, fmt.Errorf("invalid prediction in %s: %w", c.file.Name(), err)
+		}
+
+		if !c.end.IsZero() && !prediction.Timestamp.Before(c.end) {
+			return Prediction{}, io.EOF
+		}
+		if !prediction.Timestamp.Before(c.start) {
+			return prediction, nil
+		}
+	}
+}
+
+func (c *coinFileSource) Close() error {
+	return c.file.Close()
+}
+
+func (s *FileStore) mergeCoin(coin string, predictions []Prediction) error {
+	path, err := s.coinPath(coin)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.readCoin(coin)
+	if err != nil {
+		return err
+	}
+
+	byTime := make(map[int64]Prediction, len(existing)+len(predictions))
+	for _, prediction := range append(existing, predictions...) {
+		byTime[prediction.Timestamp.UnixNano()] = prediction
+	}
+
+	merged := make([]Prediction, 0, len(byTime))
+	for _, prediction := range byTime {
+		merged = append(merged, prediction)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	return writeAtomic(path, func(writer io.Writer) error {
+		encoder := json.NewEncoder(writer)
+		for _, prediction := range merged {
+			if err := encoder.Encode(prediction); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *FileStore) saveIndex() error {
+	return writeAtomic(filepath.Join(s.Dir, storeIndexFile), func(writer io.Writer) error {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s.index)
+	})
+}
+
+// writeAtomic writes to a temporary file renamed over path once complete, so readers never see a partial file.
+func writeAtomic(path string, write func(writer io.Writer) error) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func mergeRanges(ranges []TimeRange) []TimeRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start.Before(ranges[j].Start)
+	})
+
+	merged := make([]TimeRange, 0, len(ranges))
+	for _, current := range ranges {
+		if !current.End.After(current.Start) {
+			continue
+		}
+
+		last := len(merged) - 1
+		if last >= 0 && !current.Start.After(merged[last].End) {
+			if current.End.After(merged[last].End) {
+				merged[last].End = current.End
+			}
+			continue
+		}
+
+		merged = append(merged, current)
+	}
+
+	return merged
+}
@@ -0,0 +1,121 @@
+package predictor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func hourlyPredictions(start time.Time, end time.Time) []Prediction {
+	predictions := make([]Prediction, 0)
+	for ts := start; ts.Before(end); ts = ts.Add(time.Hour) {
+		for _, coin := range []string{"ETHUSDT", "BTCUSDT"} {
+			predictions = append(predictions, Prediction{Timestamp: ts, Coin: coin, CloseValue: float64(ts.Hour())})
+		}
+	}
+	return predictions
+}
+
+func TestFileStoreSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "predictions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requested []TimeRange
+	fetch := func(start time.Time, end time.Time) ([]Prediction, error) {
+		requested = append(requested, TimeRange{start, end})
+		return hourlyPredictions(start, end), nil
+	}
+
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if fetched, err := store.Sync(day.Add(6*time.Hour), day.Add(12*time.Hour), fetch); err != nil || fetched != 12 {
+		t.Fatal("Incorrect first sync ", fetched, err)
+	}
+
+	if fetched, err := store.Sync(day, day.Add(24*time.Hour), fetch); err != nil || fetched != 36 {
+		t.Fatal("Incorrect incremental sync ", fetched, err)
+	}
+
+	if len(requested) != 3 || !requested[1].End.Equal(day.Add(6*time.Hour)) || !requested[2].Start.Equal(day.Add(12*time.Hour)) {
+		t.Error("Expected only the missing ranges to be fetched, got ", requested)
+	}
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ranges := reopened.Ranges(); len(ranges) != 1 || !ranges[0].Start.Equal(day) || !ranges[0].End.Equal(day.Add(24*time.Hour)) {
+		t.Error("Incorrect synced ranges ", ranges)
+	}
+
+	offline := func(start time.Time, end time.Time) ([]Prediction, error) {
+		return nil, errors.New("offline")
+	}
+	if _, err := reopened.Sync(day.Add(time.Hour), day.Add(20*time.Hour), offline); err != nil {
+		t.Error("Expected covered range not to be fetched ", err)
+	}
+
+	predictions, err := reopened.Load(day.Add(10*time.Hour), day.Add(14*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(predictions) != 8 || predictions[0].Coin != "BTCUSDT" || predictions[1].Coin != "ETHUSDT" ||
+		!predictions[7].Timestamp.Equal(day.Add(13*time.Hour)) {
+		t.Error("Incorrect loaded predictions ", predictions)
+	}
+
+	if coins, _ := reopened.Coins(); len(coins) != 2 {
+		t.Error("Expected a file per coin, got ", coins)
+	}
+
+	if coins, _ := Coins(reopened.Dataset(day, day.Add(time.Hour))); len(coins) != 2 || coins[1] != "ETHUSDT" {
+		t.Error("Expected the coins of the store, got ", coins)
+	}
+
+	if missing := reopened.Missing(day.Add(20*time.Hour), day.Add(48*time.Hour)); len(missing) != 1 ||
+		!missing[0].Start.Equal(day.Add(24*time.Hour)) {
+		t.Error("Incorrect missing ranges ", missing)
+	}
+}
+
+func TestFileStoreOverwrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "predictions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, _ := OpenFileStore(dir)
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := []Prediction{{Timestamp: day, Coin: "BTCUSDT", Pred5: 1}}
+	if err := store.Write(first, TimeRange{day, day.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	second := []Prediction{{Timestamp: day, Coin: "BTCUSDT", Pred5: 2}}
+	if err := store.Write(second, TimeRange{day, day.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	predictions, _ := store.Load(day, day.Add(time.Hour))
+	if len(predictions) != 1 || predictions[0].Pred5 != 2 {
+		t.Error("Expected the prediction to be replaced, got ", predictions)
+	}
+
+	if err := store.Write([]Prediction{{Timestamp: day, Coin: "../BTCUSDT"}}, TimeRange{day, day}); err == nil {
+		t.Error("Expected coin outside the store to fail")
+	}
+}
@@ -0,0 +1,97 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+const NamedConfigVersion = 1
+
+// NamedConfig is the file format of a strategy config: the params keyed by field name together with the strategy they
+// belong to and where they came from, so an evolved config can be promoted to live trading as is.
+type NamedConfig struct {
+	Name       string          `json:"name"`
+	Strategy   string          `json:"strategy"`
+	Version    int             `json:"version"`
+	Fitness    float64         `json:"fitness,omitempty"`
+	Provenance Provenance      `json:"provenance"`
+	Params     json.RawMessage `json:"params"`
+}
+
+// Provenance records where a config came from. The validation and test fitness of an evolved config are measured on
+// predictions after TrainEnd.
+type Provenance struct {
+	Source            string     `json:"source"`
+	CreatedAt         *time.Time `json:"created_at,omitempty"`
+	Parent            string     `json:"parent,omitempty"`
+	TrainStart        *time.Time `json:"train_start,omitempty"`
+	TrainEnd          *time.Time `json:"train_end,omitempty"`
+	Generations       int        `json:"generations,omitempty"`
+	Fitness           string     `json:"fitness_function,omitempty"`
+	ValidationFitness *float64   `json:"validation_fitness,omitempty"`
+	TestFitness       *float64   `json:"test_fitness,omitempty"`
+}
+
+func NewNamedConfig(name string, strategy string, config StrategyConfig, fitness float64, provenance Provenance) (*NamedConfig, error) {
+	params, err := config.Schema().MarshalParams(config.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+
+	return &NamedConfig{
+		Name:       name,
+		Strategy:   strategy,
+		Version:    NamedConfigVersion,
+		Fitness:    fitness,
+		Provenance: provenance,
+		Params:     params,
+	}, nil
+}
+
+func LoadNamedConfig(path string) (*NamedConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var named NamedConfig
+	if err := json.Unmarshal(data, &named); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if named.Version < 1 || named.Version > NamedConfigVersion {
+		return nil, errors.New(fmt.Sprintf("config file %s has unsupported version %d", path, named.Version))
+	}
+
+	if named.Strategy == "" {
+		return nil, errors.New(fmt.Sprintf("config file %s doesn't name its strategy", path))
+	}
+
+	return &named, nil
+}
+
+func (n *NamedConfig) Save(path string) error {
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), os.FileMode(0644))
+}
+
+// Decode fills config with the params of the file, validated against its schema. Params the file leaves out take
+// their default, so configs saved before a param was added still load. Params the schema doesn't know are rejected.
+func (n *NamedConfig) Decode(config StrategyConfig) error {
+	params, err := config.Schema().UnmarshalParams(n.Params)
+	if err != nil {
+		return fmt.Errorf("invalid params for %s config %s: %w", n.Strategy, n.Name, err)
+	}
+
+	config.FromSlice(params)
+
+	return nil
+}
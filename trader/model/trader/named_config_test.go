@@ -0,0 +1,107 @@
+package trader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"scoing-trader/trader/model/trader"
+	"scoing-trader/trader/model/trader/strategies"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamedConfigRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "named-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &strategies.BasicWithMemoryConfig{}
+	config.FromSlice([]float64{1.1, 1.2, 1.3, 2.1, 2.2, 2.3, -0.1, 0.2, 0.5, 0.6, 0.01, 0.02})
+
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	named, err := trader.NewNamedConfig("evolved", "BasicWithMemory", config, 1042.5,
+		trader.Provenance{Source: "evolution", CreatedAt: &createdAt, Parent: "evolution-start", Generations: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "evolved.json")
+	if err := named.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(data), `"StopLoss": -0.1`) {
+		t.Error("Expected params keyed by field name got ", string(data))
+	}
+
+	loaded, err := trader.LoadNamedConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Name != "evolved" || loaded.Fitness != 1042.5 || loaded.Provenance.Parent != "evolution-start" ||
+		!loaded.Provenance.CreatedAt.Equal(createdAt) {
+		t.Error("Incorrect config metadata ", loaded)
+	}
+
+	decoded := &strategies.BasicWithMemoryConfig{}
+	if err := loaded.Decode(decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded.ToSlice(), config.ToSlice()) {
+		t.Error("Decoded params differ ", decoded.ToSlice())
+	}
+
+	if err := loaded.Decode(&strategies.BasicConfig{}); err == nil {
+		t.Error("Expected decoding into a different strategy config to fail")
+	}
+}
+
+func TestNamedConfigValidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "named-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"version":  `{"name": "a", "strategy": "Basic", "version": 99, "params": {}}`,
+		"strategy": `{"name": "a", "version": 1, "params": {}}`,
+		"json":     `{"name": `,
+	} {
+		path := filepath.Join(dir, name+".json")
+		ioutil.WriteFile(path, []byte(content), 0644)
+
+		if _, err := trader.LoadNamedConfig(path); err == nil {
+			t.Error("Expected invalid config to fail: " + name)
+		}
+	}
+
+	named := &trader.NamedConfig{Name: "a", Strategy: "Basic", Version: 1,
+		Params: []byte(`{"BuyPred5Mod": 1, "BuyPred10Mod": 1, "BuyPred100Mod": 1, "SellPred5Mod": 1, "SellPred10Mod": 1,
+		"SellPred100Mod": 1, "StopLoss": 1, "ProfitCap": 1, "BuyQtyMod": 1, "Typo": 1}`)}
+
+	if err := named.Decode(&strategies.BasicConfig{}); err == nil {
+		t.Error("Expected unknown param to fail")
+	}
+
+	named.Params = []byte(`{"BuyPred5Mod": 2, "StopLoss": -0.2}`)
+	config := &strategies.BasicConfig{}
+	if err := named.Decode(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.BuyPred5Mod != 2 || config.StopLoss != -0.2 || config.SellQtyMod != config.Schema()[9].Default {
+		t.Error("Expected the params left out to take their default ", config)
+	}
+
+	named.Params = []byte(`{"StopLoss": 0.5}`)
+	if err := named.Decode(config); err == nil {
+		t.Error("Expected a param out of its range to fail")
+	}
+}
@@ -0,0 +1,274 @@
+package trader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type ParamType string
+
+const (
+	FloatParam ParamType = "float"
+	IntParam   ParamType = "int"
+)
+
+// Param describes a strategy param: the config field it is held in, the range it is searched over and the value a
+// config file may leave it at. Integer params take whole values and stepped params the values Min + k*Step. Log scale
+// params are drawn and bred by order of magnitude, their range must be positive.
+type Param struct {
+	Name     string
+	Type     ParamType
+	Min      float64
+	Max      float64
+	Step     float64
+	LogScale bool
+	Default  float64
+}
+
+// Schema lists the params of a strategy config in the positional order of its slices.
+type Schema []Param
+
+func (s Schema) Names() []string {
+	names := make([]string, len(s))
+	for i, param := range s {
+		names[i] = param.Name
+	}
+	return names
+}
+
+// Index finds a param by name, or by position when given a number.
+func (s Schema) Index(name string) (int, error) {
+	for i, param := range s {
+		if param.Name == name {
+			return i, nil
+		}
+	}
+
+	if index, err := strconv.Atoi(name); err == nil && index >= 0 && index < len(s) {
+		return index, nil
+	}
+
+	return 0, errors.New(fmt.Sprintf("unknown param %s, expected one of %s", name, strings.Join(s.Names(), ", ")))
+}
+
+func (s Schema) Ranges() ([]float64, []float64) {
+	min := make([]float64, len(s))
+	max := make([]float64, len(s))
+	for i, param := range s {
+		min[i], max[i] = param.Min, param.Max
+	}
+	return min, max
+}
+
+func (s Schema) Defaults() []float64 {
+	defaults := make([]float64, len(s))
+	for i, param := range s {
+		defaults[i] = param.Default
+	}
+	return defaults
+}
+
+// Check verifies the schema itself: names are unique, ranges hold their defaults and log scales are positive.
+func (s Schema) Check() error {
+	seen := make(map[string]bool)
+
+	for _, param := range s {
+		if param.Name == "" || seen[param.Name] {
+			return errors.New(fmt.Sprintf("param name %q is empty or repeated", param.Name))
+		}
+		seen[param.Name] = true
+
+		if param.Type != FloatParam && param.Type != IntParam {
+			return errors.New(fmt.Sprintf("param %s has unknown type %q", param.Name, param.Type))
+		}
+		if param.Min > param.Max || param.Step < 0 || param.LogScale && param.Min <= 0 {
+			return errors.New(fmt.Sprintf("param %s has an invalid range", param.Name))
+		}
+		if err := param.check(param.Default); err != nil {
+			return fmt.Errorf("default of %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks a config has a value for every param, in its range and of its type.
+func (s Schema) Validate(params []float64) error {
+	if len(params) != len(s) {
+		return errors.New(fmt.Sprintf("expected %d params, got %d", len(s), len(params)))
+	}
+
+	for i, param := range s {
+		if err := param.check(params[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p Param) check(value float64) error {
+	if math.IsNaN(value) || value < p.Min || value > p.Max {
+		return errors.New(fmt.Sprintf("param %s is %v, outside [%v, %v]", p.Name, value, p.Min, p.Max))
+	}
+	if p.Type == IntParam && value != math.Trunc(value) {
+		return errors.New(fmt.Sprintf("param %s is %v, expected a whole number", p.Name, value))
+	}
+	return nil
+}
+
+// ToUnit maps the params onto [0, 1], linearly or by order of magnitude for log scale params. Optimisers search the
+// unit cube and FromUnit turns their points back into configs.
+func (s Schema) ToUnit(params []float64) []float64 {
+	unit := make([]float64, len(s))
+	for i, param := range s {
+		unit[i] = param.Position(params[i])
+	}
+	return unit
+}
+
+// FromUnit maps a point of the unit cube back onto the params, snapped to their steps and whole numbers.
+func (s Schema) FromUnit(unit []float64) []float64 {
+	params := make([]float64, len(s))
+	for i, param := range s {
+		params[i] = param.snap(param.Value(unit[i]))
+	}
+	return params
+}
+
+// Position places value on [0, 1], linearly or by order of magnitude for log scale params.
+func (p Param) Position(value float64) float64 {
+	if p.Max == p.Min {
+		return 0
+	}
+
+	var position float64
+	if p.LogScale {
+		position = math.Log(value/p.Min) / math.Log(p.Max/p.Min)
+	} else {
+		position = (value - p.Min) / (p.Max - p.Min)
+	}
+	return math.Max(0, math.Min(1, position))
+}
+
+// Value is the param at position of [0, 1], before it is snapped to its step.
+func (p Param) Value(position float64) float64 {
+	position = math.Max(0, math.Min(1, position))
+	if p.LogScale {
+		return p.Min * math.Pow(p.Max/p.Min, position)
+	}
+	return p.Min + position*(p.Max-p.Min)
+}
+
+func (p Param) snap(value float64) float64 {
+	if p.Step > 0 {
+		value = p.Min + math.Round((value-p.Min)/p.Step)*p.Step
+	}
+	if p.Type == IntParam {
+		value = math.Round(value)
+	}
+	if value > p.Max {
+		value -= math.Max(p.Step, 1)
+	}
+	return math.Max(p.Min, math.Min(p.Max, value))
+}
+
+// Random draws a config uniformly over the unit cube, so log scale params are as likely in every order of magnitude.
+func (s Schema) Random(random *rand.Rand) []float64 {
+	unit := make([]float64, len(s))
+	for i := range unit {
+		unit[i] = random.Float64()
+	}
+	return s.FromUnit(unit)
+}
+
+// RandomizeParam draws a new value for one param of the config picked at random.
+func (s Schema) RandomizeParam(params []float64, random *rand.Rand) {
+	i := random.Intn(len(s))
+	unit := s.ToUnit(params)
+	unit[i] = random.Float64()
+	params[i] = s.FromUnit(unit)[i]
+}
+
+// MarshalParams writes the params as a JSON object keyed by name, in the order of the schema.
+func (s Schema) MarshalParams(params []float64) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+
+	for i, param := range s {
+		name, _ := json.Marshal(param.Name)
+		value, err := json.Marshal(params[i])
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", param.Name, err)
+		}
+
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalParams reads params keyed by name, the params left out taking their default. Unknown names and invalid
+// values are rejected.
+func (s Schema) UnmarshalParams(data []byte) ([]float64, error) {
+	var fields map[string]float64
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	params := s.Defaults()
+	for name, value := range fields {
+		i, err := s.Index(name)
+		if err != nil || s[i].Name != name {
+			return nil, errors.New(fmt.Sprintf("unknown param %s, expected one of %s", name,
+				strings.Join(s.Names(), ", ")))
+		}
+		params[i] = value
+	}
+
+	return params, s.Validate(params)
+}
+
+// ParamsOf reads the params of a config from the struct fields named by its schema.
+func ParamsOf(config StrategyConfig) []float64 {
+	fields := reflect.ValueOf(config).Elem()
+	schema := config.Schema()
+
+	params := make([]float64, len(schema))
+	for i, param := range schema {
+		field := fields.FieldByName(param.Name)
+		if param.Type == IntParam {
+			params[i] = float64(field.Int())
+		} else {
+			params[i] = field.Float()
+		}
+	}
+	return params
+}
+
+// SetParams fills the struct fields named by the schema of a config with the params.
+func SetParams(config StrategyConfig, params []float64) {
+	fields := reflect.ValueOf(config).Elem()
+
+	for i, param := range config.Schema() {
+		field := fields.FieldByName(param.Name)
+		if param.Type == IntParam {
+			field.SetInt(int64(math.Round(params[i])))
+		} else {
+			field.SetFloat(params[i])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package trader_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"scoing-trader/trader/model/trader"
+	"testing"
+)
+
+type testConfig struct {
+	Rate     float64
+	Window   int
+	Stop     float64
+	Leverage float64
+}
+
+var testSchema = trader.Schema{
+	{Name: "Rate", Type: trader.FloatParam, Min: 0.001, Max: 10, LogScale: true, Default: 0.1},
+	{Name: "Window", Type: trader.IntParam, Min: 2, Max: 20, Default: 10},
+	{Name: "Stop", Type: trader.FloatParam, Min: -0.3, Max: 0, Step: 0.05, Default: -0.1},
+	{Name: "Leverage", Type: trader.FloatParam, Min: 1, Max: 1, Default: 1},
+}
+
+func (c *testConfig) Schema() trader.Schema {
+	return testSchema
+}
+
+func (c *testConfig) ToSlice() []float64 {
+	return trader.ParamsOf(c)
+}
+
+func (c *testConfig) FromSlice(slice []float64) {
+	trader.SetParams(c, slice)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	if err := testSchema.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []trader.Schema{
+		{{Name: "A", Type: trader.FloatParam, Max: 1}, {Name: "A", Type: trader.FloatParam, Max: 1}},
+		{{Name: "A", Type: trader.FloatParam, Max: 1, LogScale: true}},
+		{{Name: "A", Type: trader.FloatParam, Max: 1, Default: 2}},
+		{{Name: "A", Type: "string", Max: 1}},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Error("Expected invalid schema to fail ", invalid)
+		}
+	}
+
+	if err := testSchema.Validate([]float64{1, 5, -0.1, 1}); err != nil {
+		t.Error(err)
+	}
+
+	for _, params := range [][]float64{{1, 5, -0.1}, {20, 5, -0.1, 1}, {1, 5.5, -0.1, 1}, {math.NaN(), 5, -0.1, 1}} {
+		if err := testSchema.Validate(params); err == nil {
+			t.Error("Expected invalid params to fail ", params)
+		}
+	}
+}
+
+func TestSchemaUnit(t *testing.T) {
+	params := testSchema.FromUnit([]float64{0.5, 0.5, 0.52, 0.5})
+	if math.Abs(params[0]-0.1) > 1e-9 || params[1] != 11 || math.Abs(params[2]+0.15) > 1e-9 || params[3] != 1 {
+		t.Error("Expected log scale, whole and stepped params ", params)
+	}
+
+	unit := testSchema.ToUnit([]float64{0.01, 2, 0, 1})
+	if math.Abs(unit[0]-0.25) > 1e-9 || unit[1] != 0 || unit[2] != 1 || unit[3] != 0 {
+		t.Error("Incorrect unit point ", unit)
+	}
+
+	random := rand.New(rand.NewSource(3))
+	for i := 0; i < 100; i++ {
+		params := testSchema.Random(random)
+		if err := testSchema.Validate(params); err != nil {
+			t.Fatal(err)
+		}
+
+		before := append([]float64{}, params...)
+		testSchema.RandomizeParam(params, random)
+		changed := 0
+		for j := range params {
+			if params[j] != before[j] {
+				changed++
+			}
+		}
+		if changed > 1 || testSchema.Validate(params) != nil {
+			t.Fatal("Expected a single valid param to change ", before, params)
+		}
+	}
+}
+
+func TestSchemaParams(t *testing.T) {
+	config := &testConfig{}
+	config.FromSlice([]float64{0.5, 7, -0.2, 1})
+	if config.Window != 7 || config.Stop != -0.2 || !reflect.DeepEqual(config.ToSlice(), []float64{0.5, 7, -0.2, 1}) {
+		t.Error("Incorrect params ", config)
+	}
+
+	data, err := testSchema.MarshalParams(config.ToSlice())
+	if err != nil || string(data) != `{"Rate":0.5,"Window":7,"Stop":-0.2,"Leverage":1}` {
+		t.Error("Expected params keyed by name in schema order ", string(data), err)
+	}
+
+	params, err := testSchema.UnmarshalParams([]byte(`{"Window": 4, "Rate": 2}`))
+	if err != nil || !reflect.DeepEqual(params, []float64{2, 4, -0.1, 1}) {
+		t.Error("Expected missing params to take their default ", params, err)
+	}
+
+	for _, invalid := range []string{`{"Window": 4.5}`, `{"Window": 4, "Typo": 1}`, `{"0": 1}`, `[1, 2]`} {
+		if _, err := testSchema.UnmarshalParams([]byte(invalid)); err == nil {
+			t.Error("Expected invalid params to fail ", invalid)
+		}
+	}
+
+	if index, err := testSchema.Index("Stop"); err != nil || index != 2 {
+		t.Error("Incorrect index ", index, err)
+	}
+	if index, err := testSchema.Index("1"); err != nil || index != 1 {
+		t.Error("Expected a position to index its param ", index, err)
+	}
+}
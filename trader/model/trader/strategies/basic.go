@@ -2,12 +2,15 @@ package strategies
 
 import (
 	"github.com/shopspring/decimal"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
 	"scoing-trader/trader/model/trader"
+	"sort"
 )
 
 type BasicStrategy struct {
-	Config BasicConfig
+	Config  BasicConfig
+	Symbols *model.SymbolRegistry
 }
 
 func NewBasicStrategy(slice []float64) *BasicStrategy {
@@ -16,6 +19,10 @@ func NewBasicStrategy(slice []float64) *BasicStrategy {
 	return basicStrategy
 }
 
+func (s *BasicStrategy) SetSymbols(symbols *model.SymbolRegistry) {
+	s.Symbols = symbols
+}
+
 func (s *BasicStrategy) ComputeDecision(prediction predictor.Prediction, positions map[string]decimal.Decimal,
 	coinNetWorth decimal.Decimal, coinValue decimal.Decimal, totalNetWorth decimal.Decimal, balance decimal.Decimal, fee decimal.Decimal) map[trader.DecisionType]trader.Decision {
 
@@ -34,7 +41,8 @@ func (s *BasicStrategy) ComputeDecision(prediction predictor.Prediction, positio
 		}
 	}
 
-	for val, qty := range positions {
+	for _, val := range buyValues(positions) {
+		qty := positions[val]
 		decimalVal, _ := decimal.NewFromString(val)
 		currentProfit := decimal.NewFromInt(1).Sub(decimalVal.Div(decimal.NewFromFloat(prediction.CloseValue)).Mul(decimal.NewFromInt(1).Sub(fee)))
 		if (((pred5*s.Config.SellPred5Mod)+(pred10*s.Config.SellPred10Mod)+(pred100*s.Config.SellPred100Mod)) < -2 &&
@@ -72,16 +80,17 @@ func (s *BasicStrategy) BuySize(prediction predictor.Prediction, coinNetWorth de
 
 	maxCoinNetWorth := totalNetWorth.Mul(decimal.NewFromFloat(0.3))
 	maxTransaction := totalNetWorth.Mul(decimal.NewFromFloat(0.05))
+	minTransaction := minNotional(s.Symbols, prediction.Coin)
 
-	if maxCoinNetWorth.Sub(coinNetWorth).GreaterThanOrEqual(decimal.NewFromInt(10)) &&
-		balance.GreaterThanOrEqual(decimal.NewFromInt(10).Mul(decimal.NewFromInt(1).Mul(fee))) {
-		transaction := decimal.Max(decimal.NewFromInt(10), decimal.Min(maxTransaction, maxCoinNetWorth.Sub(coinNetWorth).Mul(decimal.NewFromFloat(s.Config.BuyQtyMod))))
+	if maxCoinNetWorth.Sub(coinNetWorth).GreaterThanOrEqual(minTransaction) &&
+		balance.GreaterThanOrEqual(minTransaction.Mul(decimal.NewFromInt(1).Mul(fee))) {
+		transaction := decimal.Max(minTransaction, decimal.Min(maxTransaction, maxCoinNetWorth.Sub(coinNetWorth).Mul(decimal.NewFromFloat(s.Config.BuyQtyMod))))
 		transactionWFee := transaction.Mul(decimal.NewFromInt(1).Add(fee))
 
 		if transactionWFee.LessThan(balance) {
-			return transaction.Div(decimal.NewFromFloat(prediction.CloseValue))
+			return roundQty(s.Symbols, prediction.Coin, transaction.Div(decimal.NewFromFloat(prediction.CloseValue)))
 		} else {
-			return balance.Sub(decimal.NewFromInt(1)).Div(decimal.NewFromFloat(prediction.CloseValue).Mul(decimal.NewFromInt(1).Add(fee)))
+			return roundQty(s.Symbols, prediction.Coin, balance.Sub(decimal.NewFromInt(1)).Div(decimal.NewFromFloat(prediction.CloseValue).Mul(decimal.NewFromInt(1).Add(fee))))
 		}
 	} else {
 		return decimal.Zero
@@ -89,9 +98,9 @@ func (s *BasicStrategy) BuySize(prediction predictor.Prediction, coinNetWorth de
 }
 
 func (s *BasicStrategy) SellSize(prediction predictor.Prediction, positionQty decimal.Decimal, coinValue decimal.Decimal) decimal.Decimal {
-	proposedQty := positionQty.Mul(decimal.NewFromFloat(s.Config.SellQtyMod))
+	proposedQty := roundQty(s.Symbols, prediction.Coin, positionQty.Mul(decimal.NewFromFloat(s.Config.SellQtyMod)))
 
-	if coinValue.Mul(proposedQty).GreaterThan(decimal.NewFromInt(10)) {
+	if coinValue.Mul(proposedQty).GreaterThan(minNotional(s.Symbols, prediction.Coin)) {
 		return proposedQty
 	} else {
 		return decimal.Zero
@@ -123,3 +132,19 @@ func (s *BasicStrategy) computePredictors(predValue5 float64, predValue10 float6
 
 	return pred5, pred10, pred100
 }
+
+// buyValues are the buy values of the positions from the lowest, the order they are looked at in so that runs repeat.
+func buyValues(positions map[string]decimal.Decimal) []string {
+	values := make([]string, 0, len(positions))
+	for value := range positions {
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		valueI, _ := decimal.NewFromString(values[i])
+		valueJ, _ := decimal.NewFromString(values[j])
+		return valueI.LessThan(valueJ)
+	})
+
+	return values
+}
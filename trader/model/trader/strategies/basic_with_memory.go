@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/shopspring/decimal"
 	"math"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
 	"scoing-trader/trader/model/trader"
 )
@@ -14,6 +15,7 @@ type BasicWithMemoryStrategy struct {
 	PredictionHistory5 map[string][]float64
 	DecisionHistory    map[string][]trader.DecisionType
 	HistoryLength      int
+	Symbols            *model.SymbolRegistry
 }
 
 func NewBasicWithMemoryStrategy(slice []float64, historyLength int) *BasicWithMemoryStrategy {
@@ -28,6 +30,10 @@ func NewBasicWithMemoryStrategy(slice []float64, historyLength int) *BasicWithMe
 	return basicWithMemoryStrategy
 }
 
+func (s *BasicWithMemoryStrategy) SetSymbols(symbols *model.SymbolRegistry) {
+	s.Symbols = symbols
+}
+
 func (s *BasicWithMemoryStrategy) ComputeDecision(prediction predictor.Prediction, positions map[string]decimal.Decimal,
 	coinNetWorth decimal.Decimal, coinValue decimal.Decimal, totalNetWorth decimal.Decimal, balance decimal.Decimal, fee decimal.Decimal) map[trader.DecisionType]trader.Decision {
 
@@ -69,7 +75,8 @@ func (s *BasicWithMemoryStrategy) ComputeDecision(prediction predictor.Predictio
 	if len(s.PriceHistory[prediction.Coin]) < s.HistoryLength || (s.historyGetDecisionCount(prediction.Coin, trader.SELL) <
 		math.Round(float64(s.HistoryLength)/2) && priceDelta != 1 && predDelta != 1) {
 
-		for val, qty := range positions {
+		for _, val := range buyValues(positions) {
+			qty := positions[val]
 			decimalVal, _ := decimal.NewFromString(val)
 			currentProfit := decimal.NewFromInt(1).Sub(decimalVal.Div(decimal.NewFromFloat(prediction.CloseValue)).Mul(decimal.NewFromInt(1).Sub(fee)))
 			if (len(s.PriceHistory) < s.HistoryLength || (s.historyGetDecisionCount(prediction.Coin, trader.SELL) <
@@ -107,8 +114,10 @@ func (s *BasicWithMemoryStrategy) ComputeDecision(prediction predictor.Predictio
 	}
 
 	decisionTypes := make([]trader.DecisionType, 0, len(decisionMap))
-	for d := range decisionMap {
-		decisionTypes = append(decisionTypes, d)
+	for _, d := range trader.DecisionOrder {
+		if _, decided := decisionMap[d]; decided {
+			decisionTypes = append(decisionTypes, d)
+		}
 	}
 
 	s.addToHistory(prediction.Coin, prediction.CloseValue, prediction.Pred5, decisionTypes)
@@ -121,16 +130,17 @@ func (s *BasicWithMemoryStrategy) BuySize(prediction predictor.Prediction, coinN
 
 	maxCoinNetWorth := totalNetWorth.Mul(decimal.NewFromFloat(0.3))
 	maxTransaction := totalNetWorth.Mul(decimal.NewFromFloat(0.05))
+	minTransaction := minNotional(s.Symbols, prediction.Coin)
 
-	if maxCoinNetWorth.Sub(coinNetWorth).GreaterThanOrEqual(decimal.NewFromInt(10)) &&
-		balance.GreaterThanOrEqual(decimal.NewFromInt(10).Mul(decimal.NewFromInt(1).Mul(fee))) {
-		transaction := decimal.Max(decimal.NewFromInt(10), decimal.Min(maxTransaction, maxCoinNetWorth.Sub(coinNetWorth).Mul(decimal.NewFromFloat(s.Config.BuyQtyMod))))
+	if maxCoinNetWorth.Sub(coinNetWorth).GreaterThanOrEqual(minTransaction) &&
+		balance.GreaterThanOrEqual(minTransaction.Mul(decimal.NewFromInt(1).Mul(fee))) {
+		transaction := decimal.Max(minTransaction, decimal.Min(maxTransaction, maxCoinNetWorth.Sub(coinNetWorth).Mul(decimal.NewFromFloat(s.Config.BuyQtyMod))))
 		transactionWFee := transaction.Mul(decimal.NewFromInt(1).Add(fee))
 
 		if transactionWFee.LessThan(balance) {
-			return transaction.Div(decimal.NewFromFloat(prediction.CloseValue))
+			return roundQty(s.Symbols, prediction.Coin, transaction.Div(decimal.NewFromFloat(prediction.CloseValue)))
 		} else {
-			return balance.Sub(decimal.NewFromInt(1)).Div(decimal.NewFromFloat(prediction.CloseValue).Mul(decimal.NewFromInt(1).Add(fee)))
+			return roundQty(s.Symbols, prediction.Coin, balance.Sub(decimal.NewFromInt(1)).Div(decimal.NewFromFloat(prediction.CloseValue).Mul(decimal.NewFromInt(1).Add(fee))))
 		}
 	} else {
 		return decimal.Zero
@@ -138,9 +148,9 @@ func (s *BasicWithMemoryStrategy) BuySize(prediction predictor.Prediction, coinN
 }
 
 func (s *BasicWithMemoryStrategy) SellSize(prediction predictor.Prediction, positionQty decimal.Decimal, coinValue decimal.Decimal) decimal.Decimal {
-	proposedQty := positionQty.Mul(decimal.NewFromFloat(s.Config.SellQtyMod))
+	proposedQty := roundQty(s.Symbols, prediction.Coin, positionQty.Mul(decimal.NewFromFloat(s.Config.SellQtyMod)))
 
-	if coinValue.Mul(proposedQty).GreaterThan(decimal.NewFromInt(10)) {
+	if coinValue.Mul(proposedQty).GreaterThan(minNotional(s.Symbols, prediction.Coin)) {
 		return proposedQty
 	} else {
 		return decimal.Zero
@@ -0,0 +1,133 @@
+package strategies
+
+import (
+	"errors"
+	"fmt"
+	"scoing-trader/trader/model/trader"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const DefaultHistoryLength = 10
+
+// Factory builds a strategy and the config holding its params. NewStrategy receives the params in the positional
+// order of the config's schema.
+type Factory struct {
+	NewStrategy func(params []float64) trader.Strategy
+	NewConfig   func() trader.StrategyConfig
+}
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{factories: make(map[string]Factory)}
+
+func init() {
+	Register("basic", Factory{
+		NewStrategy: func(params []float64) trader.Strategy {
+			return NewBasicStrategy(params)
+		},
+		NewConfig: func() trader.StrategyConfig {
+			return &BasicConfig{}
+		},
+	})
+
+	Register("basic_with_memory", Factory{
+		NewStrategy: func(params []float64) trader.Strategy {
+			return NewBasicWithMemoryStrategy(params, DefaultHistoryLength)
+		},
+		NewConfig: func() trader.StrategyConfig {
+			return &BasicWithMemoryConfig{}
+		},
+	})
+}
+
+func Register(name string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.factories[CanonicalName(name)] = factory
+}
+
+// Lookup finds the factory of a strategy, names are matched in snake case so BasicWithMemory is basic_with_memory.
+func Lookup(name string) (Factory, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	factory, exists := registry.factories[CanonicalName(name)]
+	if !exists {
+		return Factory{}, errors.New("unknown strategy " + name + ", expected one of " + strings.Join(names(), ", "))
+	}
+
+	return factory, nil
+}
+
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return names()
+}
+
+func names() []string {
+	registered := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		registered = append(registered, name)
+	}
+
+	sort.Strings(registered)
+
+	return registered
+}
+
+func CanonicalName(name string) string {
+	var canonical strings.Builder
+
+	for idx, char := range strings.TrimSpace(name) {
+		if unicode.IsUpper(char) {
+			if idx > 0 {
+				canonical.WriteRune('_')
+			}
+			char = unicode.ToLower(char)
+		} else if char == '-' || char == ' ' {
+			char = '_'
+		}
+		canonical.WriteRune(char)
+	}
+
+	return canonical.String()
+}
+
+// New builds the named strategy from positional params, validating them against the schema of its config.
+func New(name string, params []float64) (trader.Strategy, trader.StrategyConfig, error) {
+	factory, err := Lookup(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := factory.NewConfig()
+	if err := config.Schema().Validate(params); err != nil {
+		return nil, nil, fmt.Errorf("strategy %s: %w", CanonicalName(name), err)
+	}
+
+	config.FromSlice(params)
+
+	return factory.NewStrategy(params), config, nil
+}
+
+// FromConfig builds the strategy a named config file describes.
+func FromConfig(named *trader.NamedConfig) (trader.Strategy, trader.StrategyConfig, error) {
+	factory, err := Lookup(named.Strategy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := factory.NewConfig()
+	if err := named.Decode(config); err != nil {
+		return nil, nil, err
+	}
+
+	return factory.NewStrategy(config.ToSlice()), config, nil
+}
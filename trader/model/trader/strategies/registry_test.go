@@ -0,0 +1,72 @@
+package strategies
+
+import (
+	"reflect"
+	"scoing-trader/trader/model/trader"
+	"testing"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	for _, name := range []string{"basic_with_memory", "BasicWithMemory", "basic-with-memory"} {
+		factory, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := factory.NewConfig().(*BasicWithMemoryConfig); !ok {
+			t.Error("Incorrect config for " + name)
+		}
+	}
+
+	if _, err := Lookup("martingale"); err == nil {
+		t.Error("Expected unknown strategy to fail")
+	}
+}
+
+func TestRegistryNew(t *testing.T) {
+	Register("CautiousBasic", Factory{
+		NewStrategy: func(params []float64) trader.Strategy {
+			strategy := NewBasicStrategy(params)
+			strategy.Config.BuyQtyMod /= 2
+			return strategy
+		},
+		NewConfig: func() trader.StrategyConfig {
+			return &BasicConfig{}
+		},
+	})
+
+	if !reflect.DeepEqual(Names(), []string{"basic", "basic_with_memory", "cautious_basic"}) {
+		t.Error("Incorrect registered names ", Names())
+	}
+
+	params := []float64{1, 2, 3, 0.4, 0.5, 0.6, -0.1, 0.1, 0.8, 0.5}
+
+	strategy, config, err := New("cautious_basic", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strategy.(*BasicStrategy).Config.BuyQtyMod != 0.4 || !reflect.DeepEqual(config.ToSlice(), params) {
+		t.Error("Strategy not built by the registered factory")
+	}
+
+	if _, _, err := New("basic_with_memory", params); err == nil {
+		t.Error("Expected wrong number of params to fail")
+	}
+}
+
+func TestRegistrySchemas(t *testing.T) {
+	for _, name := range Names() {
+		factory, _ := Lookup(name)
+		config := factory.NewConfig()
+
+		if err := config.Schema().Check(); err != nil {
+			t.Error(name, err)
+		}
+
+		config.FromSlice(config.Schema().Defaults())
+		if !reflect.DeepEqual(config.ToSlice(), config.Schema().Defaults()) {
+			t.Error("Expected the schema of " + name + " to name the fields of its config")
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package strategies
+
+import (
+	"github.com/shopspring/decimal"
+	"scoing-trader/trader/model/market/model"
+)
+
+var defaultMinNotional = decimal.NewFromInt(10)
+
+func minNotional(symbols *model.SymbolRegistry, coin string) decimal.Decimal {
+	if symbols == nil {
+		return defaultMinNotional
+	}
+
+	symbol, err := symbols.Get(coin)
+	if err != nil || !symbol.MinNotional.GreaterThan(decimal.Zero) {
+		return defaultMinNotional
+	}
+
+	return symbol.MinNotional
+}
+
+func roundQty(symbols *model.SymbolRegistry, coin string, qty decimal.Decimal) decimal.Decimal {
+	if symbols == nil {
+		return qty
+	}
+
+	symbol, err := symbols.Get(coin)
+	if err != nil {
+		return qty
+	}
+
+	return symbol.RoundQty(qty)
+}
@@ -3,6 +3,7 @@ package trader
 import (
 	"fmt"
 	"github.com/shopspring/decimal"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
 	"time"
 )
@@ -14,13 +15,16 @@ type Strategy interface {
 	SellSize(prediction predictor.Prediction, positionQty decimal.Decimal, coinValue decimal.Decimal) decimal.Decimal
 }
 
+type SymbolAware interface {
+	SetSymbols(symbols *model.SymbolRegistry)
+}
+
+// StrategyConfig holds the params of a strategy in the fields its schema names. ToSlice and FromSlice give them in the
+// order of the schema, usually through ParamsOf and SetParams.
 type StrategyConfig interface {
-	NumParams() int
+	Schema() Schema
 	ToSlice() []float64
 	FromSlice(slice []float64)
-	ParamRanges() ([]float64, []float64)
-	RandomFromSlices(a []float64, b []float64)
-	RandomizeParam()
 }
 
 type Decision struct {
@@ -40,6 +44,9 @@ const (
 	HOLD DecisionType = "HOLD"
 )
 
+// DecisionOrder is the order the decisions on a coin are carried out in, selling first so the proceeds can fund a buy.
+var DecisionOrder = []DecisionType{SELL, BUY, HOLD}
+
 type TradeRecord struct {
 	Timestamp   time.Time
 	Coin        string
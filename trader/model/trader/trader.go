@@ -1,9 +1,13 @@
 package trader
 
 import (
+	"errors"
 	"github.com/shopspring/decimal"
+	"log"
 	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
+	"time"
 )
 
 type Trader struct {
@@ -13,9 +17,47 @@ type Trader struct {
 	Records          []TradeRecord
 	KeepRecords      bool
 	OnlyTransactions bool
+	Recorder         Recorder
+}
+
+// Recorder persists what the trader does, trades as they happen and snapshots of the account when requested.
+type Recorder interface {
+	RecordTrade(record TradeRecord, balance decimal.Decimal, netWorth decimal.Decimal,
+		positions map[string]decimal.Decimal) error
+	RecordSnapshot(timestamp time.Time, balance decimal.Decimal, netWorth decimal.Decimal,
+		positions map[string]map[string]decimal.Decimal) error
+}
+
+// Recorders passes what the trader does on to each of them, the first error is returned once all were called.
+type Recorders []Recorder
+
+func (r Recorders) RecordTrade(record TradeRecord, balance decimal.Decimal, netWorth decimal.Decimal,
+	positions map[string]decimal.Decimal) error {
+	var recordErr error
+	for _, recorder := range r {
+		if err := recorder.RecordTrade(record, balance, netWorth, positions); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+	return recordErr
+}
+
+func (r Recorders) RecordSnapshot(timestamp time.Time, balance decimal.Decimal, netWorth decimal.Decimal,
+	positions map[string]map[string]decimal.Decimal) error {
+	var recordErr error
+	for _, recorder := range r {
+		if err := recorder.RecordSnapshot(timestamp, balance, netWorth, positions); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+	return recordErr
 }
 
 func NewTrader(accountant market.Accountant, predictor predictor.Predictor, strategy Strategy, keepRecords bool, onlyTransactions bool) *Trader {
+	if symbolAware, ok := strategy.(SymbolAware); ok {
+		symbolAware.SetSymbols(accountant.Market.Symbols())
+	}
+
 	return &Trader{
 		Accountant:       accountant,
 		Predictor:        predictor,
@@ -32,40 +74,75 @@ func (t *Trader) ProcessData(coin string) {
 	decisionArr := t.Strategy.ComputeDecision(prediction, t.Accountant.GetPositions(coin), t.Accountant.AssetValue(coin),
 		t.Accountant.NetWorth(), t.Accountant.AssetValues[coin], t.Accountant.GetBalance(), t.Accountant.GetFee())
 
-	for _, decision := range decisionArr {
+	for _, eventType := range DecisionOrder {
+		decision, decided := decisionArr[eventType]
+		if !decided {
+			continue
+		}
+
 		var transaction decimal.Decimal
 		var profit decimal.Decimal
 		var err error
 
 		if decision.EventType == BUY {
 			transaction, err = t.Accountant.Buy(coin, decision.Qty)
-			if err != nil {
+			if rejected(err) {
+				log.Printf("%s %s order rejected: %s", coin, decision.EventType, err)
+				continue
+			} else if err != nil {
 				panic(err)
 			}
 		} else if decision.EventType == SELL {
 			transaction, profit, err = t.Accountant.Sell(coin, decision.Qty)
-			if err != nil {
+			if rejected(err) {
+				log.Printf("%s %s order rejected: %s", coin, decision.EventType, err)
+				continue
+			} else if err != nil {
 				panic(err)
 			}
 		}
 
-		if t.KeepRecords {
-			if !t.OnlyTransactions || decision.EventType != HOLD {
-				record := TradeRecord{
-					Timestamp:   prediction.Timestamp,
-					Coin:        coin,
-					Event:       decision.EventType,
-					Qty:         decision.Qty,
-					Value:       decimal.NewFromFloat(prediction.CloseValue),
-					Transaction: transaction,
-					Profit:      profit,
-					BuyConf:     decision.BuyConf,
-					SellConf:    decision.SellConf,
-					DebugText:   decision.DebugText,
-				}
-
-				t.Records = append(t.Records, record)
+		record := TradeRecord{
+			Timestamp:   prediction.Timestamp,
+			Coin:        coin,
+			Event:       decision.EventType,
+			Qty:         decision.Qty,
+			Value:       decimal.NewFromFloat(prediction.CloseValue),
+			Transaction: transaction,
+			Profit:      profit,
+			BuyConf:     decision.BuyConf,
+			SellConf:    decision.SellConf,
+			DebugText:   decision.DebugText,
+		}
+
+		if t.KeepRecords && (!t.OnlyTransactions || decision.EventType != HOLD) {
+			t.Records = append(t.Records, record)
+		}
+
+		if t.Recorder != nil && decision.EventType != HOLD {
+			err := t.Recorder.RecordTrade(record, t.Accountant.GetBalance(), t.Accountant.NetWorth(),
+				t.Accountant.GetPositions(coin))
+			if err != nil {
+				log.Println("Failed recording trade: " + err.Error())
 			}
 		}
 	}
 }
+
+func (t *Trader) Snapshot(timestamp time.Time) {
+	if t.Recorder == nil {
+		return
+	}
+
+	err := t.Recorder.RecordSnapshot(timestamp, t.Accountant.GetBalance(), t.Accountant.NetWorth(), t.Accountant.Positions)
+	if err != nil {
+		log.Println("Failed recording snapshot: " + err.Error())
+	}
+}
+
+// rejected reports market refusals the trader can skip, e.g. slippage leaving the balance short of the order cost or
+// a coin quoted in another asset than the balance.
+func rejected(err error) bool {
+	return errors.Is(err, model.ErrInsufficientBalance) || errors.Is(err, model.ErrFilterFailure) ||
+		errors.Is(err, model.ErrInvalidSymbol)
+}
@@ -0,0 +1,398 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"scoing-trader/trader/export"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/metrics"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Objective is a metric of a run a multi-objective evolution trades off against the others.
+type Objective struct {
+	Name     string
+	Maximize bool
+	Value    func(curve *metrics.Curve) float64
+}
+
+var objectives = []Objective{
+	{"return", true, (*metrics.Curve).TotalReturn},
+	{"annual_return", true, (*metrics.Curve).AnnualReturn},
+	{"sharpe", true, (*metrics.Curve).Sharpe},
+	{"sortino", true, (*metrics.Curve).Sortino},
+	{"calmar", true, (*metrics.Curve).Calmar},
+	{"profit_factor", true, func(curve *metrics.Curve) float64 {
+		return math.Min(curve.ProfitFactor(), fitness.MaxProfitFactor)
+	}},
+	{"win_rate", true, (*metrics.Curve).WinRate},
+	{"max_drawdown", false, (*metrics.Curve).MaxDrawdown},
+	{"volatility", false, (*metrics.Curve).Volatility},
+	{"trades", false, func(curve *metrics.Curve) float64 {
+		return float64(curve.NumTrades())
+	}},
+}
+
+// ObjectiveNames lists the objectives a pareto front can trade off.
+func ObjectiveNames() []string {
+	names := make([]string, len(objectives))
+	for i, objective := range objectives {
+		names[i] = objective.Name
+	}
+	return names
+}
+
+// ParseObjectives reads a comma separated list of at least two objectives, e.g. return,max_drawdown,trades. Each is
+// maximised or minimised as its name implies.
+func ParseObjectives(list string) ([]Objective, error) {
+	parsed := make([]Objective, 0)
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+
+		for _, objective := range objectives {
+			if objective.Name == name {
+				parsed = append(parsed, objective)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, errors.New(fmt.Sprintf("unknown objective %q, expected one of %s", name,
+				strings.Join(ObjectiveNames(), ", ")))
+		}
+	}
+
+	if len(parsed) < 2 {
+		return nil, errors.New("a pareto front needs at least 2 objectives")
+	}
+
+	return parsed, nil
+}
+
+// Pareto evolves the configs trading the Objectives off best with NSGA-II: every generation breeds as many children
+// as specimens, picking each parent as the better of two by front and then by crowding, and keeps the best of parents
+// and children by front, spreading the last front admitted by crowding. The evolution gives the dataset, the
+// crossover and mutation operators, the generations and the seed. Its fitness and selection are not used.
+//
+// The configs no other config beats on every objective form the front, saved to FrontFile in the format implied by
+// its extension so that a config can be picked for the risk it takes.
+type Pareto struct {
+	Evolution  Evolution
+	Objectives []Objective
+	FrontFile  string
+}
+
+func (p *Pareto) Run() ([]Specimen, error) {
+	evo := &p.Evolution
+	if err := evo.setup(); err != nil {
+		return nil, err
+	}
+
+	if len(p.Objectives) < 2 {
+		return nil, errors.New("a pareto front needs at least 2 objectives")
+	}
+	if len(evo.Remote) > 0 {
+		return nil, errors.New("remote workers only score a single fitness, pareto fronts run locally")
+	}
+
+	if evo.Seed == 0 {
+		evo.Seed = time.Now().UnixNano()
+	}
+	log.Printf("Pareto seed %d", evo.Seed)
+
+	population, err := p.score(evo.firstGeneration(rand.New(rand.NewSource(evo.Seed))), 0)
+	if err != nil {
+		return nil, err
+	}
+	ranks, crowding := p.rank(population)
+	p.logFront(0, p.front(population, ranks))
+
+	for generation := 1; generation < evo.NumGenerations; generation++ {
+		random := rand.New(rand.NewSource(evo.Seed + int64(generation)))
+
+		children, err := p.score(p.breed(population, ranks, crowding, generation, random), generation)
+		if err != nil {
+			return nil, err
+		}
+
+		population = p.survivors(append(population, children...))
+		ranks, crowding = p.rank(population)
+
+		p.logFront(generation, p.front(population, ranks))
+	}
+
+	front := p.front(population, ranks)
+	if p.FrontFile != "" {
+		if err := p.save(front); err != nil {
+			return front, err
+		}
+	}
+
+	return front, nil
+}
+
+// score simulates the specimens and sets their objectives, the fitness being the first one.
+func (p *Pareto) score(specimens []Specimen, generation int) ([]Specimen, error) {
+	evo := &p.Evolution
+
+	jobs := make([]evaluationJob, len(specimens))
+	for i, specimen := range specimens {
+		jobs[i] = evaluationJob{index: i, specimen: specimen}
+	}
+
+	results, err := evo.evaluatePool(jobs, generation, func(specimen Specimen, worker int) (Specimen, error) {
+		sim, err := evo.simulate(specimen, evo.Dataset)
+		if err != nil {
+			return specimen, err
+		}
+
+		specimen.Objectives = make([]float64, len(p.Objectives))
+		for i, objective := range p.Objectives {
+			specimen.Objectives[i] = objective.Value(sim.Curve)
+		}
+		specimen.Fitness = specimen.Objectives[0]
+
+		return specimen, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scored := make([]Specimen, len(specimens))
+	for _, result := range results {
+		scored[result.index] = result.specimen
+	}
+
+	return scored, nil
+}
+
+// cost orients objective i of the specimen so that lower is better, NaN being worst of all.
+func (p *Pareto) cost(specimen Specimen, i int) float64 {
+	value := specimen.Objectives[i]
+	if math.IsNaN(value) {
+		return math.Inf(1)
+	}
+	if p.Objectives[i].Maximize {
+		return -value
+	}
+	return value
+}
+
+// dominates reports whether a is no worse than b on every objective and better on one.
+func (p *Pareto) dominates(a Specimen, b Specimen) bool {
+	better := false
+	for i := range p.Objectives {
+		costA, costB := p.cost(a, i), p.cost(b, i)
+		if costA > costB {
+			return false
+		}
+		better = better || costA < costB
+	}
+	return better
+}
+
+// fronts sorts the specimens into fronts, the first holding those no specimen dominates, the next those only the
+// first dominates and so on.
+func (p *Pareto) fronts(population []Specimen) [][]int {
+	dominatedBy := make([]int, len(population))
+	dominating := make([][]int, len(population))
+	fronts := [][]int{{}}
+
+	for i := range population {
+		for j := range population {
+			if p.dominates(population[i], population[j]) {
+				dominating[i] = append(dominating[i], j)
+			} else if p.dominates(population[j], population[i]) {
+				dominatedBy[i]++
+			}
+		}
+		if dominatedBy[i] == 0 {
+			fronts[0] = append(fronts[0], i)
+		}
+	}
+
+	for current := 0; len(fronts[current]) > 0; current++ {
+		next := make([]int, 0)
+		for _, i := range fronts[current] {
+			for _, j := range dominating[i] {
+				if dominatedBy[j]--; dominatedBy[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		sort.Ints(next)
+		fronts = append(fronts, next)
+	}
+
+	return fronts[:len(fronts)-1]
+}
+
+// crowdingDistances measures how far every specimen of a front is from its neighbours on each objective, the
+// specimens at the ends of an objective being infinitely far so they are always kept.
+func (p *Pareto) crowdingDistances(population []Specimen, front []int) map[int]float64 {
+	distances := make(map[int]float64, len(front))
+	for _, i := range front {
+		distances[i] = 0
+	}
+
+	for objective := range p.Objectives {
+		sorted := append([]int{}, front...)
+		sort.SliceStable(sorted, func(a, b int) bool {
+			return p.cost(population[sorted[a]], objective) < p.cost(population[sorted[b]], objective)
+		})
+
+		low, high := p.cost(population[sorted[0]], objective), p.cost(population[sorted[len(sorted)-1]], objective)
+		distances[sorted[0]] = math.Inf(1)
+		distances[sorted[len(sorted)-1]] = math.Inf(1)
+
+		if high == low || math.IsInf(high-low, 0) {
+			continue
+		}
+
+		for k := 1; k < len(sorted)-1; k++ {
+			gap := p.cost(population[sorted[k+1]], objective) - p.cost(population[sorted[k-1]], objective)
+			distances[sorted[k]] += gap / (high - low)
+		}
+	}
+
+	return distances
+}
+
+// rank gives the front and the crowding distance of every specimen.
+func (p *Pareto) rank(population []Specimen) ([]int, []float64) {
+	ranks := make([]int, len(population))
+	crowding := make([]float64, len(population))
+
+	for rank, front := range p.fronts(population) {
+		for i, distance := range p.crowdingDistances(population, front) {
+			ranks[i] = rank
+			crowding[i] = distance
+		}
+	}
+
+	return ranks, crowding
+}
+
+// survivors keeps the generation size best of parents and children, whole fronts first and the most spread specimens
+// of the front that doesn't fit.
+func (p *Pareto) survivors(combined []Specimen) []Specimen {
+	size := p.Evolution.GenerationSize
+	survivors := make([]Specimen, 0, size)
+
+	for _, front := range p.fronts(combined) {
+		if len(survivors)+len(front) > size {
+			distances := p.crowdingDistances(combined, front)
+			sort.SliceStable(front, func(a, b int) bool {
+				return distances[front[a]] > distances[front[b]]
+			})
+			front = front[:size-len(survivors)]
+		}
+
+		for _, i := range front {
+			survivors = append(survivors, combined[i])
+		}
+		if len(survivors) == size {
+			break
+		}
+	}
+
+	return survivors
+}
+
+// breed makes a child per specimen from parents picked by binary tournaments on front and crowding.
+func (p *Pareto) breed(population []Specimen, ranks []int, crowding []float64, generation int,
+	random *rand.Rand) []Specimen {
+	evo := &p.Evolution
+
+	tournament := func() Specimen {
+		a, b := random.Intn(len(population)), random.Intn(len(population))
+		if ranks[b] < ranks[a] || ranks[b] == ranks[a] && crowding[b] > crowding[a] {
+			a = b
+		}
+		return population[a]
+	}
+
+	children := make([]Specimen, len(population))
+	for i := range children {
+		parents := make([]Specimen, evo.Parents)
+		for j := range parents {
+			parents[j] = tournament()
+		}
+		children[i] = evo.child(parents, generation, random)
+	}
+
+	return children
+}
+
+// front is the first front of the population without duplicate configs, ordered by the first objective from the best.
+func (p *Pareto) front(population []Specimen, ranks []int) []Specimen {
+	front := make([]Specimen, 0)
+	seen := make(map[string]bool)
+
+	for i, specimen := range population {
+		key := fmt.Sprint(specimen.Config.ToSlice())
+		if ranks[i] == 0 && !seen[key] {
+			seen[key] = true
+			front = append(front, specimen)
+		}
+	}
+
+	sort.SliceStable(front, func(a, b int) bool {
+		return p.cost(front[a], 0) < p.cost(front[b], 0)
+	})
+
+	return front
+}
+
+func (p *Pareto) logFront(generation int, front []Specimen) {
+	ranges := make([]string, len(p.Objectives))
+	for i, objective := range p.Objectives {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, specimen := range front {
+			low, high = math.Min(low, specimen.Objectives[i]), math.Max(high, specimen.Objectives[i])
+		}
+		ranges[i] = fmt.Sprintf("%s %.4f..%.4f", objective.Name, low, high)
+	}
+
+	log.Printf("Generation %d front of %d: %s", generation, len(front), strings.Join(ranges, ", "))
+}
+
+// save writes a row per config of the front with its objectives and params, named by the schema.
+func (p *Pareto) save(front []Specimen) error {
+	columns := make([]export.Column, 0)
+	for _, objective := range p.Objectives {
+		columns = append(columns, export.Column{Name: objective.Name, Type: export.Float})
+	}
+	for _, name := range p.Evolution.factory.NewConfig().Schema().Names() {
+		columns = append(columns, export.Column{Name: name, Type: export.Float})
+	}
+
+	table, err := export.CreateTable(p.FrontFile, export.FormatOf(p.FrontFile), columns)
+	if err != nil {
+		return err
+	}
+
+	for _, specimen := range front {
+		row := make([]interface{}, 0, len(columns))
+		for _, value := range specimen.Objectives {
+			row = append(row, value)
+		}
+		for _, param := range specimen.Config.ToSlice() {
+			row = append(row, param)
+		}
+
+		if err := table.WriteRow(row); err != nil {
+			table.Close()
+			return err
+		}
+	}
+
+	return table.Close()
+}
@@ -0,0 +1,105 @@
+package trader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParetoFronts(t *testing.T) {
+	objectives, err := ParseObjectives("return, max_drawdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Pareto{Objectives: objectives}
+
+	population := []Specimen{
+		{Objectives: []float64{0.1, 0.05}},
+		{Objectives: []float64{0.3, 0.2}},
+		{Objectives: []float64{0.2, 0.1}},
+		{Objectives: []float64{0.1, 0.2}},
+		{Objectives: []float64{math.NaN(), 0.01}},
+	}
+
+	if !p.dominates(population[2], population[3]) || p.dominates(population[0], population[2]) ||
+		p.dominates(population[3], population[3]) {
+		t.Error("Incorrect dominance")
+	}
+
+	fronts := p.fronts(population)
+	if fmt.Sprint(fronts) != "[[0 1 2 4] [3]]" {
+		t.Error("Incorrect fronts ", fronts)
+	}
+
+	distances := p.crowdingDistances(population, fronts[0])
+	if !math.IsInf(distances[1], 1) || !math.IsInf(distances[4], 1) || math.IsInf(distances[2], 1) ||
+		distances[2] <= 0 {
+		t.Error("Expected the ends of the front to be kept ", distances)
+	}
+
+	if _, err := ParseObjectives("return"); err == nil {
+		t.Error("Expected a single objective to fail")
+	}
+	if _, err := ParseObjectives("return,luck"); err == nil {
+		t.Error("Expected an unknown objective to fail")
+	}
+}
+
+func TestParetoRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pareto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	objectives, _ := ParseObjectives("return,max_drawdown,trades")
+	p := Pareto{
+		Evolution: Evolution{
+			Dataset:        testDataset(),
+			InitialBalance: decimal.NewFromInt(1000),
+			Fee:            decimal.NewFromFloat(0.001),
+			GenerationSize: 6,
+			NumGenerations: 3,
+			MutationRate:   0.5,
+			StrategyName:   "basic",
+			Seed:           5,
+		},
+		Objectives: objectives,
+		FrontFile:  filepath.Join(dir, "pareto.csv"),
+	}
+
+	front, err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(front) == 0 || len(front) > 6 {
+		t.Fatal("Incorrect front size ", len(front))
+	}
+
+	for _, a := range front {
+		for _, b := range front {
+			if p.dominates(a, b) {
+				t.Error("Expected no config of the front to dominate another ", a.Objectives, b.Objectives)
+			}
+		}
+	}
+
+	file, err := os.Open(p.FrontFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(front)+1 || rows[0][0] != "return" || rows[0][2] != "trades" || rows[0][3] != "BuyPred5Mod" {
+		t.Error("Incorrect front file ", rows)
+	}
+}
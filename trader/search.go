@@ -0,0 +1,470 @@
+package trader
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"scoing-trader/trader/fitness"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	GridSearch   = "grid"
+	RandomSearch = "random"
+)
+
+// maxGridSize bounds the number of configs of a grid search, the grid grows as the steps to the power of the params.
+const maxGridSize = 1000000
+
+// searchMetrics are the report metrics kept with every search result.
+var searchMetrics = []string{"final_net_worth", "total_return", "annual_return", "sharpe", "sortino", "max_drawdown",
+	"trades", "win_rate"}
+
+// Search scores configs spread over the param ranges of the strategy, the points of a grid with Steps values per param
+// or Samples random draws, on the dataset of the evolution with its fitness. Grid points and draws follow the schema of
+// the strategy, by order of magnitude for log scale params and snapped to the steps of the others. Only the Params
+// given are varied, all of them when empty, the others keeping their value from the starting point (their default
+// without one) so the fitness can be traced to single params. Results are appended to ResultsFile as they come in,
+// and a search finding the results of an interrupted one there only runs the configs still missing.
+type Search struct {
+	Evolution   Evolution
+	Method      string
+	Steps       int
+	Samples     int
+	Params      []int
+	Workers     int
+	Seed        int64
+	ResultsFile string
+}
+
+// SearchResult is the outcome of the config at Index in the search order.
+type SearchResult struct {
+	Index   int
+	Config  []float64
+	Fitness float64
+	Metrics map[string]float64
+}
+
+type searchOutcome struct {
+	result SearchResult
+	err    error
+}
+
+func (s *Search) Run() ([]SearchResult, error) {
+	if err := s.Evolution.setup(); err != nil {
+		return nil, err
+	}
+	if len(s.Evolution.Remote) > 0 {
+		return nil, errors.New("remote workers only score a single fitness, searches run locally")
+	}
+
+	size, point, err := s.points()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.load(size, point)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool, len(results))
+	for _, result := range results {
+		done[result.Index] = true
+	}
+	if len(done) > 0 {
+		log.Printf("Resuming search with %d of %d configs done", len(done), size)
+	}
+
+	file, writer, err := s.openResults()
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		defer file.Close()
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan int)
+	outcomes := make(chan searchOutcome)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for index := 0; index < size; index++ {
+			if done[index] {
+				continue
+			}
+			select {
+			case jobs <- index:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				result, err := s.evaluate(index, point(index))
+				outcomes <- searchOutcome{result: result, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	var searchErr error
+	for outcome := range outcomes {
+		if searchErr == nil && outcome.err == nil {
+			outcome.err = writeSearchResult(writer, outcome.result)
+		}
+		if outcome.err != nil {
+			if searchErr == nil {
+				searchErr = outcome.err
+				close(stop)
+			}
+			continue
+		}
+
+		results = append(results, outcome.result)
+		log.Printf("Search %d/%d config %d Fitness: %.4f", len(results), size, outcome.result.Index,
+			outcome.result.Fitness)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+
+	return results, searchErr
+}
+
+// points returns the number of configs searched and the config at every index of the search.
+func (s *Search) points() (int, func(index int) []float64, error) {
+	schema := s.Evolution.factory.NewConfig().Schema()
+
+	start := schema.Defaults()
+	if s.Evolution.StartingPoint != nil {
+		copy(start, s.Evolution.StartingPoint)
+	}
+
+	params := s.Params
+	if len(params) == 0 {
+		params = make([]int, len(schema))
+		for i := range params {
+			params[i] = i
+		}
+	}
+	for _, param := range params {
+		if param < 0 || param >= len(schema) {
+			return 0, nil, errors.New(fmt.Sprintf("%s has no param %d", s.Evolution.StrategyName, param))
+		}
+	}
+
+	switch s.Method {
+	case GridSearch:
+		if s.Steps < 2 {
+			return 0, nil, errors.New("grid search needs at least 2 steps per param")
+		}
+
+		size := 1
+		for range params {
+			size *= s.Steps
+			if size > maxGridSize {
+				return 0, nil, errors.New(fmt.Sprintf("grid of %d steps over %d params exceeds %d configs, vary fewer "+
+					"params", s.Steps, len(params), maxGridSize))
+			}
+		}
+
+		return size, func(index int) []float64 {
+			unit := schema.ToUnit(start)
+			for _, param := range params {
+				step := index % s.Steps
+				index /= s.Steps
+				unit[param] = float64(step) / float64(s.Steps-1)
+			}
+			return vary(start, schema.FromUnit(unit), params)
+		}, nil
+
+	case RandomSearch:
+		if s.Samples < 1 {
+			return 0, nil, errors.New("random search needs at least 1 sample")
+		}
+
+		// Every draw has its own seed so the configs of a resumed search are the same whatever ran before.
+		return s.Samples, func(index int) []float64 {
+			random := rand.New(rand.NewSource(s.Seed + int64(index)))
+			unit := schema.ToUnit(start)
+			for _, param := range params {
+				unit[param] = random.Float64()
+			}
+			return vary(start, schema.FromUnit(unit), params)
+		}, nil
+
+	default:
+		return 0, nil, errors.New(fmt.Sprintf("unknown search method %q, expected %s or %s", s.Method, GridSearch,
+			RandomSearch))
+	}
+}
+
+// vary is the start config with the params taken from point.
+func vary(start []float64, point []float64, params []int) []float64 {
+	config := append([]float64{}, start...)
+	for _, param := range params {
+		config[param] = point[param]
+	}
+	return config
+}
+
+func (s *Search) evaluate(index int, config []float64) (SearchResult, error) {
+	specimen := Specimen{Config: s.Evolution.factory.NewConfig()}
+	specimen.Config.FromSlice(config)
+
+	sim, err := s.Evolution.simulate(specimen, s.Evolution.Dataset)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	report := sim.Report()
+	return SearchResult{
+		Index:   index,
+		Config:  config,
+		Fitness: s.Evolution.Fitness.Score(sim.Curve),
+		Metrics: map[string]float64{
+			"final_net_worth": report.FinalNetWorth,
+			"total_return":    report.TotalReturn,
+			"annual_return":   report.AnnualReturn,
+			"sharpe":          report.Sharpe,
+			"sortino":         report.Sortino,
+			"max_drawdown":    report.MaxDrawdown,
+			"trades":          float64(report.Trades),
+			"win_rate":        report.WinRate,
+		},
+	}, nil
+}
+
+func searchHeader(names []string) []string {
+	header := append([]string{"index"}, names...)
+	header = append(header, "fitness")
+	return append(header, searchMetrics...)
+}
+
+// load reads the results already in the results file, checking they were searched with the same configs.
+func (s *Search) load(size int, point func(index int) []float64) ([]SearchResult, error) {
+	results := make([]SearchResult, 0)
+	if s.ResultsFile == "" {
+		return results, nil
+	}
+
+	file, err := os.Open(s.ResultsFile)
+	if os.IsNotExist(err) {
+		return results, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	numParams := len(point(0))
+	header := searchHeader(s.Evolution.factory.NewConfig().Schema().Names())
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	first, err := reader.Read()
+	if err == io.EOF {
+		return results, nil
+	} else if err != nil || fmt.Sprint(first) != fmt.Sprint(header) {
+		return nil, errors.New(fmt.Sprintf("%s doesn't hold results of a %s search", s.ResultsFile,
+			s.Evolution.StrategyName))
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		// A row cut short by an interruption is run again.
+		if len(row) != len(header) {
+			continue
+		}
+
+		result, err := parseSearchResult(row, numParams)
+		if err != nil {
+			return nil, fmt.Errorf("invalid result in %s: %w", s.ResultsFile, err)
+		}
+
+		if result.Index >= size || fmt.Sprint(result.Config) != fmt.Sprint(point(result.Index)) {
+			return nil, errors.New(fmt.Sprintf("%s holds results of another search, config %d differs",
+				s.ResultsFile, result.Index))
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// openResults opens the results file for appending, writing the header to a new one. Without a results file there is
+// no file and the rows are discarded.
+func (s *Search) openResults() (*os.File, *csv.Writer, error) {
+	if s.ResultsFile == "" {
+		return nil, csv.NewWriter(ioutil.Discard), nil
+	}
+
+	file, err := os.OpenFile(s.ResultsFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	writer := csv.NewWriter(file)
+
+	if info.Size() == 0 {
+		writer.Write(searchHeader(s.Evolution.factory.NewConfig().Schema().Names()))
+		writer.Flush()
+	} else {
+		// Start on a new line should the last row have been cut short.
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte("\n"))
+		}
+	}
+
+	return file, writer, writer.Error()
+}
+
+func writeSearchResult(writer *csv.Writer, result SearchResult) error {
+	row := []string{strconv.Itoa(result.Index)}
+	for _, param := range result.Config {
+		row = append(row, strconv.FormatFloat(param, 'g', -1, 64))
+	}
+	row = append(row, strconv.FormatFloat(result.Fitness, 'g', -1, 64))
+	for _, name := range searchMetrics {
+		row = append(row, strconv.FormatFloat(result.Metrics[name], 'g', -1, 64))
+	}
+
+	writer.Write(row)
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func parseSearchResult(row []string, numParams int) (SearchResult, error) {
+	index, err := strconv.Atoi(row[0])
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	values := make([]float64, len(row)-1)
+	for i, field := range row[1:] {
+		if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return SearchResult{}, err
+		}
+	}
+
+	result := SearchResult{
+		Index:   index,
+		Config:  values[:numParams],
+		Fitness: values[numParams],
+		Metrics: make(map[string]float64),
+	}
+	for i, name := range searchMetrics {
+		result.Metrics[name] = values[numParams+1+i]
+	}
+
+	return result, nil
+}
+
+// BestSearchResult is the result with the highest fitness, the first one found on ties.
+func BestSearchResult(results []SearchResult) (SearchResult, bool) {
+	if len(results) == 0 {
+		return SearchResult{}, false
+	}
+
+	best := results[0]
+	for _, result := range results[1:] {
+		if result.Fitness > best.Fitness {
+			best = result
+		}
+	}
+
+	return best, true
+}
+
+// SensitivityBin holds the fitness of the results whose param fell within [Low, High).
+type SensitivityBin struct {
+	Low         float64
+	High        float64
+	Count       int
+	MeanFitness float64
+	BestFitness float64
+}
+
+// Sensitivity bins the results over the range of param, one bin per grid step or ten for a random search, showing how
+// the fitness moves with the param. Bins split the unit space the points are placed in, so they span orders of
+// magnitude of log scale params. Rejected results are left out.
+func (s *Search) Sensitivity(results []SearchResult, param int) []SensitivityBin {
+	schema := s.Evolution.factory.NewConfig().Schema()
+
+	numBins := 10
+	if s.Method == GridSearch {
+		numBins = s.Steps
+	}
+
+	bins := make([]SensitivityBin, numBins)
+	for i := range bins {
+		bins[i].Low = schema[param].Value(float64(i) / float64(numBins))
+		bins[i].High = schema[param].Value(float64(i+1) / float64(numBins))
+		bins[i].BestFitness = math.Inf(-1)
+	}
+
+	for _, result := range results {
+		if result.Fitness == fitness.Rejected {
+			continue
+		}
+
+		bin := int(float64(numBins) * schema.ToUnit(result.Config)[param])
+		if bin >= numBins {
+			bin = numBins - 1
+		}
+
+		bins[bin].Count++
+		bins[bin].MeanFitness += result.Fitness
+		bins[bin].BestFitness = math.Max(bins[bin].BestFitness, result.Fitness)
+	}
+
+	for i := range bins {
+		if bins[i].Count > 0 {
+			bins[i].MeanFitness /= float64(bins[i].Count)
+		}
+	}
+
+	return bins
+}
@@ -0,0 +1,163 @@
+package trader
+
+import (
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"scoing-trader/trader/model/predictor"
+	"scoing-trader/trader/model/trader"
+	"scoing-trader/trader/model/trader/strategies"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDataset() predictor.Dataset {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	predictions := make([]predictor.Prediction, 0)
+
+	for minute := 0; minute < 6*60; minute++ {
+		price := 100 + 10*math.Sin(float64(minute)/30)
+		predictions = append(predictions, predictor.Prediction{
+			Timestamp:  start.Add(time.Duration(minute) * time.Minute),
+			Coin:       "BTCUSDT",
+			CloseValue: price,
+			Pred5:      100 + 10*math.Sin(float64(minute+5)/30),
+			Pred10:     100 + 10*math.Sin(float64(minute+10)/30),
+			Pred100:    100 + 10*math.Sin(float64(minute+100)/30),
+		})
+	}
+
+	return predictor.SliceDataset(predictions)
+}
+
+func TestGridSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	search := Search{
+		Evolution: Evolution{
+			Dataset:        testDataset(),
+			InitialBalance: decimal.NewFromInt(1000),
+			Fee:            decimal.NewFromFloat(0.001),
+			StrategyName:   "basic",
+		},
+		Method:      GridSearch,
+		Steps:       3,
+		Params:      []int{0, 1},
+		Workers:     2,
+		ResultsFile: filepath.Join(dir, "search.csv"),
+	}
+
+	results, err := search.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 9 || results[8].Index != 8 {
+		t.Fatal("Expected the 9 configs of the grid, got ", len(results))
+	}
+
+	min, max := search.Evolution.factory.NewConfig().Schema().Ranges()
+	if results[5].Config[0] != max[0] || results[5].Config[1] != (min[1]+max[1])/2 || results[5].Config[2] != results[0].Config[2] {
+		t.Error("Incorrect grid point ", results[5].Config)
+	}
+
+	for _, result := range results {
+		if result.Fitness != result.Metrics["final_net_worth"] {
+			t.Error("Expected the net worth fitness by default ", result)
+		}
+	}
+
+	// Drop the last results and cut a row short as an interruption would.
+	data, _ := ioutil.ReadFile(search.ResultsFile)
+	lines := strings.SplitAfter(string(data), "\n")
+	interrupted := strings.Join(lines[:7], "") + lines[7][:5]
+	if err := ioutil.WriteFile(search.ResultsFile, []byte(interrupted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := search.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resumed) != 9 || resumed[7].Fitness != results[7].Fitness {
+		t.Error("Incorrect resumed search ", len(resumed))
+	}
+
+	data, _ = ioutil.ReadFile(search.ResultsFile)
+	if rows := strings.Count(string(data), "\n"); rows != 11 {
+		t.Error("Expected the header, 9 results and the cut row in the file, got ", rows)
+	}
+
+	bins := search.Sensitivity(resumed, 0)
+	if len(bins) != 3 || bins[0].Count != 3 || bins[2].Count != 3 {
+		t.Error("Incorrect sensitivity ", bins)
+	}
+
+	search.Steps = 4
+	if _, err := search.Run(); err == nil {
+		t.Error("Expected resuming another grid to fail")
+	}
+
+	search.Steps = 3
+	search.Evolution.Remote = []string{"http://localhost:8990"}
+	if _, err := search.Run(); err == nil {
+		t.Error("Expected a search on remote workers to fail")
+	}
+}
+
+func TestSensitivityLogScale(t *testing.T) {
+	search := Search{Method: GridSearch, Steps: 5}
+	search.Evolution.factory = strategies.Factory{NewConfig: func() trader.StrategyConfig {
+		return &logScaleConfig{}
+	}}
+
+	results := make([]SearchResult, 0)
+	for i, rate := range []float64{0.001, 0.01, 0.1, 1, 10} {
+		results = append(results, SearchResult{Index: i, Config: []float64{rate}, Fitness: float64(i)})
+	}
+
+	bins := search.Sensitivity(results, 0)
+	for i, bin := range bins {
+		if bin.Count != 1 || bin.BestFitness != float64(i) {
+			t.Error("Expected a grid point per bin ", bins)
+			break
+		}
+	}
+	if bins[0].Low != 0.001 || math.Abs(bins[4].High-10) > 1e-9 {
+		t.Error("Incorrect bin range ", bins[0].Low, bins[4].High)
+	}
+}
+
+func TestRandomSearch(t *testing.T) {
+	search := Search{Method: RandomSearch, Samples: 4, Seed: 7, Params: []int{2}}
+	search.Evolution.StrategyName = "basic"
+	if err := search.Evolution.setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	size, point, err := search.points()
+	if err != nil || size != 4 {
+		t.Fatal("Incorrect random search ", size, err)
+	}
+
+	schema := search.Evolution.factory.NewConfig().Schema()
+	first := point(1)
+	if first[2] < schema[2].Min || first[2] > schema[2].Max || first[0] != schema[0].Default || point(1)[2] != first[2] {
+		t.Error("Expected a reproducible draw of param 2 within its range, the others at their default ", first)
+	}
+
+	search.Method = GridSearch
+	search.Steps = 10
+	search.Params = nil
+	if _, _, err := search.points(); err == nil {
+		t.Error("Expected an oversized grid to fail")
+	}
+}
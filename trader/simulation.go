@@ -1,135 +1,290 @@
 package trader
 
 import (
-	"encoding/csv"
-	"fmt"
 	"github.com/shopspring/decimal"
+	"io"
 	"log"
 	"math"
-	"os"
+	"math/rand"
+	"scoing-trader/trader/export"
+	"scoing-trader/trader/metrics"
 	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
 	"scoing-trader/trader/model/trader"
-	"sort"
+	"time"
 )
 
 type Simulation struct {
-	Predictions *[]predictor.Prediction
-	Trader      trader.Trader
-	Logging     bool
+	Source     predictor.PredictionSource
+	Curve      *metrics.Curve
+	Benchmarks *metrics.Benchmarks
+	Market     *market.SimulatedMarket
+	Trader     trader.Trader
+	// Exporter receives snapshots of the account and the decisions kept in the trader records, if it keeps them.
+	Exporter *export.Exporter
+	Logging  bool
 }
 
-func NewSimulation(predictions *[]predictor.Prediction, strategy trader.Strategy, config trader.StrategyConfig, initialBalance decimal.Decimal, fee decimal.Decimal,
-	uncertainty float64, keepRecords bool, keepOnlyTransactions bool) *Simulation {
-	marketEnt := market.NewSimulatedMarket(0, fee)
-	marketEnt.Deposit("USDT", initialBalance)
-	return &Simulation{
-		Predictions: predictions,
-		Trader: *trader.NewTrader(*market.NewAccountant(marketEnt, initialBalance, fee),
+// MarketOptions set up the simulated market of a run beyond its flat fee. The balance is kept in QuoteAsset, USDT when
+// empty, so only the symbols quoted in it are traded. Maker and taker fees replace the flat fee when given. With a
+// CommissionAsset, e.g. BNB, fills pay their commission in it at CommissionDiscount off for as long as the
+// CommissionBalance deposited lasts, and in the quote asset after. UnfilledRate is the share of market orders
+// that rest on the book instead of filling at once, and resting orders wait a latency drawn between MinLatency and
+// MaxLatency before they can fill, expiring after Expiry unless it is 0.
+type MarketOptions struct {
+	QuoteAsset         string           `json:"quote_asset,omitempty"`
+	MakerFee           *decimal.Decimal `json:"maker_fee,omitempty"`
+	TakerFee           *decimal.Decimal `json:"taker_fee,omitempty"`
+	CommissionAsset    string           `json:"commission_asset,omitempty"`
+	CommissionDiscount decimal.Decimal  `json:"commission_discount"`
+	CommissionBalance  decimal.Decimal  `json:"commission_balance"`
+	UnfilledRate       float64          `json:"unfilled_rate,omitempty"`
+	MinLatency         time.Duration    `json:"min_latency,omitempty"`
+	MaxLatency         time.Duration    `json:"max_latency,omitempty"`
+	Expiry             time.Duration    `json:"expiry,omitempty"`
+}
+
+// Quote is the asset the balance is kept in.
+func (o MarketOptions) Quote() string {
+	if o.QuoteAsset == "" {
+		return model.DefaultQuoteAsset
+	}
+	return o.QuoteAsset
+}
+
+// NewMarket creates the simulated market holding balance in the quote asset and returns it with the fee the accountant
+// reserves buys with.
+func (o MarketOptions) NewMarket(balance decimal.Decimal, fee decimal.Decimal) (*market.SimulatedMarket, decimal.Decimal) {
+	marketEnt := market.NewSimulatedMarket(o.UnfilledRate, fee)
+	marketEnt.Deposit(o.Quote(), balance)
+	marketEnt.SetOrderLifecycle(market.OrderLifecycle{MinLatency: o.MinLatency, MaxLatency: o.MaxLatency,
+		Expiry: o.Expiry})
+
+	return marketEnt, o.apply(marketEnt, fee)
+}
+
+func (o MarketOptions) apply(marketEnt *market.SimulatedMarket, fee decimal.Decimal) decimal.Decimal {
+	if o.MakerFee != nil || o.TakerFee != nil {
+		maker, taker := fee, fee
+		if o.MakerFee != nil {
+			maker = *o.MakerFee
+		}
+		if o.TakerFee != nil {
+			taker = *o.TakerFee
+		}
+		marketEnt.SetCommissionRates(maker, taker)
+		// The market reserves buys with the higher of the two fees, the accountant has to reserve as much.
+		fee = decimal.Max(maker, taker)
+	}
+
+	if o.CommissionAsset != "" {
+		marketEnt.SetCommissionAsset(o.CommissionAsset, o.CommissionDiscount)
+		if o.CommissionBalance.GreaterThan(decimal.Zero) {
+			marketEnt.Deposit(o.CommissionAsset, o.CommissionBalance)
+		}
+	}
+
+	return fee
+}
+
+func NewSimulation(source predictor.PredictionSource, strategy trader.Strategy, config trader.StrategyConfig, initialBalance decimal.Decimal, fee decimal.Decimal,
+	marketOptions MarketOptions, uncertainty float64, keepRecords bool, keepOnlyTransactions bool) *Simulation {
+	marketEnt, fee := marketOptions.NewMarket(initialBalance, fee)
+	accountant := market.NewAccountant(marketEnt, initialBalance, fee)
+	accountant.QuoteAsset = marketOptions.Quote()
+	sim := &Simulation{
+		Source: source,
+		Curve:  metrics.NewCurve(),
+		Market: marketEnt,
+		Trader: *trader.NewTrader(*accountant,
 			predictor.NewSimulatedPredictor(uncertainty), strategy, keepRecords, keepOnlyTransactions),
 		Logging: keepRecords,
 	}
+	sim.Trader.Recorder = sim.Curve
+	return sim
 }
 
-func (sim *Simulation) Run() {
-	numDecisions := 0
-	var historyCoin = make(map[string]map[string][]string)
-	var historyTrader = make(map[string][]string)
+// SetSeed seeds the market fills and prediction errors of the run, runs with the same seed and inputs repeat exactly.
+func (sim *Simulation) SetSeed(seed int64) {
+	random := rand.New(rand.NewSource(seed))
+	sim.Market.SetRandom(random)
+	if simulated, ok := sim.Trader.Predictor.(*predictor.SimulatedPredictor); ok {
+		simulated.SetRandom(random)
+	}
+}
 
-	for _, pred := range *sim.Predictions {
-		err := sim.Trader.Accountant.UpdateAssetValue(pred.Coin, decimal.NewFromFloat(pred.CloseValue), pred.Timestamp)
-		if err != nil {
-			panic(err)
-		}
-		sim.Trader.Predictor.SetNextPrediction(pred)
-		sim.Trader.ProcessData(pred.Coin)
+// SetRecorder records the trades and hourly snapshots of the run in recorder as well as in the equity curve.
+func (sim *Simulation) SetRecorder(recorder trader.Recorder) {
+	if recorder == nil {
+		sim.Trader.Recorder = sim.Curve
+		return
+	}
+	sim.Trader.Recorder = trader.Recorders{sim.Curve, recorder}
+}
 
-		if sim.Logging {
-			if len(sim.Trader.Records) != numDecisions {
-				for i := int(math.Max(0, float64(numDecisions-1))); i < len(sim.Trader.Records); i++ {
-					log.Println(sim.Trader.Records[i].ToString())
-				}
+func (sim *Simulation) SetSymbolRegistry(symbols *model.SymbolRegistry) {
+	sim.Market.SetSymbolRegistry(symbols)
+	if symbolAware, ok := sim.Trader.Strategy.(trader.SymbolAware); ok {
+		symbolAware.SetSymbols(symbols)
+	}
+}
 
-				numDecisions = len(sim.Trader.Records)
+// TrackBenchmarks follows cash, buy-and-hold and an equal weight basket rebalanced every rebalance over the prices of
+// the run, their curves sampled along with the equity curve.
+func (sim *Simulation) TrackBenchmarks(rebalance time.Duration) {
+	initial, _ := sim.Trader.Accountant.InitialBalance.Float64()
+	sim.Benchmarks = metrics.NewBenchmarks(initial, rebalance)
+}
 
-				log.Println(sim.Trader.Accountant.ToString())
-			}
+// Report summarises the performance of the run from its equity curve and the costs kept by the accountant.
+func (sim *Simulation) Report() metrics.Report {
+	accountant := sim.Trader.Accountant
+	volume, _ := accountant.Volume.Float64()
+	fees, _ := accountant.FeesPaid.Float64()
 
-			if pred.Timestamp.Minute() == 0 {
-				balance, _ := sim.Trader.Accountant.GetBalance().Float64()
-				nw, _ := sim.Trader.Accountant.NetWorth().Float64()
-				historyTrader[pred.Timestamp.Format("2006-01-02 15:04:05")] = []string{fmt.Sprintf("%.4f", balance), fmt.Sprintf("%.4f", nw)}
+	commissions := make(map[string]float64)
+	for asset, commission := range accountant.Commissions {
+		commissions[asset], _ = commission.Float64()
+	}
 
-				if _, exists := historyCoin[pred.Timestamp.Format("2006-01-02 15:04:05")]; !exists {
-					historyCoin[pred.Timestamp.Format("2006-01-02 15:04:05")] = make(map[string][]string)
-				}
+	report := metrics.NewReport(sim.Curve, metrics.Costs{Volume: volume, Fees: fees, Commissions: commissions})
 
-				assetValue, _ := sim.Trader.Accountant.AssetValue(pred.Coin).Float64()
+	if sim.Benchmarks != nil {
+		for _, name := range sim.Benchmarks.Names() {
+			report.Benchmarks = append(report.Benchmarks, metrics.Compare(name, sim.Curve, sim.Benchmarks.Curves[name]))
+		}
+	}
 
-				historyCoin[pred.Timestamp.Format("2006-01-02 15:04:05")][pred.Coin] = []string{fmt.Sprintf("%.4f", pred.CloseValue),
-					fmt.Sprintf("%.4f", assetValue)}
-			}
+	return report
+}
+
+// snapshot records the account, the equity curve gaining a point along with the value held in each coin.
+func (sim *Simulation) snapshot(timestamp time.Time) {
+	sim.Trader.Snapshot(timestamp)
+
+	exposure := make(map[string]float64)
+	for coin, qty := range sim.Trader.Accountant.Assets {
+		if qty.IsPositive() {
+			exposure[coin], _ = sim.Trader.Accountant.AssetValue(coin).Float64()
 		}
+	}
+	sim.Curve.SetExposure(exposure)
 
-		sim.Trader.Accountant.SyncWithMarket()
+	if sim.Benchmarks != nil {
+		sim.Benchmarks.Snapshot(timestamp)
 	}
+}
 
-	if sim.Logging {
-		timestamp_keys := make([]string, 0, len(historyTrader))
-		for k := range historyTrader {
-			timestamp_keys = append(timestamp_keys, k)
+// Run trades every prediction of the source, which it closes once exhausted, and then closes the exporter if any.
+func (sim *Simulation) Run() error {
+	err := sim.run()
+
+	if sim.Exporter != nil {
+		if closeErr := sim.Exporter.Close(); err == nil {
+			err = closeErr
 		}
-		sort.Strings(timestamp_keys)
+	}
+
+	return err
+}
 
-		var headers = []string{"Timestamp", "Balance", "Networth"}
-		//var coinList []string
+func (sim *Simulation) run() error {
+	numDecisions := 0
+	numExported := 0
 
-		/*for coin := range history_coin[timestamp_keys[len(timestamp_keys)/2]]{
-			coinList = append(coinList,coin)
-			headers = append(headers,coin)
-			headers = append(headers,coin+"_positions")
-		}*/
+	var lastTimestamp time.Time
 
-		var coinList = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "LTCUSDT", "XRPUSDT"}
+	defer sim.Source.Close()
 
-		for _, coin := range coinList {
-			headers = append(headers, coin)
-			headers = append(headers, coin+"_positions")
+	for {
+		pred, err := sim.Source.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
 		}
 
-		var data = [][]string{headers}
+		// Every prediction of the last timestamp was traded, the account is complete for it.
+		if sim.Exporter != nil && pred.Timestamp.After(lastTimestamp) && !lastTimestamp.IsZero() {
+			if err := sim.export(lastTimestamp); err != nil {
+				return err
+			}
+		}
+
+		err = sim.Trader.Accountant.UpdateAssetValue(pred.Coin, decimal.NewFromFloat(pred.CloseValue), pred.Timestamp)
+		if err != nil {
+			panic(err)
+		}
+		if sim.Benchmarks != nil {
+			sim.Benchmarks.Observe(pred.Timestamp, pred.Coin, pred.CloseValue)
+		}
+		if len(sim.Curve.Points) == 0 {
+			sim.snapshot(pred.Timestamp)
+		}
+		sim.Trader.Predictor.SetNextPrediction(pred)
+		sim.Trader.ProcessData(pred.Coin)
+		lastTimestamp = pred.Timestamp
 
-		for _, timestamp := range timestamp_keys {
-			var entry = []string{timestamp, historyTrader[timestamp][0], historyTrader[timestamp][1]}
+		// The first point holds the initial net worth, later predictions at the same time must not replace it.
+		if pred.Timestamp.Minute() == 0 && pred.Timestamp.After(sim.Curve.Points[0].Timestamp) {
+			sim.snapshot(pred.Timestamp)
+		}
 
-			for _, coin := range coinList {
-				if _, contains := historyCoin[timestamp][coin]; contains {
-					entry = append(entry, historyCoin[timestamp][coin][0])
-					entry = append(entry, historyCoin[timestamp][coin][1])
-				} else {
-					entry = append(entry, "")
-					entry = append(entry, "")
+		if sim.Exporter != nil {
+			for ; numExported < len(sim.Trader.Records); numExported++ {
+				if err := sim.Exporter.WriteRecord(sim.Trader.Records[numExported]); err != nil {
+					return err
 				}
 			}
-
-			data = append(data, entry)
 		}
 
-		file, err := os.Create("result.csv")
-		if err != nil {
-			panic("Cannot create file")
+		if sim.Logging && len(sim.Trader.Records) != numDecisions {
+			for i := int(math.Max(0, float64(numDecisions-1))); i < len(sim.Trader.Records); i++ {
+				log.Println(sim.Trader.Records[i].ToString())
+			}
+
+			numDecisions = len(sim.Trader.Records)
+
+			log.Println(sim.Trader.Accountant.ToString())
 		}
-		defer file.Close()
 
-		writer := csv.NewWriter(file)
-		defer writer.Flush()
+		if err := sim.Trader.Accountant.SyncWithMarket(); err != nil {
+			return err
+		}
+	}
 
-		for _, value := range data {
-			err := writer.Write(value)
-			if err != nil {
-				panic("Cannot write to file")
+	if !lastTimestamp.IsZero() {
+		sim.snapshot(lastTimestamp)
+		if sim.Exporter != nil {
+			if err := sim.export(lastTimestamp); err != nil {
+				return err
 			}
 		}
 	}
 
+	return nil
+}
+
+// export hands a snapshot of the account to the exporter when one is due.
+func (sim *Simulation) export(timestamp time.Time) error {
+	if !sim.Exporter.Due(timestamp) {
+		return nil
+	}
+
+	accountant := sim.Trader.Accountant
+	snapshot := export.Snapshot{
+		Timestamp: timestamp,
+		Prices:    make(map[string]float64),
+		Values:    make(map[string]float64),
+	}
+	snapshot.Balance, _ = accountant.GetBalance().Float64()
+	snapshot.NetWorth, _ = accountant.NetWorth().Float64()
+
+	for coin, price := range accountant.AssetValues {
+		snapshot.Prices[coin], _ = price.Float64()
+		snapshot.Values[coin], _ = accountant.AssetValue(coin).Float64()
+	}
+
+	return sim.Exporter.WriteSnapshot(snapshot)
 }
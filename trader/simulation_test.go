@@ -0,0 +1,138 @@
+package trader
+
+import (
+	"github.com/shopspring/decimal"
+	"io"
+	"scoing-trader/trader/model/market/model"
+	"scoing-trader/trader/model/predictor"
+	"scoing-trader/trader/model/trader/strategies"
+	"testing"
+	"time"
+)
+
+func readPredictions(t *testing.T, dataset predictor.Dataset) []predictor.Prediction {
+	source, err := dataset.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	predictions := make([]predictor.Prediction, 0)
+	for {
+		prediction, err := source.Next()
+		if err == io.EOF {
+			return predictions
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		predictions = append(predictions, prediction)
+	}
+}
+
+// withCoin adds a coin holding its price to the predictions of dataset.
+func withCoin(t *testing.T, dataset predictor.Dataset, coin string, price float64) predictor.Dataset {
+	combined := make([]predictor.Prediction, 0)
+	for _, prediction := range readPredictions(t, dataset) {
+		combined = append(combined, predictor.Prediction{Timestamp: prediction.Timestamp, Coin: coin, CloseValue: price},
+			prediction)
+	}
+	return predictor.SliceDataset(combined)
+}
+
+func testSimulation(t *testing.T, dataset predictor.Dataset, options MarketOptions) *Simulation {
+	schema := (&strategies.BasicConfig{}).Schema()
+	strategy, config, err := strategies.New("basic", schema.Defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := dataset.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim := NewSimulation(source, strategy, config, decimal.NewFromInt(1000), decimal.NewFromFloat(0.002), options, 0,
+		false, false)
+	if err := sim.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+func TestSimulationCommissionAsset(t *testing.T) {
+	dataset := withCoin(t, testDataset(), "BNBUSDT", 20)
+	maker, taker := decimal.NewFromFloat(0.0005), decimal.NewFromFloat(0.001)
+
+	quote := testSimulation(t, dataset, MarketOptions{MakerFee: &maker, TakerFee: &taker})
+	bnb := testSimulation(t, dataset, MarketOptions{MakerFee: &maker, TakerFee: &taker, CommissionAsset: "BNB",
+		CommissionDiscount: decimal.NewFromFloat(0.25), CommissionBalance: decimal.NewFromInt(10)})
+
+	if !quote.Trader.Accountant.Fee.Equal(taker) || quote.Market.AccountInformation().TakerCommission != 10 {
+		t.Error("Expected the higher taker fee to replace the flat fee ", quote.Trader.Accountant.Fee)
+	}
+	if quote.Trader.Accountant.Volume.IsZero() || !quote.Trader.Accountant.Commissions["BNB"].IsZero() {
+		t.Fatal("Expected trades paying commission in USDT ", quote.Trader.Accountant.Commissions)
+	}
+
+	paid := bnb.Trader.Accountant.Commissions["BNB"]
+	balance, _ := bnb.Market.Balance("BNB")
+	if !paid.GreaterThan(decimal.Zero) || !bnb.Trader.Accountant.Commissions[model.DefaultQuoteAsset].IsZero() ||
+		!balance.Free.Equal(decimal.NewFromInt(10).Sub(paid)) {
+		t.Error("Expected the commissions paid out of the BNB balance ", bnb.Trader.Accountant.Commissions, balance)
+	}
+
+	if !bnb.Trader.Accountant.FeesPaid.LessThan(quote.Trader.Accountant.FeesPaid) {
+		t.Error("Expected the BNB discount to lower the fees ", bnb.Trader.Accountant.FeesPaid,
+			quote.Trader.Accountant.FeesPaid)
+	}
+}
+
+func TestSimulationQuoteAsset(t *testing.T) {
+	predictions := readPredictions(t, testDataset())
+	for i := range predictions {
+		predictions[i].Coin = "BTCBUSD"
+	}
+	dataset := predictor.SliceDataset(predictions)
+
+	busd := testSimulation(t, dataset, MarketOptions{QuoteAsset: "BUSD"})
+	balance, _ := busd.Market.Balance("BUSD")
+	if busd.Trader.Accountant.QuoteAsset != "BUSD" || busd.Trader.Accountant.Volume.IsZero() ||
+		!balance.Free.Add(balance.Locked).Equal(busd.Trader.Accountant.Balance) {
+		t.Error("Expected BTCBUSD traded out of a BUSD balance ", busd.Trader.Accountant.Volume, balance)
+	}
+
+	usdt := testSimulation(t, dataset, MarketOptions{})
+	if !usdt.Trader.Accountant.Volume.IsZero() {
+		t.Error("Expected BTCBUSD left alone with a USDT balance")
+	}
+}
+
+func TestSimulationOrderLifecycle(t *testing.T) {
+	filled := testSimulation(t, testDataset(), MarketOptions{UnfilledRate: 1, MinLatency: time.Minute,
+		MaxLatency: time.Minute})
+	if filled.Trader.Accountant.Volume.IsZero() {
+		t.Error("Expected resting market orders to fill after their latency")
+	}
+
+	expired := testSimulation(t, testDataset(), MarketOptions{UnfilledRate: 1, MinLatency: time.Hour,
+		MaxLatency: 2 * time.Hour, Expiry: time.Minute})
+	if !expired.Trader.Accountant.Volume.IsZero() {
+		t.Error("Expected resting market orders to expire before their latency ", expired.Trader.Accountant.Volume)
+	}
+}
+
+func TestSimulationMakerFeeAboveTaker(t *testing.T) {
+	maker, taker := decimal.NewFromFloat(0.004), decimal.NewFromFloat(0.001)
+
+	sim := testSimulation(t, testDataset(), MarketOptions{MakerFee: &maker, TakerFee: &taker, UnfilledRate: 1,
+		MinLatency: time.Minute, MaxLatency: time.Minute})
+
+	balance, _ := sim.Market.Balance(model.DefaultQuoteAsset)
+	if sim.Trader.Accountant.Volume.IsZero() || !sim.Trader.Accountant.Balance.Equal(balance.Free) {
+		t.Error("Expected the resting orders settled with the balances in sync ", sim.Trader.Accountant.Balance,
+			balance.Free)
+	}
+	if !sim.Trader.Accountant.Fee.Equal(maker) {
+		t.Error("Expected the accountant to reserve the higher maker fee ", sim.Trader.Accountant.Fee)
+	}
+}
@@ -2,25 +2,138 @@ package trader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
+	"scoing-trader/trader/db"
+	"scoing-trader/trader/export"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/metrics"
+	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/market/model"
 	"scoing-trader/trader/model/predictor"
+	"scoing-trader/trader/model/trader"
 	"scoing-trader/trader/model/trader/strategies"
 	"strconv"
+	"strings"
 	"time"
 )
 
-var predictions []predictor.Prediction
+// aggregatorPageSize bounds the time range of a single request to the aggregator.
+const aggregatorPageSize = 7 * 24 * time.Hour
+
+// benchmarkRebalance is how often the equal weight benchmark of backtests is brought back to equal weights.
+const benchmarkRebalance = 24 * time.Hour
+
+// DataOptions describes where the predictions of a run come from. With a DataDir the predictions are cached there and
+// only the time not yet synced is requested from the aggregator, Offline never contacts it.
+type DataOptions struct {
+	Start    time.Time
+	End      time.Time
+	UseModel bool
+	Host     string
+	Port     string
+	DataDir  string
+	Offline  bool
+}
+
+// SetupEnvironment loads the predictions of the time range, or opens them in the data directory.
+func SetupEnvironment(options DataOptions) (predictor.Dataset, error) {
+	var dataset predictor.Dataset
+
+	if options.DataDir == "" {
+		if options.Offline {
+			return nil, errors.New("offline runs need a prediction data directory")
+		}
+
+		// Without a store every run would request the predictions again, keep them in memory instead.
+		endpoint := aggregatorEndpoint(options)
+		loaded, err := predictor.Collect(predictor.NewPagedSource(func(start time.Time, end time.Time) ([]predictor.Prediction, error) {
+			return TrainingData(endpoint, start, end, options.UseModel)
+		}, options.Start, options.End, aggregatorPageSize))
+		if err != nil {
+			return nil, err
+		}
+		dataset = predictor.SliceDataset(loaded)
+	} else {
+		store, err := SyncPredictions(options)
+		if err != nil {
+			return nil, err
+		}
+
+		if missing := store.Missing(options.Start, options.End); len(missing) > 0 {
+			return nil, errors.New(fmt.Sprintf("predictions from %s are not in %s, sync them before running offline",
+				missing[0], store.Dir))
+		}
+
+		dataset = store.Dataset(options.Start, options.End)
+		log.Printf("Streaming predictions from %s", store.Dir)
+	}
 
-func SetupEnvironment(startTime time.Time, endTime time.Time, useModel bool, host string, port string) {
-	predictions = TrainingData("http://"+host+":"+port+"/aggregator/trader/*", startTime, endTime, useModel)
 	log.Println("Locked and Loaded")
+
+	return dataset, nil
+}
+
+// SyncPredictions opens the prediction store of the data directory and, unless offline, fetches the time it is missing
+// from the aggregator. Model predictions and plain history are kept apart.
+func SyncPredictions(options DataOptions) (*predictor.FileStore, error) {
+	kind := "history"
+	if options.UseModel {
+		kind = "model"
+	}
+
+	store, err := predictor.OpenFileStore(filepath.Join(options.DataDir, kind))
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Offline {
+		return store, nil
+	}
+
+	endpoint := aggregatorEndpoint(options)
+	fetched := 0
+	for _, gap := range store.Missing(options.Start, options.End) {
+		for start := gap.Start; start.Before(gap.End); start = start.Add(aggregatorPageSize) {
+			end := start.Add(aggregatorPageSize)
+			if end.After(gap.End) {
+				end = gap.End
+			}
+
+			synced, err := store.Sync(start, end, func(start time.Time, end time.Time) ([]predictor.Prediction, error) {
+				return TrainingData(endpoint, start, end, options.UseModel)
+			})
+			fetched += synced
+			if err != nil {
+				return store, err
+			}
+		}
+	}
+	log.Printf("Synced %d predictions into %s", fetched, store.Dir)
+
+	return store, nil
+}
+
+func aggregatorEndpoint(options DataOptions) string {
+	return "http://" + options.Host + ":" + options.Port + "/aggregator/trader/*"
 }
 
-func TrainingData(serverEndpoint string, startTime time.Time, endTime time.Time, use_model bool) []predictor.Prediction {
+func LoadSymbols(path string) (*model.SymbolRegistry, error) {
+	registry, err := model.LoadSymbolRegistry(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded %d symbols from %s", len(registry.Symbols()), path)
+	return registry, nil
+}
+
+func TrainingData(serverEndpoint string, startTime time.Time, endTime time.Time, use_model bool) ([]predictor.Prediction, error) {
 	client := http.Client{Timeout: 120 * time.Second}
 
 	var predictions []predictor.Prediction
@@ -28,7 +141,7 @@ func TrainingData(serverEndpoint string, startTime time.Time, endTime time.Time,
 	req, err := http.NewRequest("GET", serverEndpoint, nil)
 
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	req.Header.Set("start_time", fmt.Sprint(startTime.Unix()))
@@ -38,118 +151,428 @@ func TrainingData(serverEndpoint string, startTime time.Time, endTime time.Time,
 	resp, err := client.Do(req)
 
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
+	defer resp.Body.Close()
+
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		panic("Request Failed : " + strconv.Itoa(resp.StatusCode))
+		return nil, errors.New("Request Failed : " + strconv.Itoa(resp.StatusCode))
 	}
 
-	defer resp.Body.Close()
-
 	err = json.NewDecoder(resp.Body).Decode(&predictions)
 
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	log.Printf("Obtained %d predictions from server...", len(predictions))
 
-	return predictions
+	return predictions, nil
 }
 
-func RunSingleSim() {
-	conf := strategies.BasicWithMemoryConfig{
-		BuyPred5Mod:    1.2079495905208983,
-		BuyPred10Mod:   1.2314340651251743,
-		BuyPred100Mod:  2.639287803446922,
-		SellPred5Mod:   0.7310100033627728,
-		SellPred10Mod:  2.4236266048303667,
-		SellPred100Mod: 0.971248749628451,
-		StopLoss:       -0.24754584132282575,
-		ProfitCap:      0.09154362564165196,
-		BuyQtyMod:      0.4571151261645299,
-		SellQtyMod:     0.4373028907049203,
-		SegTh:          0.02140330866341518,
-		HistSegTh:      0.06870754931936505,
-	}
+// RunOptions describes a backtest, replay or evolution run over the predictions of Dataset, traded with the Symbols and
+// FillPriceModel when given and recorded in the Repository when set. Evolutions with a TrainWindow roll walk forward
+// splits over the time range, otherwise it is split once by the fractions.
+type RunOptions struct {
+	Dataset            predictor.Dataset
+	Symbols            *model.SymbolRegistry
+	FillPriceModel     market.FillPriceModel
+	Repository         db.Repository
+	Config             *trader.NamedConfig
+	ConfigOutput       string
+	Start              time.Time
+	End                time.Time
+	InitialBalance     decimal.Decimal
+	Fee                decimal.Decimal
+	Market             MarketOptions
+	LogFile            string
+	Export             export.Config
+	ReportFile         string
+	HTMLFile           string
+	GenerationSize     int
+	NumGenerations     int
+	MutationRate       float64
+	Selection          Selection
+	Crossover          Crossover
+	Mutation           Mutation
+	Parents            int
+	Elitism            int
+	MinDiversity       float64
+	Fitness            fitness.Fitness
+	FitnessSpec        string
+	ValidationFraction float64
+	TestFraction       float64
+	TrainWindow        time.Duration
+	ValidationWindow   time.Duration
+	TestWindow         time.Duration
+	SearchMethod       string
+	GridSteps          int
+	Samples            int
+	SearchParams       []int
+	Workers            int
+	Remote             []string
+	Objectives         []Objective
+	FrontFile          string
+	Seed               int64
+	SearchResults      string
+	CheckpointFile     string
+	HistoryFile        string
+	Resume             bool
+}
 
-	var _, err = os.Stat("trader.log")
-	if !os.IsNotExist(err) {
-		os.Remove("trader.log")
+func (o RunOptions) Splits() ([]Split, error) {
+	if o.TrainWindow > 0 {
+		return WalkForwardSplits(o.Start, o.End, o.TrainWindow, o.ValidationWindow, o.TestWindow)
 	}
-	logFile, err := os.OpenFile("trader.log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+
+	split, err := HoldoutSplit(o.Start, o.End, o.ValidationFraction, o.TestFraction)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	log.SetOutput(logFile)
 
-	strategy := strategies.NewBasicWithMemoryStrategy(conf.ToSlice(), 10)
-	simulation := NewSimulation(&predictions, strategy, &conf, decimal.NewFromInt(1000), decimal.NewFromFloat(0.001), 0, true, false)
-	simulation.Run()
+	return []Split{split}, nil
+}
+
+func RunSingleSim(options RunOptions) (*Simulation, error) {
+	return runSimulation(options, nil)
+}
+
+// RunReplay runs the strategy over the loaded predictions as the live trader would, logging every decision and
+// recording trades and hourly snapshots in the repository when one is set.
+func RunReplay(options RunOptions) (*Simulation, error) {
+	var recorder trader.Recorder
+	if options.Repository != nil {
+		recorder = db.NewEventRecorder(options.Repository)
+	}
+
+	return runSimulation(options, recorder)
+}
+
+func runSimulation(options RunOptions, recorder trader.Recorder) (*Simulation, error) {
+	strategy, config, err := strategies.FromConfig(options.Config)
+	if err != nil {
+		return nil, err
+	}
 
-	fmt.Println(simulation.Trader.Accountant.NetWorth().String() + "$")
+	restoreLog, err := redirectLog(options.LogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer restoreLog()
 
-	/*for i := 0; i < 5; i++ {
-		simulation := NewSimulation(&predictions, strategy, &conf, decimal.NewFromInt(1000), decimal.NewFromFloat(0.001), 0, true)
-		simulation.Run()
+	source, err := options.Dataset.Open()
+	if err != nil {
+		return nil, err
+	}
 
-		fmt.Println(simulation.Trader.Accountant.NetWorth().String() + "$")
+	simulation := NewSimulation(source, strategy, config, options.InitialBalance, options.Fee,
+		options.Market, 0, true, false)
+	simulation.SetSeed(options.Seed)
+	if simulation.Exporter, err = newExporter(options, options.Dataset); err != nil {
+		source.Close()
+		return nil, err
+	}
+	simulation.SetRecorder(recorder)
+	simulation.TrackBenchmarks(benchmarkRebalance)
+	if options.Symbols != nil {
+		simulation.SetSymbolRegistry(options.Symbols)
+	}
+	if options.FillPriceModel != nil {
+		simulation.Market.SetFillPriceModel(options.FillPriceModel)
 	}
-	conf2 := strategies.BasicConfig{
-		BuyPred5Mod:    1.5940533413689444,
-		BuyPred10Mod:   1.6265337296196787,
-		BuyPred100Mod:  2.6448109927782526,
-		SellPred5Mod:   1.1962528097006584,
-		SellPred10Mod:  2.250716035097317,
-		SellPred100Mod: 2.9358504210266423,
-		StopLoss:       -0.003961030174404023,
-		ProfitCap:      0.010777727359352375,
-		BuyQtyMod:      0.7042771619721528,
-		SellQtyMod:     0.9751410320690478,
+
+	if err := simulation.Run(); err != nil {
+		return simulation, err
 	}
-	strategy2 := strategies.NewBasicStrategy(conf2.ToSlice())
-	simulation2 := NewSimulation(&predictions, strategy2, &conf2, decimal.NewFromInt(1000), decimal.NewFromFloat(0.001), 0, true)
-	simulation2.Run()
 
-	fmt.Println(simulation2.Trader.Accountant.NetWorth().String() + "$  <--- Sem MEM")*/
+	return simulation, saveReport(options, simulation)
 }
 
-func RunEvolution() {
-	evo := Evolution{
-		Predictions:    predictions,
-		InitialBalance: decimal.NewFromInt(1000),
-		Fee:            decimal.NewFromFloat(0.001),
-		Uncertainty:    0,
-		GenerationSize: 200,
-		NumGenerations: 10,
-		MutationRate:   0.4,
-		StartingPoint: []float64{0.9668821395093679, 2.7138169720897705, 2.639287803446922, 1.0940385580770726,
-			1.6007641962561916, 0.8169274098545057, -0.22309816719590436, 0.21838016983605293, 0.44771076616692107,
-			0.4373028907049203, 0.02140330866341518, 0.16750974746124225},
+// RunEvolution evolves a config on every split of the time range, saving the one of the last split, and replays it with
+// full logging over the last test window.
+func RunEvolution(options RunOptions) ([]Fold, error) {
+	_, startingPoint, err := strategies.FromConfig(options.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	splits, err := options.Splits()
+	if err != nil {
+		return nil, err
+	}
+
+	walkForward := WalkForward{
+		Evolution: Evolution{
+			InitialBalance: options.InitialBalance,
+			Fee:            options.Fee,
+			Market:         options.Market,
+			Uncertainty:    0,
+			GenerationSize: options.GenerationSize,
+			NumGenerations: options.NumGenerations,
+			MutationRate:   options.MutationRate,
+			Selection:      options.Selection,
+			Crossover:      options.Crossover,
+			Mutation:       options.Mutation,
+			Parents:        options.Parents,
+			Elitism:        options.Elitism,
+			MinDiversity:   options.MinDiversity,
+			Fitness:        options.Fitness,
+			FitnessSpec:    options.FitnessSpec,
+			StrategyName:   strategies.CanonicalName(options.Config.Strategy),
+			Symbols:        options.Symbols,
+			FillPriceModel: options.FillPriceModel,
+			Repository:     options.Repository,
+			Workers:        options.Workers,
+			Remote:         options.Remote,
+			StartingPoint:  startingPoint.ToSlice(),
+			Seed:           options.Seed,
+			CheckpointFile: options.CheckpointFile,
+			HistoryFile:    options.HistoryFile,
+			Resume:         options.Resume,
+		},
+		Dataset: options.Dataset,
+		Splits:  splits,
 	}
 
 	log.Println("Starting Evo...")
 
-	result := evo.Run()
+	folds, err := walkForward.Run()
+	if err != nil {
+		return folds, err
+	}
+
+	last := folds[len(folds)-1]
+	result := last.Best
 
 	log.Println(result.Fitness)
 	log.Println(result.Config.ToSlice())
-	log.Println("Running single to validate...")
 
-	var _, err = os.Stat("trader.log")
-	if !os.IsNotExist(err) {
-		os.Remove("trader.log")
+	if options.ConfigOutput != "" {
+		if err := saveEvolved(options, last); err != nil {
+			return folds, err
+		}
+		log.Println("Saved evolved config to " + options.ConfigOutput)
 	}
-	logFile, err := os.OpenFile("trader.log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+
+	restoreLog, err := redirectLog(options.LogFile)
 	if err != nil {
-		panic(err)
+		return folds, err
 	}
-	log.SetOutput(logFile)
+	defer restoreLog()
 
-	strategy := strategies.NewBasicWithMemoryStrategy(result.Config.ToSlice(), 5)
-	simulation := NewSimulation(&predictions, strategy, result.Config, decimal.NewFromInt(1000), decimal.NewFromFloat(0.001), 0, true, false)
-	simulation.Run()
+	validationRange := last.Split.Test
+	if validationRange.Empty() {
+		log.Println("No test window, validating on the training predictions...")
+		validationRange = last.Split.Train
+	} else {
+		log.Println("Running single on the test window to validate...")
+	}
+
+	strategy, _, err := strategies.New(walkForward.Evolution.StrategyName, result.Config.ToSlice())
+	if err != nil {
+		return folds, err
+	}
+	validationData := window(options.Dataset, validationRange)
+	source, err := validationData.Open()
+	if err != nil {
+		return folds, err
+	}
+
+	simulation := NewSimulation(source, strategy, result.Config, options.InitialBalance, options.Fee,
+		options.Market, 0, true, false)
+	simulation.SetSeed(options.Seed)
+	if simulation.Exporter, err = newExporter(options, validationData); err != nil {
+		source.Close()
+		return folds, err
+	}
+	simulation.TrackBenchmarks(benchmarkRebalance)
+	if options.Symbols != nil {
+		simulation.SetSymbolRegistry(options.Symbols)
+	}
+	if options.FillPriceModel != nil {
+		simulation.Market.SetFillPriceModel(options.FillPriceModel)
+	}
+	if err := simulation.Run(); err != nil {
+		return folds, err
+	}
 
 	log.Println(simulation.Trader.Accountant.NetWorth())
+	log.Print("\n" + simulation.Report().String())
+
+	return folds, saveReport(options, simulation)
+}
+
+// RunSearch scores the configs of a grid or random search over the whole time range, the params not searched keeping
+// their value from the strategy config.
+func RunSearch(options RunOptions) (*Search, []SearchResult, error) {
+	_, startingPoint, err := strategies.FromConfig(options.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	search := &Search{
+		Evolution: Evolution{
+			Dataset:        options.Dataset,
+			InitialBalance: options.InitialBalance,
+			Fee:            options.Fee,
+			Market:         options.Market,
+			Fitness:        options.Fitness,
+			StrategyName:   strategies.CanonicalName(options.Config.Strategy),
+			Symbols:        options.Symbols,
+			FillPriceModel: options.FillPriceModel,
+			StartingPoint:  startingPoint.ToSlice(),
+			Remote:         options.Remote,
+			Seed:           options.Seed,
+		},
+		Method:      options.SearchMethod,
+		Steps:       options.GridSteps,
+		Samples:     options.Samples,
+		Params:      options.SearchParams,
+		Workers:     options.Workers,
+		Seed:        options.Seed,
+		ResultsFile: options.SearchResults,
+	}
+
+	log.Printf("Starting %s search...", options.SearchMethod)
+
+	results, err := search.Run()
+	return search, results, err
+}
+
+// newExporter opens the export of the run over dataset, nil when it has no path. The equity table gets a column for
+// every coin of the dataset.
+func newExporter(options RunOptions, dataset predictor.Dataset) (*export.Exporter, error) {
+	if options.Export.Path == "" {
+		return nil, nil
+	}
+
+	if options.Export.Exports(export.EquityTable) {
+		coins, err := predictor.Coins(dataset)
+		if err != nil {
+			return nil, err
+		}
+		options.Export.Coins = coins
+	}
+	return export.New(options.Export)
+}
+
+func saveReport(options RunOptions, simulation *Simulation) error {
+	if options.ReportFile == "" && options.HTMLFile == "" {
+		return nil
+	}
+
+	report := simulation.Report()
+
+	if options.ReportFile != "" {
+		if err := report.Save(options.ReportFile); err != nil {
+			return err
+		}
+	}
+
+	if options.HTMLFile != "" {
+		page := metrics.HTMLReport{
+			Title: fmt.Sprintf("%s %s - %s", options.Config.Strategy, report.Start.UTC().Format("2006-01-02"),
+				report.End.UTC().Format("2006-01-02")),
+			Report:     report,
+			Curve:      simulation.Curve,
+			Benchmarks: simulation.Benchmarks,
+		}
+		return page.Save(options.HTMLFile)
+	}
+
+	return nil
+}
+
+func saveEvolved(options RunOptions, fold Fold) error {
+	createdAt := time.Now().UTC()
+	name := strings.TrimSuffix(filepath.Base(options.ConfigOutput), filepath.Ext(options.ConfigOutput))
+
+	provenance := trader.Provenance{
+		Source:      "evolution",
+		CreatedAt:   &createdAt,
+		Parent:      options.Config.Name,
+		TrainStart:  &fold.Split.Train.Start,
+		TrainEnd:    &fold.Split.Train.End,
+		Generations: options.NumGenerations,
+		Fitness:     options.FitnessSpec,
+	}
+	if !math.IsNaN(fold.Validation) {
+		provenance.ValidationFitness = &fold.Validation
+	}
+	if !math.IsNaN(fold.Test) {
+		provenance.TestFitness = &fold.Test
+	}
+
+	named, err := trader.NewNamedConfig(name, strategies.CanonicalName(options.Config.Strategy), fold.Best.Config,
+		fold.Best.Fitness, provenance)
+	if err != nil {
+		return err
+	}
+
+	return named.Save(options.ConfigOutput)
+}
+
+// redirectLog truncates the log file and sends the log output to it, an empty path keeps the current output. The
+// returned func closes the file and restores the previous output.
+func redirectLog(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+
+	logFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := log.Writer()
+	log.SetOutput(logFile)
+
+	return func() {
+		log.SetOutput(previous)
+		logFile.Close()
+	}, nil
+}
+
+// RunPareto evolves the pareto front of the objectives over the loaded predictions, saving it to the front file.
+func RunPareto(options RunOptions) ([]Specimen, error) {
+	_, startingPoint, err := strategies.FromConfig(options.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	pareto := &Pareto{
+		Evolution: Evolution{
+			Dataset:        options.Dataset,
+			InitialBalance: options.InitialBalance,
+			Fee:            options.Fee,
+			Market:         options.Market,
+			GenerationSize: options.GenerationSize,
+			NumGenerations: options.NumGenerations,
+			MutationRate:   options.MutationRate,
+			Crossover:      options.Crossover,
+			Mutation:       options.Mutation,
+			Parents:        options.Parents,
+			StrategyName:   strategies.CanonicalName(options.Config.Strategy),
+			Symbols:        options.Symbols,
+			FillPriceModel: options.FillPriceModel,
+			StartingPoint:  startingPoint.ToSlice(),
+			Seed:           options.Seed,
+			Workers:        options.Workers,
+		},
+		Objectives: options.Objectives,
+		FrontFile:  options.FrontFile,
+	}
+
+	log.Printf("Starting pareto evolution of %d objectives...", len(options.Objectives))
+
+	return pareto.Run()
+}
+
+// ServeWorker serves evaluations of configs over the predictions of the options on address, for evolutions farming
+// their simulations out. It runs until the server fails.
+func ServeWorker(address string, options RunOptions) error {
+	log.Printf("Serving evaluations on %s", address)
+	return http.ListenAndServe(address, NewWorkerServer(options.Dataset, options.Symbols, options.FillPriceModel,
+		options.Workers))
 }
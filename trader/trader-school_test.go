@@ -0,0 +1,97 @@
+package trader
+
+import (
+	"bytes"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"scoing-trader/trader/model/predictor"
+	"scoing-trader/trader/model/trader"
+	"scoing-trader/trader/model/trader/strategies"
+	"sync"
+	"testing"
+)
+
+func TestRunSingleSimDatasets(t *testing.T) {
+	schema := (&strategies.BasicConfig{}).Schema()
+	config := &strategies.BasicConfig{}
+	trader.SetParams(config, schema.Defaults())
+	named, err := trader.NewNamedConfig("defaults", "basic", config, 0, trader.Provenance{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full := testDataset()
+	predictions := readPredictions(t, full)
+	datasets := []predictor.Dataset{full, predictor.SliceDataset(predictions[:60])}
+
+	run := func(dataset predictor.Dataset) (decimal.Decimal, error) {
+		simulation, err := RunSingleSim(RunOptions{Dataset: dataset, Config: named,
+			InitialBalance: decimal.NewFromInt(1000), Fee: decimal.NewFromFloat(0.001)})
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return simulation.Trader.Accountant.NetWorth(), nil
+	}
+
+	expected := make([]decimal.Decimal, len(datasets))
+	for i, dataset := range datasets {
+		if expected[i], err = run(dataset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected[0].Equal(expected[1]) {
+		t.Fatal("Expected the datasets to end at different net worths ", expected)
+	}
+
+	var wait sync.WaitGroup
+	netWorths := make([]decimal.Decimal, len(datasets))
+	errs := make([]error, len(datasets))
+	for i := range datasets {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			netWorths[i], errs[i] = run(datasets[i])
+		}(i)
+	}
+	wait.Wait()
+
+	for i := range datasets {
+		if errs[i] != nil || !netWorths[i].Equal(expected[i]) {
+			t.Error("Expected concurrent runs to keep to their own dataset ", i, netWorths[i], expected[i], errs[i])
+		}
+	}
+}
+
+func TestRedirectLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	previous := log.Writer()
+	defer log.SetOutput(previous)
+
+	var output bytes.Buffer
+	log.SetOutput(&output)
+
+	path := filepath.Join(dir, "trader.log")
+	restoreLog, err := redirectLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Print("to the file")
+	restoreLog()
+	log.Print("back to the buffer")
+
+	data, _ := ioutil.ReadFile(path)
+	if !bytes.Contains(data, []byte("to the file")) || bytes.Contains(data, []byte("back to the buffer")) {
+		t.Error("Incorrect log file ", string(data))
+	}
+	if !bytes.Contains(output.Bytes(), []byte("back to the buffer")) {
+		t.Error("Expected the previous log output restored ", output.String())
+	}
+}
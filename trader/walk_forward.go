@@ -0,0 +1,144 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"scoing-trader/trader/model/predictor"
+	"time"
+)
+
+// Split divides a time range chronologically into the window a config is evolved on, the window its generations are
+// compared on and the window it is finally scored on. Validation and Test may be empty.
+type Split struct {
+	Train      predictor.TimeRange
+	Validation predictor.TimeRange
+	Test       predictor.TimeRange
+}
+
+// HoldoutSplit keeps the last testFraction of [start, end) for test and the validationFraction before it for
+// validation, training on the rest.
+func HoldoutSplit(start time.Time, end time.Time, validationFraction float64, testFraction float64) (Split, error) {
+	if validationFraction < 0 || testFraction < 0 || validationFraction+testFraction >= 1 {
+		return Split{}, errors.New("validation and test fractions must be non-negative and leave time to train")
+	}
+
+	length := end.Sub(start)
+	testStart := end.Add(-time.Duration(float64(length) * testFraction))
+	validationStart := testStart.Add(-time.Duration(float64(length) * validationFraction))
+
+	return Split{
+		Train:      predictor.TimeRange{Start: start, End: validationStart},
+		Validation: predictor.TimeRange{Start: validationStart, End: testStart},
+		Test:       predictor.TimeRange{Start: testStart, End: end},
+	}, nil
+}
+
+// WalkForwardSplits rolls windows of fixed length over [start, end), each split moving forward by the test length so
+// the test windows follow each other without overlapping. A trailing test window that doesn't fit is dropped.
+func WalkForwardSplits(start time.Time, end time.Time, train time.Duration, validation time.Duration,
+	test time.Duration) ([]Split, error) {
+	if train <= 0 || validation < 0 || test <= 0 {
+		return nil, errors.New("walk forward needs train and test windows")
+	}
+
+	splits := make([]Split, 0)
+	for trainStart := start; !trainStart.Add(train + validation + test).After(end); trainStart = trainStart.Add(test) {
+		validationStart := trainStart.Add(train)
+		testStart := validationStart.Add(validation)
+
+		splits = append(splits, Split{
+			Train:      predictor.TimeRange{Start: trainStart, End: validationStart},
+			Validation: predictor.TimeRange{Start: validationStart, End: testStart},
+			Test:       predictor.TimeRange{Start: testStart, End: testStart.Add(test)},
+		})
+	}
+
+	if len(splits) == 0 {
+		return nil, fmt.Errorf("%s is too short for a %s walk forward window", end.Sub(start),
+			train+validation+test)
+	}
+
+	return splits, nil
+}
+
+// Fold is the outcome of evolving on one split. Validation and Test are NaN when the split has no such window.
+type Fold struct {
+	Split      Split
+	Best       Specimen
+	Validation float64
+	Test       float64
+	History    []GenerationResult
+}
+
+// WalkForward evolves a config on the train window of every split, keeping the generation best that did best on the
+// validation window, and scores it on the test window no selection was made on. Each split starts evolving from the
+// best config of the one before. With several splits every fold keeps its own checkpoint and history, so a resumed
+// walk forward goes through the folds done without evolving them again.
+type WalkForward struct {
+	Evolution Evolution
+	Dataset   predictor.Dataset
+	Splits    []Split
+}
+
+func (wf *WalkForward) Run() ([]Fold, error) {
+	folds := make([]Fold, 0, len(wf.Splits))
+	startingPoint := wf.Evolution.StartingPoint
+
+	for i, split := range wf.Splits {
+		evo := wf.Evolution
+		evo.StartingPoint = startingPoint
+		evo.Dataset = window(wf.Dataset, split.Train)
+		evo.Train = split.Train
+		evo.Validation = nil
+		if len(wf.Splits) > 1 {
+			evo.CheckpointFile = foldPath(wf.Evolution.CheckpointFile, i)
+			evo.HistoryFile = foldPath(wf.Evolution.HistoryFile, i)
+		}
+		if !split.Validation.Empty() {
+			evo.Validation = window(wf.Dataset, split.Validation)
+		}
+
+		log.Printf("Fold %d: train %s, validation %s, test %s", i, split.Train, split.Validation, split.Test)
+
+		best, err := evo.Run()
+		if err != nil {
+			return folds, err
+		}
+
+		fold := Fold{
+			Split:      split,
+			Best:       best,
+			Validation: math.NaN(),
+			Test:       math.NaN(),
+			History:    evo.History,
+		}
+
+		if evo.Validation != nil {
+			for _, generation := range evo.History {
+				if math.IsNaN(fold.Validation) || generation.OutOfSample > fold.Validation {
+					fold.Validation = generation.OutOfSample
+				}
+			}
+		}
+
+		if !split.Test.Empty() {
+			fold.Test, err = evo.evaluate(best, window(wf.Dataset, split.Test))
+			if err != nil {
+				return folds, err
+			}
+		}
+
+		log.Printf("Fold %d: in sample %.4f, validation %.4f, test %.4f", i, best.Fitness, fold.Validation, fold.Test)
+
+		folds = append(folds, fold)
+		startingPoint = best.Config.ToSlice()
+	}
+
+	return folds, nil
+}
+
+func window(dataset predictor.Dataset, timeRange predictor.TimeRange) predictor.Dataset {
+	return predictor.Window(dataset, timeRange.Start, timeRange.End)
+}
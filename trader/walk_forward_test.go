@@ -0,0 +1,53 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoldoutSplit(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(100 * time.Hour)
+
+	split, err := HoldoutSplit(start, end, 0.2, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !split.Train.Start.Equal(start) || !split.Train.End.Equal(start.Add(70*time.Hour)) ||
+		!split.Validation.End.Equal(start.Add(90*time.Hour)) || !split.Test.End.Equal(end) {
+		t.Error("Incorrect holdout split ", split)
+	}
+
+	if split, _ := HoldoutSplit(start, end, 0, 0); !split.Validation.Empty() || !split.Test.Empty() ||
+		!split.Train.End.Equal(end) {
+		t.Error("Expected the whole range to train on, got ", split)
+	}
+
+	if _, err := HoldoutSplit(start, end, 0.5, 0.5); err == nil {
+		t.Error("Expected split without training time to fail")
+	}
+}
+
+func TestWalkForwardSplits(t *testing.T) {
+	day := 24 * time.Hour
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	splits, err := WalkForwardSplits(start, start.Add(40*day), 20*day, 5*day, 7*day)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(splits) != 2 {
+		t.Fatal("Expected 2 splits, got ", len(splits))
+	}
+
+	if !splits[1].Train.Start.Equal(start.Add(7*day)) || !splits[1].Test.Start.Equal(splits[0].Test.End) ||
+		!splits[1].Test.End.Equal(start.Add(39*day)) {
+		t.Error("Incorrect rolling split ", splits[1])
+	}
+
+	if _, err := WalkForwardSplits(start, start.Add(10*day), 20*day, 0, 7*day); err == nil {
+		t.Error("Expected range shorter than a window to fail")
+	}
+}
@@ -0,0 +1,278 @@
+package trader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"runtime"
+	"scoing-trader/trader/fitness"
+	"scoing-trader/trader/model/market"
+	"scoing-trader/trader/model/market/model"
+	"scoing-trader/trader/model/predictor"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EvaluationPath is where a worker server takes evaluation requests.
+const EvaluationPath = "/evaluate"
+
+// EvaluationRequest asks a worker to score a config of a strategy over the predictions in [Start, End), all of its
+// predictions when both are zero. The config is simulated with the seed its evolution would use, so a worker scores
+// it as the evolution would have.
+type EvaluationRequest struct {
+	Strategy       string          `json:"strategy"`
+	Config         []float64       `json:"config"`
+	Fitness        string          `json:"fitness"`
+	InitialBalance decimal.Decimal `json:"initial_balance"`
+	Fee            decimal.Decimal `json:"fee"`
+	Market         MarketOptions   `json:"market"`
+	Uncertainty    float64         `json:"uncertainty"`
+	Seed           int64           `json:"seed"`
+	Start          time.Time       `json:"start"`
+	End            time.Time       `json:"end"`
+}
+
+type EvaluationResponse struct {
+	Fitness float64 `json:"fitness"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// errWorkerUnavailable marks failures to reach a worker, the evaluation can be tried on another one.
+var errWorkerUnavailable = errors.New("worker unavailable")
+
+// RemoteWorker evaluates configs on a worker server over HTTP.
+type RemoteWorker struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewRemoteWorker(url string) *RemoteWorker {
+	return &RemoteWorker{URL: strings.TrimSuffix(url, "/"), Client: &http.Client{}}
+}
+
+func (w *RemoteWorker) Evaluate(request EvaluationRequest) (float64, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+
+	httpResponse, err := w.Client.Post(w.URL+EvaluationPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errWorkerUnavailable, err)
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errWorkerUnavailable, err)
+	}
+
+	var response EvaluationResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return 0, fmt.Errorf("%w: %s answered %s: %s", errWorkerUnavailable, w.URL, httpResponse.Status,
+			strings.TrimSpace(string(data)))
+	}
+
+	if response.Error != "" {
+		return 0, errors.New(fmt.Sprintf("worker %s: %s", w.URL, response.Error))
+	}
+
+	return response.Fitness, nil
+}
+
+// WorkerServer scores the configs evolutions send it over its own predictions, symbols and fill price model, running
+// at most Workers simulations at once.
+type WorkerServer struct {
+	Dataset        predictor.Dataset
+	Symbols        *model.SymbolRegistry
+	FillPriceModel market.FillPriceModel
+	slots          chan struct{}
+}
+
+// NewWorkerServer serves evaluations over dataset, workers simulations at a time or one per CPU when 0.
+func NewWorkerServer(dataset predictor.Dataset, symbols *model.SymbolRegistry, fillPriceModel market.FillPriceModel,
+	workers int) *WorkerServer {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &WorkerServer{
+		Dataset:        dataset,
+		Symbols:        symbols,
+		FillPriceModel: fillPriceModel,
+		slots:          make(chan struct{}, workers),
+	}
+}
+
+func (s *WorkerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != EvaluationPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "evaluations must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request EvaluationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid evaluation request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.slots <- struct{}{}
+	score, err := s.Evaluate(request)
+	<-s.slots
+
+	response := EvaluationResponse{Fitness: score}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Evaluate scores the config of request over the predictions of the server.
+func (s *WorkerServer) Evaluate(request EvaluationRequest) (float64, error) {
+	fitnessFunction, err := fitness.Parse(request.Fitness)
+	if err != nil {
+		return 0, err
+	}
+
+	dataset := s.Dataset
+	if !request.Start.IsZero() || !request.End.IsZero() {
+		dataset = predictor.Window(dataset, request.Start, request.End)
+	}
+
+	evo := Evolution{
+		Dataset:        dataset,
+		Fitness:        fitnessFunction,
+		InitialBalance: request.InitialBalance,
+		Fee:            request.Fee,
+		Market:         request.Market,
+		Uncertainty:    request.Uncertainty,
+		StrategyName:   request.Strategy,
+		Symbols:        s.Symbols,
+		FillPriceModel: s.FillPriceModel,
+		Seed:           request.Seed,
+	}
+	if err := evo.setup(); err != nil {
+		return 0, err
+	}
+
+	if err := evo.factory.NewConfig().Schema().Validate(request.Config); err != nil {
+		return 0, fmt.Errorf("%s: %w", evo.StrategyName, err)
+	}
+
+	specimen := Specimen{Config: evo.factory.NewConfig()}
+	specimen.Config.FromSlice(request.Config)
+
+	return evo.evaluate(specimen, dataset)
+}
+
+// evaluationJob is a specimen of the pool at index waiting for its fitness.
+type evaluationJob struct {
+	index    int
+	specimen Specimen
+}
+
+// evaluatePool scores the specimens with evaluate on at most Workers goroutines at once, each knowing its number,
+// logging the progress as they complete.
+func (evo *Evolution) evaluatePool(jobs []evaluationJob, generation int,
+	evaluate func(specimen Specimen, worker int) (Specimen, error)) ([]specimenResult, error) {
+	workers := evo.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	queue := make(chan evaluationJob)
+	results := make(chan specimenResult)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for job := range queue {
+				specimen, err := evaluate(job.specimen, worker)
+				results <- specimenResult{index: job.index, specimen: specimen, err: err}
+			}
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	evaluated := make([]specimenResult, 0, len(jobs))
+	var poolErr error
+	step := len(jobs)/10 + 1
+
+	for result := range results {
+		if result.err != nil {
+			if poolErr == nil {
+				poolErr = result.err
+				close(stop)
+			}
+			continue
+		}
+
+		evaluated = append(evaluated, result)
+		if len(evaluated)%step == 0 || len(evaluated) == len(jobs) {
+			log.Printf("Generation %d: %d/%d specimens simulated", generation, len(evaluated), len(jobs))
+		}
+	}
+
+	return evaluated, poolErr
+}
+
+// evaluateRemote sends the specimen to the remote worker assigned to the pool worker, trying the others in turn when it
+// can't be reached.
+func (evo *Evolution) evaluateRemote(specimen Specimen, remotes []*RemoteWorker, worker int) (float64, error) {
+	request := EvaluationRequest{
+		Strategy:       evo.StrategyName,
+		Config:         specimen.Config.ToSlice(),
+		Fitness:        evo.FitnessSpec,
+		InitialBalance: evo.InitialBalance,
+		Fee:            evo.Fee,
+		Market:         evo.Market,
+		Uncertainty:    evo.Uncertainty,
+		Seed:           evo.Seed,
+		Start:          evo.Train.Start,
+		End:            evo.Train.End,
+	}
+
+	var err error
+	for attempt := 0; attempt < len(remotes); attempt++ {
+		var score float64
+		remote := remotes[(worker+attempt)%len(remotes)]
+
+		if score, err = remote.Evaluate(request); !errors.Is(err, errWorkerUnavailable) {
+			return score, err
+		}
+		log.Println(err)
+	}
+
+	return 0, err
+}
@@ -0,0 +1,61 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"net/http/httptest"
+	"scoing-trader/trader/model/predictor"
+	"testing"
+	"time"
+)
+
+func TestRemoteWorkers(t *testing.T) {
+	first := httptest.NewServer(NewWorkerServer(testDataset(), nil, nil, 2))
+	defer first.Close()
+	second := httptest.NewServer(NewWorkerServer(testDataset(), nil, nil, 1))
+	defer second.Close()
+	down := httptest.NewServer(NewWorkerServer(testDataset(), nil, nil, 1))
+	down.Close()
+
+	start := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+	train := predictor.TimeRange{Start: start, End: start.Add(4 * time.Hour)}
+
+	evolve := func(workers int, remote []string) Evolution {
+		evo := Evolution{
+			Dataset:        predictor.Window(testDataset(), train.Start, train.End),
+			Train:          train,
+			InitialBalance: decimal.NewFromInt(1000),
+			Fee:            decimal.NewFromFloat(0.001),
+			Uncertainty:    0.1,
+			GenerationSize: 5,
+			NumGenerations: 2,
+			MutationRate:   0.5,
+			FitnessSpec:    "return",
+			StrategyName:   "basic",
+			Seed:           3,
+			Workers:        workers,
+			Remote:         remote,
+		}
+		if _, err := evo.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return evo
+	}
+
+	local := evolve(2, nil)
+	remote := evolve(3, []string{down.URL, first.URL, second.URL})
+
+	if fmt.Sprint(local.History) != fmt.Sprint(remote.History) {
+		t.Error("Expected remote workers to score as the local pool ", local.History, remote.History)
+	}
+
+	_, err := NewRemoteWorker(first.URL).Evaluate(EvaluationRequest{Strategy: "unknown", Fitness: "net_worth"})
+	if err == nil || errors.Is(err, errWorkerUnavailable) {
+		t.Error("Expected the worker to report the unknown strategy ", err)
+	}
+
+	if _, err := NewRemoteWorker(down.URL).Evaluate(EvaluationRequest{}); !errors.Is(err, errWorkerUnavailable) {
+		t.Error("Expected an unreachable worker to be unavailable ", err)
+	}
+}